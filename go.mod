@@ -1,3 +1,5 @@
 module github.com/psucodervn/lovepac
 
-go 1.15
+go 1.20
+
+require golang.org/x/image v0.24.0
@@ -4,12 +4,14 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	_ "image/gif"
-	_ "image/jpeg"
+	"image/png"
+	"regexp"
 	"runtime/pprof"
+	"strings"
 	"time"
 
 	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/packing"
 	"github.com/psucodervn/lovepac/target"
 
 	"log"
@@ -19,6 +21,47 @@ import (
 // Command line arguments
 var pVerbose *bool
 
+// paddingModes maps the values of the -paddingmode flag to padding modes
+var paddingModes = map[string]packer.PaddingMode{
+	"shared":    packer.PaddingShared,
+	"symmetric": packer.PaddingSymmetric,
+	"noedge":    packer.PaddingNoEdge,
+}
+
+// containerFormats maps the values of the -container flag to container formats
+var containerFormats = map[string]packer.ContainerFormat{
+	"png": packer.ContainerPNG,
+	"ktx": packer.ContainerKTX,
+}
+
+// trimModes maps the values of the -trim flag to trim modes
+var trimModes = map[string]packer.TrimMode{
+	"none":      packer.TrimNone,
+	"alpha":     packer.TrimAlpha,
+	"symmetric": packer.TrimSymmetric,
+}
+
+// pngCompressions maps the values of the -compression flag to png compression levels
+var pngCompressions = map[string]png.CompressionLevel{
+	"default": png.DefaultCompression,
+	"none":    png.NoCompression,
+	"speed":   png.BestSpeed,
+	"best":    png.BestCompression,
+}
+
+// fillDirections maps the values of the -fill flag to fill directions
+var fillDirections = map[string]packing.FillDirection{
+	"free":    packing.FillFree,
+	"rows":    packing.FillRows,
+	"columns": packing.FillColumns,
+}
+
+// colorProfiles maps the values of the -profile flag to color profiles
+var colorProfiles = map[string]packer.ColorProfile{
+	"none": packer.ColorProfileNone,
+	"srgb": packer.ColorProfileSRGB,
+}
+
 func main() {
 
 	// Set the function to call when printing command line usage
@@ -28,14 +71,63 @@ func main() {
 	}
 
 	// Set and parse the command line arguments
-	pName := flag.String("name", packer.DefaultAtlasName, "the base name of the output images and data files")
+	pName := flag.String("name", packer.DefaultAtlasName, "the base name of the output images and data files, {index}, {width}, {height} and {hash} are replaced for each atlas")
 	pOutputDir := flag.String("out", "", "the directory to output the result to")
 	pVerbose = flag.Bool("v", false, "use verbose logging")
-	pFormat := flag.String("format", "love", "the export format of the atlas")
+	pFormat := flag.String("format", "love", "the export format of the atlas, several comma separated formats write a descriptor in each")
 	pWidth := flag.Int("width", packer.DefaultAtlasWidth, "maximum width of an atlas image")
 	pHeight := flag.Int("height", packer.DefaultAtlasHeight, "maximum height of an atlas image")
 	pPadding := flag.Int("padding", 0, "the space between images in the atlas")
+	pPaddingX := flag.Int("paddingx", 0, "the horizontal space between images, overriding -padding")
+	pPaddingY := flag.Int("paddingy", 0, "the vertical space between images, overriding -padding")
+	pPaddingMode := flag.String("paddingmode", "shared", "where padding is reserved: shared, symmetric or noedge to leave the atlas edges unpadded")
+	pFit := flag.Bool("fit", false, "shrink each atlas to fit its contents, width and height become the maximum size")
+	pMaxAspectRatio := flag.Float64("maxaspect", 0, "the maximum aspect ratio of an atlas in fit mode, 0 indicates no maximum")
+	pMinWidth := flag.Int("minwidth", 0, "the minimum width of an atlas in fit mode")
+	pMinHeight := flag.Int("minheight", 0, "the minimum height of an atlas in fit mode")
+	pAutoGrow := flag.Bool("autogrow", false, "double the width and height of the atlases and retry when the sprites do not fit")
+	pAutoGrowLimit := flag.Int("autogrowlimit", 0, "the largest width and height that -autogrow grows the atlases to, 0 uses the default of 8192")
 	pMaxAtlases := flag.Int("maxatlases", 0, "the maximum number of atlases to write, 0 indicates no maximum")
+	pMaxFileBytes := flag.Int("maxbytes", 0, "the maximum size in bytes of an atlas image, larger atlases are split, 0 indicates no maximum")
+	pContainer := flag.String("container", "png", "the file format of the atlas images: png or ktx")
+	pPreserveColor := flag.Bool("preservecolor", false, "keep the atlases of grayscale images in 8-bit grayscale")
+	pPalettes := flag.Bool("palettes", false, "describe the distinct colors of each image, for templates that use the palettes")
+	pAnimations := flag.Bool("animations", false, "describe images named like numbered frames, eg. walk_0.png, as animations")
+	pAnimationPattern := flag.String("animationpattern", "", "a regexp capturing the animation name and frame number of image names, eg. '^(.+)-(\\d+)$'")
+	pEmbed := flag.Bool("embed", false, "embed the atlas images in JSON descriptors as base64 data URIs instead of writing image files")
+	pReport := flag.String("report", "", "the name of an HTML report showing the packed sprites to write to the output directory")
+	pTypes := flag.String("types", "", "comma separated image types to allow, eg. 'png,jpeg', other images are rejected, by default every supported type is allowed")
+	pInclude := flag.String("include", "", "comma separated patterns of the image paths to pack, eg. 'ui/*.png', by default every image is packed")
+	pExclude := flag.String("exclude", "", "comma separated patterns of the image paths never to pack, eg. 'wip/*'")
+	pByExtension := flag.Bool("byext", false, "pack the images of each file extension into separate atlases named after the extension")
+	pFolderAsAtlas := flag.Bool("folders", false, "pack the images of each folder of the input directory into atlases named after the folder")
+	pTrim := flag.String("trim", "none", "trim the transparent edges of images: none, alpha or symmetric to keep them centered")
+	pDPI := flag.Int("dpi", 0, "the pixel density written into atlas images, 0 leaves it unspecified")
+	pFill := flag.String("fill", "free", "the direction atlases are filled in: free, or rows or columns for predictable shelf layouts")
+	pCompression := flag.String("compression", "default", "the compression of png atlas images: default, none, speed or best")
+	pColorProfile := flag.String("profile", "none", "the color space that png atlas images are tagged with: none or srgb")
+	pPreview := flag.Bool("preview", false, "write a preview of each atlas with every sprite labeled by name")
+	pGroupDepth := flag.Int("groupdepth", 0, "pack the sprites of each directory up to this depth into separate atlases, 0 packs everything together")
+	pSortByName := flag.Bool("sortbyname", false, "list the sprites of descriptors by name instead of in packing order")
+	pPretty := flag.Bool("pretty", false, "indent JSON descriptors")
+	pMinify := flag.Bool("minify", false, "compact JSON descriptors to a single line")
+	pStable := flag.Bool("stable", false, "keep sprites on the same atlases across builds by assigning them by name, at some cost in density")
+	pBalance := flag.Int("balance", 0, "pack the sprites into this many equally full atlases, 0 fills one atlas after another")
+	pTextureArray := flag.Bool("texturearray", false, "pack every atlas at the full size as a layer of one texture array, eg. with -format texturearray")
+	pVerifyOverlap := flag.Bool("verifyoverlap", false, "fail if any two sprites of an atlas overlap including their padding, a self-check of the packer")
+	pMinLastOccupancy := flag.Float64("minlastoccupancy", 0, "halve the last atlas while less than this fraction of it is covered by sprites and they still fit")
+	pFractional := flag.Bool("fractional", false, "round the sizes of sprites that -spec scales to a fraction of a pixel instead of failing")
+	pSpec := flag.String("spec", "", "a JSON or CSV file listing images of the input directory with the names, padding, scale and pivots of their sprites")
+	pOptimize := flag.Bool("optimize", false, "try several packing strategies and use the one needing the fewest atlases")
+	pNameSeparator := flag.String("nameseparator", "", "replace the directory separators in the full names of sprites with this, eg. . or _")
+	pBuildTag := flag.String("buildtag", "", "a build number or timestamp recorded in the meta of descriptors, eg. for phasermulti")
+	pImagePrefix := flag.String("imageprefix", "", "a path prepended to the image filenames in descriptors, eg. textures/")
+	pSeed := flag.Int64("seed", 0, "seed the randomized steps of packing, eg. the order optimize tries strategies in")
+	pTransactional := flag.Bool("transactional", false, "hold every file in memory and only write them once the whole run has succeeded")
+	pDepFile := flag.String("depfile", "", "write a Makefile dependency file with this name listing the inputs of the outputs")
+	pDescExt := flag.String("ext", "", "the file extension of the descriptors, defaults to the extension of the format")
+	pMaxDecode := flag.Int("maxdecode", packer.DefaultMaxDecodeDimension, "reject images wider or taller than this many pixels before decoding them, -1 indicates no maximum")
+	pRespectEXIF := flag.Bool("exif", true, "rotate JPEG images upright according to their EXIF orientation")
 	pCPUProfile := flag.String("cpuprofile", "", "write cpu profile to file")
 	pMemprofile := flag.String("memprofile", "", "write memory profile to file")
 
@@ -58,22 +150,124 @@ func main() {
 		return
 	}
 	inputDir := args[0]
+	input := packer.NewFileStream(inputDir)
+	if *pSpec != "" {
+		input = packer.NewSpecOverlayStream(input, *pSpec)
+	}
+
+	var formats []target.Format
+	for _, name := range strings.Split(*pFormat, ",") {
+		format := target.FormatNamed(name)
+		if format == target.Unknown {
+			log.Fatalf("Unknown format '%s'", name)
+		}
+		formats = append(formats, format)
+	}
+	// A single format is written with the -ext extension
+	format := formats[0]
+	if len(formats) == 1 {
+		formats = nil
+	}
+
+	paddingMode, ok := paddingModes[*pPaddingMode]
+	if !ok {
+		log.Fatalf("Unknown padding mode '%s'", *pPaddingMode)
+	}
+
+	container, ok := containerFormats[*pContainer]
+	if !ok {
+		log.Fatalf("Unknown container format '%s'", *pContainer)
+	}
+
+	trimMode, ok := trimModes[*pTrim]
+	if !ok {
+		log.Fatalf("Unknown trim mode '%s'", *pTrim)
+	}
+
+	var animationPattern *regexp.Regexp
+	if *pAnimationPattern != "" {
+		pattern, err := regexp.Compile(*pAnimationPattern)
+		if err != nil {
+			log.Fatalf("Invalid animation pattern '%s': %s", *pAnimationPattern, err)
+		}
+		animationPattern = pattern
+	}
 
-	format := target.FormatNamed(*pFormat)
-	if format == target.Unknown {
-		log.Fatalf("Unknown format '%s'", *pFormat)
+	fillDirection, ok := fillDirections[*pFill]
+	if !ok {
+		log.Fatalf("Unknown fill direction '%s'", *pFill)
 	}
 
+	pngCompression, ok := pngCompressions[*pCompression]
+	if !ok {
+		log.Fatalf("Unknown png compression '%s'", *pCompression)
+	}
+
+	colorProfile, ok := colorProfiles[*pColorProfile]
+	if !ok {
+		log.Fatalf("Unknown color profile '%s'", *pColorProfile)
+	}
+
+	output := packer.NewMeasuringOutputter(packer.NewFileOutputter(*pOutputDir))
 	stopTimer := startTimer("Texture packing")
-	err := packer.Run(context.Background(), &packer.Params{
-		Name:       *pName,
-		Input:      packer.NewFileStream(inputDir),
-		Output:     packer.NewFileOutputter(*pOutputDir),
-		Format:     format,
-		Width:      *pWidth,
-		Height:     *pHeight,
-		Padding:    *pPadding,
-		MaxAtlases: *pMaxAtlases,
+	result, err := packer.RunWithResult(context.Background(), &packer.Params{
+		Name:                       *pName,
+		Input:                      input,
+		Output:                     output,
+		Format:                     format,
+		Formats:                    formats,
+		Width:                      *pWidth,
+		Height:                     *pHeight,
+		Padding:                    *pPadding,
+		PaddingX:                   *pPaddingX,
+		PaddingY:                   *pPaddingY,
+		PaddingMode:                paddingMode,
+		FillDirection:              fillDirection,
+		MaxAtlases:                 *pMaxAtlases,
+		AutoGrow:                   *pAutoGrow,
+		AutoGrowLimit:              *pAutoGrowLimit,
+		MaxFileBytes:               *pMaxFileBytes,
+		Fit:                        *pFit,
+		MaxAspectRatio:             *pMaxAspectRatio,
+		MinWidth:                   *pMinWidth,
+		MinHeight:                  *pMinHeight,
+		RespectEXIF:                *pRespectEXIF,
+		MaxDecodeDimension:         *pMaxDecode,
+		Seed:                       *pSeed,
+		ImagePathPrefix:            *pImagePrefix,
+		NameSeparator:              *pNameSeparator,
+		BuildTag:                   *pBuildTag,
+		ImageDPI:                   *pDPI,
+		PNGCompression:             pngCompression,
+		ColorProfile:               colorProfile,
+		ContainerFormat:            container,
+		PreserveColorModel:         *pPreserveColor,
+		TrimMode:                   trimMode,
+		EmitPalettes:               *pPalettes,
+		DetectAnimations:           *pAnimations,
+		AnimationPattern:           animationPattern,
+		EmbedImage:                 *pEmbed,
+		ReportPath:                 *pReport,
+		AllowedTypes:               splitList(*pTypes),
+		Include:                    splitList(*pInclude),
+		Exclude:                    splitList(*pExclude),
+		FolderAsAtlas:              *pFolderAsAtlas,
+		SeparateByExtension:        *pByExtension,
+		EmitLabeledPreview:         *pPreview,
+		GroupByPrefixDepth:         *pGroupDepth,
+		PrettyPrint:                *pPretty,
+		Minify:                     *pMinify,
+		SortDescriptorByName:       *pSortByName,
+		Optimize:                   *pOptimize,
+		StablePaging:               *pStable,
+		BalanceAcrossAtlases:       *pBalance,
+		MinLastAtlasOccupancy:      *pMinLastOccupancy,
+		TextureArray:               *pTextureArray,
+		VerifyNoOverlap:            *pVerifyOverlap,
+		DescExtension:              *pDescExt,
+		DepFile:                    *pDepFile,
+		Transactional:              *pTransactional,
+		AllowFractionalCoordinates: *pFractional,
 	})
 	stopTimer()
 
@@ -90,6 +284,12 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if *pVerbose && *pAutoGrow && result.Width > 0 {
+		log.Printf("Packed atlases of %dx%d", result.Width, result.Height)
+	}
+	if *pVerbose {
+		log.Printf("Wrote %d files totalling %d bytes", output.FileCount(), output.BytesWritten())
+	}
 }
 
 func startTimer(name string) func() {
@@ -98,3 +298,11 @@ func startTimer(name string) func() {
 		log.Printf("%s took %s", name, time.Since(start))
 	}
 }
+
+// splitList splits a comma separated flag value, an empty value has no elements
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
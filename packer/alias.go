@@ -0,0 +1,53 @@
+package packer
+
+import (
+	"fmt"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// validateAliases checks that every alias of Params.Aliases describes
+// only one sprite, so that no descriptor names two rects the same
+func validateAliases(aliases map[string][]string, sprites []packing.Block) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+	// described maps each name to the name of the sprite it describes
+	described := map[string]string{}
+	for _, block := range sprites {
+		name := block.(*sprite).DisplayName()
+		described[name] = name
+	}
+	for _, block := range sprites {
+		spr := block.(*sprite)
+		for _, alias := range aliases[spr.DisplayName()] {
+			alias = spr.separateName(alias)
+			if other, ok := described[alias]; ok && other == alias {
+				return fmt.Errorf("Alias '%s' of '%s' is already the name of a sprite", alias, spr.DisplayName())
+			} else if ok {
+				return fmt.Errorf("Alias '%s' is given to both '%s' and '%s'", alias, other, spr.DisplayName())
+			}
+			described[alias] = spr.DisplayName()
+		}
+	}
+	return nil
+}
+
+// withAliases returns the sprites with a copy of each sprite that has
+// aliases following it for every alias, named by the alias and placed
+// at the same rect. The copies are only described, never drawn.
+func withAliases(sprites []packing.Block, aliases map[string][]string) []packing.Block {
+	described := make([]packing.Block, 0, len(sprites))
+	for _, block := range sprites {
+		described = append(described, block)
+		spr := block.(*sprite)
+		for _, alias := range aliases[spr.DisplayName()] {
+			copied := *spr
+			copied.path = alias + spr.ext()
+			// Copies would otherwise keep the pixels alive after the sprite releases them
+			copied.release()
+			described = append(described, &copied)
+		}
+	}
+	return described
+}
@@ -0,0 +1,71 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestAliasesDescribeASpriteUnderMoreNames(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input: NewImageStream(t, map[string]image.Image{
+			"icons/coin.png": newSolidImage(8, 8, color.White),
+			"icons/gem.png":  newSolidImage(4, 4, color.White),
+		}),
+		Output:  outputRecorder,
+		Width:   16,
+		Height:  16,
+		Aliases: map[string][]string{"icons/coin": {"icons/currency", "money"}, "unknown": {"ignored"}},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+	for _, quad := range []string{
+		"quads['coin'] = love.graphics.newQuad(0,0,8,8,16,16)",
+		"quads['currency'] = love.graphics.newQuad(0,0,8,8,16,16)",
+		"quads['money'] = love.graphics.newQuad(0,0,8,8,16,16)",
+		"quads['gem'] = love.graphics.newQuad(8,0,4,4,16,16)",
+	} {
+		if !strings.Contains(gotStr, quad) {
+			t.Errorf("Expected descriptor to contain '%s' but got\n\n%s", quad, gotStr)
+		}
+	}
+	if strings.Contains(gotStr, "ignored") {
+		t.Errorf("Expected the aliases of an unknown sprite to be ignored but got\n\n%s", gotStr)
+	}
+}
+
+func TestAliasesMustNotNameTwoSprites(t *testing.T) {
+	for expected, aliases := range map[string]map[string][]string{
+		"is already the name of a sprite": {"coin": {"gem"}},
+		"is given to both":                {"coin": {"shiny"}, "gem": {"shiny"}},
+	} {
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: target.Love,
+			Input: NewImageStream(t, map[string]image.Image{
+				"coin.png": newSolidImage(8, 8, color.White),
+				"gem.png":  newSolidImage(4, 4, color.White),
+			}),
+			Output:  NewOutputRecorder(),
+			Width:   16,
+			Height:  16,
+			Aliases: aliases,
+		}
+		err := packer.Run(context.Background(), params)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing '%s' but got '%v'", expected, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package packer
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// DefaultAnimationPattern matches the sprite names of animation frames
+// when no AnimationPattern is specified, a name and a frame number
+// separated by an underscore, eg. "hero/walk_0" is frame 0 of "hero/walk"
+var DefaultAnimationPattern = regexp.MustCompile(`^(.+)_(\d+)$`)
+
+// animation is a sequence of frames detected by Params.DetectAnimations,
+// used for template rendering
+type animation struct {
+	Name   string
+	Frames []*sprite
+}
+
+// validateAnimationPattern checks that the AnimationPattern
+// captures both the animation name and the frame number
+func (p *Params) validateAnimationPattern() error {
+	if p.DetectAnimations && p.AnimationPattern != nil && p.AnimationPattern.NumSubexp() < 2 {
+		return errors.New("AnimationPattern must capture the animation name and the frame number")
+	}
+	return nil
+}
+
+// detectAnimations groups the sprites whose DisplayName matches the pattern
+// into animations of at least two frames, sorted by name. The frames of each
+// animation are sorted by frame number.
+func detectAnimations(sprites []packing.Block, pattern *regexp.Regexp) []*animation {
+	if pattern == nil {
+		pattern = DefaultAnimationPattern
+	}
+	type frame struct {
+		spr    *sprite
+		number int
+	}
+	frames := map[string][]frame{}
+	for _, block := range sprites {
+		spr := block.(*sprite)
+		match := pattern.FindStringSubmatch(spr.DisplayName())
+		if match == nil {
+			continue
+		}
+		number, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+		frames[match[1]] = append(frames[match[1]], frame{spr, number})
+	}
+
+	var animations []*animation
+	for name, sequence := range frames {
+		if len(sequence) < 2 {
+			continue
+		}
+		sort.SliceStable(sequence, func(i, j int) bool { return sequence[i].number < sequence[j].number })
+		anim := &animation{Name: name, Frames: make([]*sprite, len(sequence))}
+		for i, f := range sequence {
+			anim.Frames[i] = f.spr
+		}
+		animations = append(animations, anim)
+	}
+	sort.Slice(animations, func(i, j int) bool { return animations[i].Name < animations[j].Name })
+	return animations
+}
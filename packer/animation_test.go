@@ -0,0 +1,99 @@
+package packer_test
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestDetectAnimationsGroupsFramesByNumber(t *testing.T) {
+	images := map[string]image.Image{}
+	for _, name := range []string{"hero/walk_10.png", "hero/walk_2.png", "hero/walk_0.png", "hero/jump_0.png", "hero/jump_1.png", "hero/idle_0.png", "hero/shadow.png"} {
+		images[name] = newSolidImage(4, 4, color.White)
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.PhaserMulti,
+		Input:            NewImageStream(t, images),
+		Output:           outputRecorder,
+		Width:            16,
+		Height:           16,
+		DetectAnimations: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	var desc struct {
+		Animations map[string][]string `json:"animations"`
+	}
+	if err := json.Unmarshal(outputRecorder.Got()["atlas-1.json"], &desc); err != nil {
+		t.Fatalf("Expected a JSON descriptor but got '%s'", err)
+	}
+	// A single numbered frame is no animation
+	expected := map[string][]string{
+		"hero/jump": {"jump_0", "jump_1"},
+		"hero/walk": {"walk_0", "walk_2", "walk_10"},
+	}
+	if !reflect.DeepEqual(desc.Animations, expected) {
+		t.Errorf("Expected the animations %v but got %v", expected, desc.Animations)
+	}
+}
+
+func TestDetectAnimationsUsesAnimationPattern(t *testing.T) {
+	images := map[string]image.Image{
+		"walk-1.png": newSolidImage(4, 4, color.White),
+		"walk-2.png": newSolidImage(4, 4, color.White),
+		"walk_3.png": newSolidImage(4, 4, color.White),
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.PhaserMulti,
+		Input:            NewImageStream(t, images),
+		Output:           outputRecorder,
+		Width:            16,
+		Height:           16,
+		CombineDescFiles: true,
+		DetectAnimations: true,
+		AnimationPattern: regexp.MustCompile(`^(.+)-(\d+)$`),
+		Minify:           true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expected := `"animations":{"walk":["walk-1","walk-2"]}`
+	if got := string(outputRecorder.Got()["atlas.json"]); !strings.Contains(got, expected) {
+		t.Errorf("Expected the descriptor to contain %s but got %s", expected, got)
+	}
+}
+
+func TestDetectAnimationsRejectsPatternsWithoutFrameNumber(t *testing.T) {
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.PhaserMulti,
+		Input:            NewImageStream(t, map[string]image.Image{"walk_0.png": newSolidImage(4, 4, color.White)}),
+		Output:           NewOutputRecorder(),
+		DetectAnimations: true,
+		AnimationPattern: regexp.MustCompile(`^walk_\d+$`),
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "AnimationPattern") {
+		t.Errorf("Expected an AnimationPattern error but got '%v'", err)
+	}
+}
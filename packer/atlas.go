@@ -1,13 +1,19 @@
 package packer
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/png"
 	"io"
-	"text/template"
+	"io/ioutil"
 
 	"github.com/psucodervn/lovepac/packing"
+	"github.com/psucodervn/lovepac/target"
 )
 
 type atlas struct {
@@ -16,48 +22,167 @@ type atlas struct {
 
 	DescFilename  string
 	ImageFilename string
+	// AlphaFilename is the grayscale alpha image of the atlas when
+	// the alpha channel is split from the image, otherwise empty
+	AlphaFilename string
 
 	Width   int
 	Height  int
 	Padding int
 	Scale   float64
+	// Occupancy is the fraction of the atlas covered by sprites
+	Occupancy float64
+	// Animations are the frame sequences of the atlas sprites
+	// when Params.DetectAnimations is set
+	Animations []*animation
+	// Meta describes the build that wrote the descriptor
+	Meta meta
+
+	// descSprites are the Sprites described to templates when they differ,
+	// eg. with the copies of Params.Aliases
+	descSprites []packing.Block
+
+	colorModel ColorModel
+	background color.Color
+	dpi        int
+	// colorProfile tags the png images, see Params.ColorProfile
+	colorProfile ColorProfile
+	// pngCompression is the compression level of the png images
+	pngCompression png.CompressionLevel
+	container      ContainerFormat
+	// gray atlases are drawn in 8-bit grayscale, see Params.PreserveColorModel
+	gray bool
+
+	jsonStyle jsonStyle
+	// imagePathPrefix is prepended to the image filenames in descriptors
+	imagePathPrefix string
+	// embedImage atlases are not written as image files, descriptors are
+	// given the imageData URI instead, see Params.EmbedImage
+	embedImage bool
+	imageData  string
+
+	// index is the 1 based index of the atlas, passed to postProcess
+	index       int
+	postProcess func(img *image.NRGBA, atlasIndex int) image.Image
+
+	// canvases and pngBuffers are reused to draw and encode the images,
+	// see Packer. The images given to postProcess are never reused.
+	canvases   *canvasPool
+	pngBuffers png.EncoderBufferPool
 }
 
 func (a *atlas) CreateImage() (image.Image, error) {
-	img := image.NewNRGBA(image.Rect(0, 0, a.Width, a.Height))
+	if a.gray {
+		return a.drawGraySprites()
+	}
+	img, err := a.renderImage()
+	if err != nil {
+		return nil, err
+	}
+	converted := convertColorModel(img, a.colorModel, a.background)
+	if converted != image.Image(img) {
+		a.release(img)
+	}
+	return converted, nil
+}
+
+// release returns an image of the atlas that is no longer used to the
+// canvases, unless it may have been kept by the post process
+func (a *atlas) release(img image.Image) {
+	if a.postProcess == nil {
+		a.canvases.put(img)
+	}
+}
+
+// renderImage draws the sprites and applies the post process, if any
+func (a *atlas) renderImage() (*image.NRGBA, error) {
+	img, err := a.drawSprites()
+	if err != nil || a.postProcess == nil {
+		return img, err
+	}
+	processed := a.postProcess(img, a.index)
+	if processed == nil {
+		return nil, fmt.Errorf("PostProcess returned no image for atlas '%s'", a.Name)
+	}
+	if processed.Bounds() != img.Bounds() {
+		return nil, fmt.Errorf("PostProcess changed the size of atlas '%s' from %v to %v",
+			a.Name, img.Bounds().Size(), processed.Bounds().Size())
+	}
+	if nrgba, ok := processed.(*image.NRGBA); ok {
+		return nrgba, nil
+	}
+	out := image.NewNRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), processed, processed.Bounds().Min, draw.Src)
+	return out, nil
+}
+
+// drawSprites draws every sprite into a new image the size of the atlas
+func (a *atlas) drawSprites() (*image.NRGBA, error) {
+	img := a.canvases.get(a.Width, a.Height)
 
 	// TODO run these draw steps in parallel
 	for i := range a.Sprites {
 		spr := a.Sprites[i].(*sprite)
 		rect := image.Rect(spr.x, spr.y, spr.x+spr.w, spr.y+spr.h)
 
-		assetReader, err := spr.Asset.Reader()
+		sprImg, err := spr.decodeImage()
 		if err != nil {
-			return nil, fmt.Errorf("Failed to read asset '%s': %s", spr.path, err)
-		}
-		sprImg, _, err := image.Decode(assetReader)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to decode asset '%s': %s", spr.path, err)
+			a.release(img)
+			return nil, err
 		}
 
-		fastDraw(img, rect, sprImg)
+		fastDraw(img, rect, sprImg, a.canvases)
 	}
 
 	return img, nil
 }
 
-func (a *atlas) Output(outputter Outputter, descriptorTemplate *template.Template) error {
-	errc := make(chan error, 2)
-	go func() {
-		// Create and write the resulting image
-		errc <- a.OutputImage(outputter, descriptorTemplate)
-	}()
-	go func() {
-		// Create and write the file that describes the image
-		errc <- a.OutputDesc(outputter, false, descriptorTemplate)
-	}()
+// releaseSprites drops the decoded images held by the sprites of the
+// atlas so they can be garbage collected. It must only be called once
+// the atlas images have been written, the sprites can't be drawn again.
+func (a *atlas) releaseSprites() {
+	for _, block := range a.Sprites {
+		block.(*sprite).release()
+	}
+}
+
+// atlasSet is the template data used to render Multi formats,
+// which describe several atlases in a single descriptor
+type atlasSet struct {
+	Name    string
+	Atlases []*atlas
+	// Animations are the frame sequences of the sprites of every
+	// atlas in the set when Params.DetectAnimations is set
+	Animations []*animation
+	// Meta describes the build that wrote the descriptor
+	Meta meta
+
+	jsonStyle jsonStyle
+}
+
+func (a *atlas) Output(outputter Outputter, formats []descFormat) error {
+	errc := make(chan error, 1+len(formats))
+	if a.embedImage {
+		// The image is encoded before the descriptors that embed it
+		if err := a.embed(); err != nil {
+			return err
+		}
+		errc <- nil
+	} else {
+		go func() {
+			// Create and write the resulting image
+			errc <- a.OutputImage(outputter)
+		}()
+	}
+	for _, format := range formats {
+		go func(format descFormat) {
+			// Create and write the file that describes the image
+			desc := a.withDescFilename(fmt.Sprintf("%s.%s", a.Name, format.ext))
+			errc <- desc.OutputDesc(outputter, false, format.Format)
+		}(format)
+	}
 	// Drain error channel
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 1+len(formats); i++ {
 		if err := <-errc; err != nil {
 			return err
 		}
@@ -65,20 +190,146 @@ func (a *atlas) Output(outputter Outputter, descriptorTemplate *template.Templat
 	return nil
 }
 
-func (a *atlas) OutputImage(imageOutputter Outputter, descriptorTemplate *template.Template) error {
+// withDescFilename returns a copy of the atlas that is described in the named file
+func (a *atlas) withDescFilename(filename string) *atlas {
+	desc := *a
+	desc.DescFilename = filename
+	return &desc
+}
+
+func (a *atlas) OutputImage(imageOutputter Outputter) error {
+	if a.AlphaFilename != "" {
+		return a.outputSplitAlphaImages(imageOutputter)
+	}
 	// Create and write the resulting image
 	return withFile(imageOutputter, a.ImageFilename, false, func(writer io.Writer) error {
 		img, err := a.CreateImage()
 		if err != nil {
 			return err
 		}
-		return png.Encode(writer, img)
+		defer a.release(img)
+		return a.encodeImage(writer, img)
 	})
 }
 
-func (a *atlas) OutputDesc(descOutputter Outputter, append bool, descriptorTemplate *template.Template) error {
+// imagesFit encodes the images of the atlas without writing them and
+// returns true if none of the files are larger than maxBytes
+func (a *atlas) imagesFit(maxBytes int) (bool, error) {
+	measure := NewMeasuringOutputter(OutputterFunc(func(filename string, append bool) (io.WriteCloser, error) {
+		return nopWriteCloser{ioutil.Discard}, nil
+	}))
+	if err := a.OutputImage(measure); err != nil {
+		return false, err
+	}
+	for _, size := range measure.Sizes() {
+		if size > int64(maxBytes) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (a *atlas) OutputDesc(descOutputter Outputter, append bool, format target.Format) error {
 	// Create and write the file that describes the image
 	return withFile(descOutputter, a.DescFilename, append, func(writer io.Writer) error {
-		return descriptorTemplate.Execute(writer, a)
+		if format.Multi {
+			set := &atlasSet{Name: a.Name, Atlases: []*atlas{a}, Animations: a.Animations, Meta: a.Meta, jsonStyle: a.jsonStyle}
+			return executeDescTemplate(writer, format, set.descData(), a.jsonStyle)
+		}
+		return executeDescTemplate(writer, format, a.descData(), a.jsonStyle)
+	})
+}
+
+// descData returns the atlas as it is described to templates, with its
+// descSprites, and the image path prefix prepended to its image filenames
+// or the image data URI as its image filename when the image is embedded
+func (a *atlas) descData() *atlas {
+	if a.imageData == "" && a.imagePathPrefix == "" && a.descSprites == nil {
+		return a
+	}
+	desc := *a
+	if a.descSprites != nil {
+		desc.Sprites = a.descSprites
+	}
+	if a.imageData != "" {
+		desc.ImageFilename = a.imageData
+		return &desc
+	}
+	desc.ImageFilename = a.imagePathPrefix + a.ImageFilename
+	if a.AlphaFilename != "" {
+		desc.AlphaFilename = a.imagePathPrefix + a.AlphaFilename
+	}
+	return &desc
+}
+
+// descData returns the set with the template data of each atlas
+func (s *atlasSet) descData() *atlasSet {
+	desc := *s
+	desc.Atlases = make([]*atlas, len(s.Atlases))
+	for i, a := range s.Atlases {
+		desc.Atlases[i] = a.descData()
+	}
+	return &desc
+}
+
+// outputAtlasSet writes a single descriptor describing every
+// atlas in the set using a Multi format
+func outputAtlasSet(descOutputter Outputter, filename string, set *atlasSet, format target.Format) error {
+	return withFile(descOutputter, filename, false, func(writer io.Writer) error {
+		return executeDescTemplate(writer, format, set.descData(), set.jsonStyle)
 	})
 }
+
+// jsonStyle is how the descriptors of JSON formats are reformatted
+type jsonStyle int
+
+const (
+	// jsonAsRendered writes JSON exactly as the template renders it
+	jsonAsRendered jsonStyle = iota
+	jsonIndented
+	jsonMinified
+)
+
+// jsonStyle returns the style of JSON descriptors, see PrettyPrint and Minify
+func (p *Params) jsonStyle() jsonStyle {
+	switch {
+	case p.PrettyPrint:
+		return jsonIndented
+	case p.Minify:
+		return jsonMinified
+	}
+	return jsonAsRendered
+}
+
+// executeDescTemplate renders the descriptor template with the data.
+// The output of JSON formats is reformatted when the style is indented
+// or minified. Other formats, and JSON written as rendered, are streamed
+// to the writer as they are rendered, through a buffer of a fixed size
+// rather than the whole descriptor, eg. target.NDJSON for huge sets.
+func executeDescTemplate(writer io.Writer, format target.Format, data interface{}, style jsonStyle) error {
+	if format.Ext != "json" || style == jsonAsRendered {
+		buffered := bufio.NewWriter(writer)
+		if err := format.Template.Execute(buffered, data); err != nil {
+			return err
+		}
+		return buffered.Flush()
+	}
+
+	rendered := &bytes.Buffer{}
+	if err := format.Template.Execute(rendered, data); err != nil {
+		return err
+	}
+	formatted := &bytes.Buffer{}
+	var err error
+	if style == jsonIndented {
+		err = json.Indent(formatted, rendered.Bytes(), "", "  ")
+		formatted.WriteByte('\n')
+	} else {
+		err = json.Compact(formatted, rendered.Bytes())
+	}
+	if err != nil {
+		return fmt.Errorf("Format '%s' rendered invalid JSON: %s", format.Name, err)
+	}
+	_, err = formatted.WriteTo(writer)
+	return err
+}
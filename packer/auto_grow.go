@@ -0,0 +1,58 @@
+package packer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// packGrowing packs with the parameters, doubling the Width and Height
+// of the atlases whenever the sprites do not fit, see Params.AutoGrow.
+// The parameters are copied for each try, so an Input that can only be
+// read once is not supported.
+func (p *Packer) packGrowing(ctx context.Context, params *Params) (*RunResult, error) {
+	if len(params.AtlasSizes) > 0 {
+		return nil, errors.New("AutoGrow does not support AtlasSizes")
+	}
+	if params.LayoutHint != nil {
+		return nil, errors.New("AutoGrow does not support LayoutHint, the atlases must keep their size")
+	}
+	if params.AutoGrowLimit < 0 {
+		return nil, fmt.Errorf("AutoGrowLimit must not be negative, got %d", params.AutoGrowLimit)
+	}
+	limit := params.AutoGrowLimit
+	if limit == 0 {
+		limit = DefaultAutoGrowLimit
+	}
+
+	grown := *params
+	grown.AutoGrow = false
+	// A try that fails must not leave its atlases behind
+	grown.Transactional = true
+	for {
+		try := grown
+		result, err := p.Pack(ctx, &try)
+		if err == nil || !(errors.Is(err, packing.ErrOutOfRoom) || errors.Is(err, packing.ErrInputTooLarge)) {
+			return result, err
+		}
+		// The defaults of the try are grown from when no size was given
+		if try.Width >= limit && try.Height >= limit {
+			return nil, fmt.Errorf("%w, even with atlases grown to the AutoGrowLimit of %d", err, limit)
+		}
+		grown.Width, grown.Height = growSide(try.Width, limit), growSide(try.Height, limit)
+	}
+}
+
+// growSide doubles a side of the atlases up to the limit, sides already
+// larger than the limit are left as they are
+func growSide(side, limit int) int {
+	if side >= limit {
+		return side
+	}
+	if side *= 2; side > limit {
+		return limit
+	}
+	return side
+}
@@ -0,0 +1,56 @@
+package packer_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/packing"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestAutoGrowDoublesTheAtlasesUntilTheSpritesFit(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:       "atlas",
+		Format:     target.Love,
+		Input:      NewImageStream(t, map[string]image.Image{"big.png": newSolidImage(40, 40, color.White)}),
+		Output:     outputRecorder,
+		Width:      16,
+		Height:     16,
+		MaxAtlases: 1,
+		AutoGrow:   true,
+	}
+
+	result, err := packer.RunWithResult(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	if result.Width != 64 || result.Height != 64 {
+		t.Errorf("Expected the atlases to grow to 64x64 but got %dx%d", result.Width, result.Height)
+	}
+	if _, ok := outputRecorder.Got()["atlas-1.png"]; !ok {
+		t.Errorf("Expected 'atlas-1.png' to be written but got %v", outputRecorder.Order())
+	}
+}
+
+func TestAutoGrowStopsAtTheLimit(t *testing.T) {
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        target.Love,
+		Input:         NewImageStream(t, map[string]image.Image{"big.png": newSolidImage(40, 40, color.White)}),
+		Output:        NewOutputRecorder(),
+		Width:         16,
+		Height:        16,
+		AutoGrow:      true,
+		AutoGrowLimit: 32,
+	}
+
+	_, err := packer.RunWithResult(context.Background(), params)
+	if !errors.Is(err, packing.ErrInputTooLarge) {
+		t.Errorf("Expected ErrInputTooLarge once the atlases reach the AutoGrowLimit but got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package packer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// validateBalance checks that BalanceAcrossAtlases can be honoured
+func (p *Params) validateBalance() error {
+	if p.BalanceAcrossAtlases == 0 {
+		return nil
+	}
+	if p.BalanceAcrossAtlases < 0 {
+		return fmt.Errorf("BalanceAcrossAtlases must not be negative, got %d", p.BalanceAcrossAtlases)
+	}
+	if p.MaxAtlases > 0 && p.BalanceAcrossAtlases > p.MaxAtlases {
+		return fmt.Errorf("BalanceAcrossAtlases (%d) exceeds MaxAtlases (%d)", p.BalanceAcrossAtlases, p.MaxAtlases)
+	}
+	if p.StablePaging {
+		return errors.New("BalanceAcrossAtlases can not be used with StablePaging")
+	}
+	if p.LayoutHint != nil {
+		return errors.New("BalanceAcrossAtlases can not be used with LayoutHint, which already keeps sprites on their atlases")
+	}
+	return nil
+}
+
+// newBalancedPager assigns each unit, in packing order, to the one of the
+// BalanceAcrossAtlases atlases with the least sprite area on it so far
+// that the unit still fits on. The units are packed onto copies of the
+// atlases as they are assigned, so packing each atlas from its units
+// afterwards places them exactly as they were when balancing.
+func newBalancedPager(units []*packUnit, params *Params, strategy Strategy) (*pager, error) {
+	n := params.BalanceAcrossAtlases
+	packers := make([]packing.Packer, n)
+	placed := make([][]packing.Block, n)
+	used := make([]int, n)
+	for i := range packers {
+		packers[i] = newAtlasPacker(params, strategy, i, 0)
+	}
+
+	homes := make([]int, len(units))
+	order := make([]int, n)
+	for u, unit := range units {
+		blocks := make([]packing.Block, len(unit.blocks))
+		area := 0
+		for i, block := range unit.blocks {
+			w, h := block.Size()
+			blocks[i] = &sizeBlock{w, h, unit.group}
+			area += w * h
+		}
+
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return used[order[i]] < used[order[j]] })
+		homes[u] = -1
+		for _, i := range order {
+			if packBlocks(packers[i], blocks) {
+				homes[u] = i
+				placed[i] = append(placed[i], blocks...)
+				used[i] += area
+				break
+			}
+			// Part of the unit may have been placed, so rebuild the atlas without it
+			packers[i] = newAtlasPacker(params, strategy, i, 0)
+			packBlocks(packers[i], placed[i])
+		}
+		if homes[u] < 0 {
+			return nil, fmt.Errorf("The sprites do not fit on the %d atlases of BalanceAcrossAtlases: %w", n, packing.ErrOutOfRoom)
+		}
+	}
+	return newPager(units, homes, n), nil
+}
+
+// packBlocks packs all of the blocks, it returns false as soon as one doesn't fit
+func packBlocks(packer packing.Packer, blocks []packing.Block) bool {
+	for _, block := range blocks {
+		if packer.Pack(block) != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package packer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/packing"
+)
+
+func TestBalanceAcrossAtlasesEqualizesOccupancy(t *testing.T) {
+	images := map[string]image.Image{
+		"big1.png": newSolidImage(32, 32, color.White),
+		"big2.png": newSolidImage(32, 32, color.White),
+	}
+	for i := 0; i < 8; i++ {
+		images[fmt.Sprintf("icon%d.png", i)] = newSolidImage(16, 16, color.White)
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:                 "atlas",
+		Format:               spriteNamesFormat,
+		Input:                NewImageStream(t, images),
+		Output:               outputRecorder,
+		Width:                64,
+		Height:               64,
+		BalanceAcrossAtlases: 2,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	// Every sprite fits a single atlas, balancing splits them evenly
+	got := outputRecorder.Got()
+	for _, filename := range []string{"atlas-1.txt", "atlas-2.txt"} {
+		names := strings.Fields(string(got[filename]))
+		big := 0
+		for _, name := range names {
+			if strings.HasPrefix(name, "big") {
+				big++
+			}
+		}
+		if len(names) != 5 || big != 1 {
+			t.Errorf("Expected %s to hold a big sprite and 4 icons but got %v", filename, names)
+		}
+	}
+	if _, ok := got["atlas-3.txt"]; ok {
+		t.Errorf("Expected only 2 atlases but got %v", outputRecorder.Order())
+	}
+}
+
+func TestBalanceAcrossAtlasesFailsWhenSpritesDoNotFit(t *testing.T) {
+	images := map[string]image.Image{}
+	for i := 0; i < 3; i++ {
+		images[fmt.Sprintf("big%d.png", i)] = newSolidImage(64, 64, color.White)
+	}
+
+	params := &packer.Params{
+		Name:                 "atlas",
+		Format:               spriteNamesFormat,
+		Input:                NewImageStream(t, images),
+		Output:               NewOutputRecorder(),
+		Width:                64,
+		Height:               64,
+		BalanceAcrossAtlases: 2,
+	}
+	err := packer.Run(context.Background(), params)
+	if !errors.Is(err, packing.ErrOutOfRoom) || !strings.Contains(err.Error(), "BalanceAcrossAtlases") {
+		t.Errorf("Expected a BalanceAcrossAtlases out of room error but got '%v'", err)
+	}
+}
@@ -0,0 +1,45 @@
+package packer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RunBatch performs several independent texture packing runs concurrently.
+// Each Params is packed exactly as it would be by RunWithResult and the
+// results are returned in the same order as the given params. A failed
+// run does not interrupt the others, its result is nil and its error is
+// included in the returned error, which joins the errors of every run.
+// The runs share the buffers of a single Packer.
+func RunBatch(ctx context.Context, params []*Params) ([]*RunResult, error) {
+	packer := NewPacker()
+	results := make([]*RunResult, len(params))
+	errs := make([]error, len(params))
+
+	var wg sync.WaitGroup
+	wg.Add(len(params))
+	for i := range params {
+		go func(i int) {
+			defer wg.Done()
+			result, err := packer.Pack(ctx, params[i])
+			if err != nil {
+				errs[i] = fmt.Errorf("Batch run %d (%s) failed: %w", i, batchRunName(params[i]), err)
+				return
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// batchRunName returns a name to identify the params in errors
+func batchRunName(params *Params) string {
+	if params == nil || params.Name == "" {
+		return DefaultAtlasName
+	}
+	return params.Name
+}
@@ -0,0 +1,87 @@
+package packer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestRunBatchPacksEachParamsIndependently(t *testing.T) {
+	ui := NewOutputRecorder()
+	characters := NewOutputRecorder()
+	params := []*packer.Params{
+		{
+			Name:   "ui",
+			Format: target.Love,
+			Input:  packer.NewFilenameStream("./fixtures", "button.png", "button_hover.png"),
+			Output: ui,
+		},
+		{
+			Name:   "characters",
+			Format: target.Starling,
+			Input:  packer.NewFilenameStream("./fixtures", "character_evil.png", "character_hero.png"),
+			Output: characters,
+		},
+	}
+
+	results, err := packer.RunBatch(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Expected batch to succeed without error but got '%s'", err)
+	}
+	if len(results) != len(params) {
+		t.Fatalf("Expected %d results but got %d", len(params), len(results))
+	}
+
+	expected := []struct {
+		recorder *OutputRecorder
+		files    []string
+	}{
+		{ui, []string{"ui-1.png", "ui-1.lua"}},
+		{characters, []string{"characters-1.png", "characters-1.xml"}},
+	}
+	for i, expect := range expected {
+		got := expect.recorder.Got()
+		for _, file := range expect.files {
+			if _, ok := got[file]; !ok {
+				t.Errorf("Expected file '%s' to be outputted", file)
+			}
+			if _, ok := results[i].Hashes[file]; !ok {
+				t.Errorf("Expected result %d to contain a hash for '%s'", i, file)
+			}
+		}
+	}
+}
+
+func TestRunBatchAggregatesErrors(t *testing.T) {
+	good := NewOutputRecorder()
+	params := []*packer.Params{
+		{
+			Name:   "good",
+			Format: target.Love,
+			Input:  packer.NewFilenameStream("./fixtures", "button.png"),
+			Output: good,
+		},
+		{
+			Name:   "bad",
+			Format: target.Love,
+			Input:  packer.NewFilenameStream("./fixtures", "doesnotexist.png"),
+			Output: NewOutputRecorder(),
+		},
+	}
+
+	results, err := packer.RunBatch(context.Background(), params)
+	if err == nil {
+		t.Errorf("Expected batch to fail but error was nil")
+	}
+	if results[0] == nil {
+		t.Errorf("Expected the successful run to have a result")
+	}
+	if results[1] != nil {
+		t.Errorf("Expected the failed run to have a nil result")
+	}
+	if _, ok := good.Got()["good-1.png"]; !ok {
+		t.Errorf("Expected the successful run to output its atlas despite the failed run")
+	}
+}
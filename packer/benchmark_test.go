@@ -1,8 +1,11 @@
 package packer_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image/color"
+	"image/png"
 	"testing"
 
 	_ "image/gif"
@@ -65,3 +68,52 @@ func benchmarkPackX(b *testing.B, w, h int, assets string) {
 		}
 	}
 }
+
+// BenchmarkPackSmallIconSet packs 20 small icons that easily fit a single
+// 2048x2048 atlas, measuring the fixed overhead of a run. Such runs encode
+// their only atlas without the goroutines and channels of the multi atlas
+// loop, but nearly all of the time is spent png encoding the mostly empty
+// atlas, so small sets are best sped up by packing them with Fit.
+func BenchmarkPackSmallIconSet(b *testing.B) {
+	benchmarkPackSmallIconSet(b, func(params *packer.Params) error {
+		return packer.Run(context.Background(), params)
+	})
+}
+
+// BenchmarkPackerPackSmallIconSet packs the icons of BenchmarkPackSmallIconSet
+// with a single Packer, which reuses the atlas image and encoder buffers
+func BenchmarkPackerPackSmallIconSet(b *testing.B) {
+	p := packer.NewPacker()
+	benchmarkPackSmallIconSet(b, func(params *packer.Params) error {
+		_, err := p.Pack(context.Background(), params)
+		return err
+	})
+}
+
+func benchmarkPackSmallIconSet(b *testing.B, run func(params *packer.Params) error) {
+	files := map[string][]byte{}
+	for i := 0; i < 20; i++ {
+		buf := &bytes.Buffer{}
+		icon := newSolidImage(16+i, 32-i, color.NRGBA{uint8(i * 12), 0x80, 0xff, 0xff})
+		if err := png.Encode(buf, icon); err != nil {
+			b.Fatalf("Failed to encode icon: %s", err)
+		}
+		files[fmt.Sprintf("icon_%d.png", i)] = buf.Bytes()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		params := &packer.Params{
+			Name:   "icons",
+			Format: target.Love,
+			Input:  NewBytesStream(files),
+			Output: NewOutputRecorder(),
+			Width:  2048,
+			Height: 2048,
+		}
+		if err := run(params); err != nil {
+			b.Fatalf("%s", err)
+		}
+	}
+}
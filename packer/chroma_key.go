@@ -0,0 +1,50 @@
+package packer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// chromaKey replaces pixels of a key color with transparency
+type chromaKey struct {
+	key       color.NRGBA
+	tolerance int
+}
+
+func newChromaKey(key color.Color, tolerance uint8) *chromaKey {
+	if key == nil {
+		return nil
+	}
+	return &chromaKey{
+		key:       color.NRGBAModel.Convert(key).(color.NRGBA),
+		tolerance: int(tolerance),
+	}
+}
+
+// matches reports whether each channel of the color is
+// within the tolerance of the key color
+func (c *chromaKey) matches(r, g, b uint8) bool {
+	within := func(a, b uint8) bool {
+		d := int(a) - int(b)
+		return -c.tolerance <= d && d <= c.tolerance
+	}
+	return within(r, c.key.R) && within(g, c.key.G) && within(b, c.key.B)
+}
+
+// apply returns a copy of the image where every pixel
+// matching the key color has been made transparent
+func (c *chromaKey) apply(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Src)
+	for i := 0; i < len(out.Pix); i += 4 {
+		if c.matches(out.Pix[i], out.Pix[i+1], out.Pix[i+2]) {
+			out.Pix[i+0] = 0
+			out.Pix[i+1] = 0
+			out.Pix[i+2] = 0
+			out.Pix[i+3] = 0
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestChromaKeyPixelsBecomeTransparent(t *testing.T) {
+	magenta := color.NRGBA{255, 0, 255, 255}
+	nearMagenta := color.NRGBA{250, 4, 252, 255}
+	red := color.NRGBA{255, 0, 0, 255}
+
+	img := newSolidImage(3, 1, magenta)
+	img.Set(1, 0, nearMagenta)
+	img.Set(2, 0, red)
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:               "atlas",
+		Format:             target.Love,
+		Input:              NewImageStream(t, map[string]image.Image{"legacy.png": img}),
+		Output:             outputRecorder,
+		Width:              4,
+		Height:             4,
+		ChromaKey:          magenta,
+		ChromaKeyTolerance: 8,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	atlas, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+	if err != nil {
+		t.Fatalf("Expected output image to be a valid png but got '%s'", err)
+	}
+
+	expected := []color.NRGBA{{}, {}, red}
+	for x, expect := range expected {
+		got := color.NRGBAModel.Convert(atlas.At(x, 0)).(color.NRGBA)
+		if got != expect {
+			t.Errorf("Expected pixel %d to be %v but got %v", x, expect, got)
+		}
+	}
+}
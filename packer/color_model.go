@@ -0,0 +1,104 @@
+package packer
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/psucodervn/lovepac/packing"
+	"golang.org/x/image/draw"
+)
+
+// ColorModel selects the pixel format used when encoding atlas images
+type ColorModel int
+
+const (
+	// ColorModelRGBA writes images with red, green, blue and alpha channels.
+	// This is the default. The png encoder will automatically drop the alpha
+	// channel if every pixel of the atlas is opaque.
+	ColorModelRGBA ColorModel = iota
+	// ColorModelRGB writes images without an alpha channel, any
+	// transparency is composited onto the background color.
+	ColorModelRGB
+	// ColorModelGray writes 8-bit grayscale images without an alpha
+	// channel, any transparency is composited onto the background color.
+	ColorModelGray
+	// ColorModelGrayAlpha writes grayscale images that retain their alpha
+	// channel. The standard png encoder has no gray+alpha mode, so these
+	// are stored as RGBA with equal color channels.
+	ColorModelGrayAlpha
+)
+
+// DefaultBackground is the color that atlases are composited onto when
+// an opaque color model is used and no background has been specified
+var DefaultBackground color.Color = color.Black
+
+// convertColorModel converts the rendered atlas image to the given color model
+func convertColorModel(img *image.NRGBA, model ColorModel, background color.Color) image.Image {
+	switch model {
+	case ColorModelRGB:
+		return flatten(img, background)
+	case ColorModelGray:
+		gray := image.NewGray(img.Bounds())
+		draw.Draw(gray, gray.Bounds(), flatten(img, background), image.ZP, draw.Src)
+		return gray
+	case ColorModelGrayAlpha:
+		out := image.NewNRGBA(img.Bounds())
+		for i := 0; i < len(img.Pix); i += 4 {
+			y := color.GrayModel.Convert(color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], 0xff}).(color.Gray).Y
+			out.Pix[i+0] = y
+			out.Pix[i+1] = y
+			out.Pix[i+2] = y
+			out.Pix[i+3] = img.Pix[i+3]
+		}
+		return out
+	default:
+		return img
+	}
+}
+
+// preservesGray returns true if the atlases of the sprites are drawn
+// in 8-bit grayscale, see Params.PreserveColorModel
+func (p *Params) preservesGray(sprites []packing.Block) bool {
+	if !p.PreserveColorModel || p.PostProcess != nil || p.SplitAlpha ||
+		(p.ColorModel != ColorModelRGBA && p.ColorModel != ColorModelGray) {
+		return false
+	}
+	for _, block := range sprites {
+		if spr := block.(*sprite); !spr.gray || spr.chromaKey != nil {
+			return false
+		}
+	}
+	return len(sprites) > 0
+}
+
+// drawGraySprites draws every sprite into a new grayscale image the size of the atlas
+func (a *atlas) drawGraySprites() (*image.Gray, error) {
+	img := image.NewGray(image.Rect(0, 0, a.Width, a.Height))
+	for i := range a.Sprites {
+		spr := a.Sprites[i].(*sprite)
+		rect := image.Rect(spr.x, spr.y, spr.x+spr.w, spr.y+spr.h)
+
+		sprImg, err := spr.decodeImage()
+		if err != nil {
+			return nil, err
+		}
+
+		if sprImg.Bounds().Size() == rect.Size() {
+			draw.Draw(img, rect, sprImg, sprImg.Bounds().Min, draw.Src)
+		} else {
+			draw.BiLinear.Scale(img, rect, sprImg, sprImg.Bounds(), draw.Src, nil)
+		}
+	}
+	return img, nil
+}
+
+// flatten composites the image onto an opaque background
+func flatten(img *image.NRGBA, background color.Color) *image.RGBA {
+	r, g, b, _ := background.RGBA()
+	opaqueBackground := color.RGBA64{uint16(r), uint16(g), uint16(b), 0xffff}
+
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), image.NewUniform(opaqueBackground), image.ZP, draw.Src)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Over)
+	return out
+}
@@ -0,0 +1,104 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestColorModelIsAppliedToOutputImage(t *testing.T) {
+	sprite := newSolidImage(8, 8, color.NRGBA{0, 0, 255, 0})
+
+	tests := map[packer.ColorModel]color.Model{
+		packer.ColorModelRGBA:      color.NRGBAModel,
+		packer.ColorModelRGB:       color.RGBAModel,
+		packer.ColorModelGray:      color.GrayModel,
+		packer.ColorModelGrayAlpha: color.NRGBAModel,
+	}
+
+	for model, expectedModel := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:       "atlas",
+			Format:     target.Love,
+			Input:      NewImageStream(t, map[string]image.Image{"sprite.png": sprite}),
+			Output:     outputRecorder,
+			Width:      16,
+			Height:     16,
+			ColorModel: model,
+			Background: color.White,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		img, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+		if err != nil {
+			t.Fatalf("Expected output image to be a valid png but got '%s'", err)
+		}
+		if img.ColorModel() != expectedModel {
+			t.Errorf("Expected color model %d to produce an image with model %T but got %T",
+				model, expectedModel.Convert(color.Black), img.ColorModel().Convert(color.Black))
+		}
+
+		// Transparent pixels in an opaque color model should show the background
+		if model == packer.ColorModelRGB || model == packer.ColorModelGray {
+			r, g, b, a := img.At(0, 0).RGBA()
+			if r != 0xffff || g != 0xffff || b != 0xffff || a != 0xffff {
+				t.Errorf("Expected color model %d to composite onto the background but got %v", model, img.At(0, 0))
+			}
+		}
+	}
+}
+
+func TestPreserveColorModelKeepsGrayscaleAtlasesGray(t *testing.T) {
+	mask := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range mask.Pix {
+		mask.Pix[i] = 0x80
+	}
+
+	tests := []struct {
+		name          string
+		images        map[string]image.Image
+		expectedImage string
+	}{
+		{"only masks", map[string]image.Image{"a.png": mask, "b.png": mask}, "*image.Gray"},
+		{"a color sprite", map[string]image.Image{"a.png": mask, "b.png": newSolidImage(8, 8, color.White)}, "*image.NRGBA"},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:               "atlas",
+			Format:             target.Love,
+			Input:              NewImageStream(t, test.images),
+			Output:             outputRecorder,
+			Width:              16,
+			Height:             16,
+			PreserveColorModel: true,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run with %s to succeed without error but got '%s'", test.name, err)
+		}
+
+		img, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+		if err != nil {
+			t.Fatalf("Expected output image to be a valid png but got '%s'", err)
+		}
+		if got := fmt.Sprintf("%T", img); got != test.expectedImage {
+			t.Errorf("Expected the atlas of %s to decode as %s but got %s", test.name, test.expectedImage, got)
+		}
+		if r, _, _, _ := img.At(0, 0).RGBA(); r>>8 != 0x80 {
+			t.Errorf("Expected the mask of %s to keep its value 0x80 but got %v", test.name, img.At(0, 0))
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package packer
+
+import "encoding/binary"
+
+// ColorProfile selects the color space that png atlas images are
+// tagged with, for color managed pipelines that would otherwise guess
+// the color space of untagged images. KTX images are never tagged.
+type ColorProfile int
+
+const (
+	// ColorProfileNone writes untagged images. This is the default.
+	ColorProfileNone ColorProfile = iota
+	// ColorProfileSRGB tags images as sRGB with the perceptual rendering
+	// intent by writing an sRGB chunk, along with the gAMA and cHRM
+	// chunks of sRGB that the png specification recommends for decoders
+	// that don't support the sRGB chunk.
+	ColorProfileSRGB
+)
+
+// The gamma and chromaticities of sRGB, times 100000 as stored by gAMA and cHRM
+const srgbGamma = 45455
+
+var srgbChromaticities = [8]uint32{31270, 32900, 64000, 33000, 30000, 60000, 15000, 6000}
+
+// chunks returns the png chunks that tag an image with the color profile
+func (c ColorProfile) chunks() [][]byte {
+	if c != ColorProfileSRGB {
+		return nil
+	}
+	// 0 is the perceptual rendering intent
+	srgb := pngChunk("sRGB", []byte{0})
+
+	gamma := make([]byte, 4)
+	binary.BigEndian.PutUint32(gamma, srgbGamma)
+
+	chromaticities := make([]byte, 4*len(srgbChromaticities))
+	for i, v := range srgbChromaticities {
+		binary.BigEndian.PutUint32(chromaticities[i*4:], v)
+	}
+	return [][]byte{srgb, pngChunk("gAMA", gamma), pngChunk("cHRM", chromaticities)}
+}
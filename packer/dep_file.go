@@ -0,0 +1,57 @@
+package packer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// depFileEscaper escapes the characters that make treats specially in
+// the file names of a rule
+var depFileEscaper = strings.NewReplacer(" ", "\\ ", "#", "\\#", "$", "$$")
+
+// depFilePath returns the path of an asset as a dependency, the path
+// of the file it was read from when known, otherwise its name
+func depFilePath(asset Asset) string {
+	if source, ok := asset.(SourceAsset); ok {
+		return source.SourcePath()
+	}
+	return asset.Asset()
+}
+
+// outputDepFile writes a Makefile rule making every target depend on every
+// asset, in the format that compilers write for "-MD" dependency files.
+func outputDepFile(outputter Outputter, filename string, targets []string, assets []Asset) error {
+	targets = append([]string{}, targets...)
+	sort.Strings(targets)
+
+	seen := map[string]bool{}
+	var deps []string
+	for _, asset := range assets {
+		dep := depFilePath(asset)
+		if !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	sort.Strings(deps)
+
+	rule := &strings.Builder{}
+	for i, target := range targets {
+		if i > 0 {
+			rule.WriteString(" ")
+		}
+		rule.WriteString(depFileEscaper.Replace(target))
+	}
+	rule.WriteString(":")
+	for _, dep := range deps {
+		fmt.Fprintf(rule, " \\\n  %s", depFileEscaper.Replace(dep))
+	}
+	rule.WriteString("\n")
+
+	return withFile(outputter, filename, false, func(writer io.Writer) error {
+		_, err := io.WriteString(writer, rule.String())
+		return err
+	})
+}
@@ -22,6 +22,31 @@ You can specify maximum width and height of an atlas to conform to
 platform limitations and you can build multiple atlases with a single
 command.
 
+Images named with the Android style ".9.png" suffix are treated as
+nine-patch images. The 1px stretch guides around the image are read
+into the sprite's BorderLeft, BorderTop, BorderRight and BorderBottom
+template values and are stripped from the image before packing.
+
+Sprites can be trimmed of their fully transparent edges with Params.TrimMode.
+The untrimmed size is given to templates as SourceWidth and SourceHeight and
+the offset of the trimmed sprite within it as TrimX and TrimY. TrimSymmetric
+trims opposite edges equally so the center of a sprite is left unchanged.
+
+Vector assets such as SVG files can be packed by providing a Rasterizer
+for their file extension in Params.Rasterizers. The packer does not depend
+on an SVG library itself; a Rasterizer is a small adapter around one, eg.
+oksvg and rasterx. Vector assets are rendered directly at their scaled
+size so they stay crisp at any Scale.
+
+Layered PSD files can be packed with NewPSDStream, which streams every
+visible layer as a sprite named after the PSD file and the layer, eg.
+"hero/sword". Only 8 bit RGB files with raw or RLE compressed layer data
+are supported.
+
+Tiled maps can be packed with NewTMXStream, which streams only the tiles
+of the map's tilesets that the map uses, named after the map file and the
+gid of the tile, eg. "level1/17", so the descriptor maps gids to rects.
+
 The Input and Output parameters have been designed to be highly flexible.
 Simple file system readers and writers are provided out of the box but
 consumers could implement the AssetStreamer and Outputter interfaces to
@@ -0,0 +1,52 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// validateEmbedImage checks that every descriptor format of an
+// EmbedImage run is JSON and that no option needs the image files
+func (p *Params) validateEmbedImage() error {
+	if !p.EmbedImage {
+		return nil
+	}
+	for _, format := range p.descFormats() {
+		if format.Ext != "json" {
+			return fmt.Errorf("EmbedImage is only supported by JSON formats, not '%s'", format.Name)
+		}
+	}
+	if p.SplitAlpha {
+		return errors.New("EmbedImage does not support SplitAlpha")
+	}
+	if p.ReportPath != "" {
+		return errors.New("EmbedImage does not support ReportPath as the report references the image files")
+	}
+	return nil
+}
+
+// mimeType returns the media type of the images of the container format
+func (c ContainerFormat) mimeType() string {
+	if c == ContainerKTX {
+		return "image/ktx"
+	}
+	return "image/png"
+}
+
+// embed encodes the atlas image and keeps it as a data URI that
+// descriptors are given as the ImageFilename, instead of writing it
+func (a *atlas) embed() error {
+	img, err := a.CreateImage()
+	if err != nil {
+		return err
+	}
+	defer a.release(img)
+	buf := &bytes.Buffer{}
+	if err := a.encodeImage(buf, img); err != nil {
+		return err
+	}
+	a.imageData = fmt.Sprintf("data:%s;base64,%s", a.container.mimeType(), base64.StdEncoding.EncodeToString(buf.Bytes()))
+	return nil
+}
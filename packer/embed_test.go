@@ -0,0 +1,106 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// decodeEmbeddedImages returns the images embedded in a phasermulti descriptor
+func decodeEmbeddedImages(t *testing.T, desc []byte) []image.Image {
+	var phaser struct {
+		Textures []struct {
+			Image string `json:"image"`
+		} `json:"textures"`
+	}
+	if err := json.Unmarshal(desc, &phaser); err != nil {
+		t.Fatalf("Expected a JSON descriptor but got '%s'", err)
+	}
+	var images []image.Image
+	for _, texture := range phaser.Textures {
+		const prefix = "data:image/png;base64,"
+		if !strings.HasPrefix(texture.Image, prefix) {
+			t.Fatalf("Expected the image to be a png data URI but got '%.40s'", texture.Image)
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(texture.Image, prefix))
+		if err != nil {
+			t.Fatalf("Expected base64 image data but got '%s'", err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Expected the embedded image to decode but got '%s'", err)
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+func TestEmbedImageWritesOnlyTheDescriptor(t *testing.T) {
+	for _, combine := range []bool{false, true} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: target.PhaserMulti,
+			Input: NewImageStream(t, map[string]image.Image{
+				"red.png":  newSolidImage(8, 8, color.NRGBA{255, 0, 0, 255}),
+				"blue.png": newSolidImage(8, 8, color.NRGBA{0, 0, 255, 255}),
+			}),
+			Output:           outputRecorder,
+			Width:            8,
+			Height:           8,
+			CombineDescFiles: combine,
+			EmbedImage:       true,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		got := outputRecorder.Got()
+		var images []image.Image
+		if combine {
+			if len(got) != 1 {
+				t.Errorf("Expected only the combined descriptor to be written but got %d files", len(got))
+			}
+			images = decodeEmbeddedImages(t, got["atlas.json"])
+		} else {
+			if len(got) != 2 {
+				t.Errorf("Expected only a descriptor per atlas to be written but got %d files", len(got))
+			}
+			images = append(decodeEmbeddedImages(t, got["atlas-1.json"]), decodeEmbeddedImages(t, got["atlas-2.json"])...)
+		}
+		if len(images) != 2 {
+			t.Fatalf("Expected 2 embedded images but got %d", len(images))
+		}
+		for i, img := range images {
+			if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 8 {
+				t.Errorf("Expected embedded image %d to be 8x8 but got %dx%d", i+1, b.Dx(), b.Dy())
+			}
+		}
+	}
+}
+
+func TestEmbedImageRejectsFormatsThatAreNotJSON(t *testing.T) {
+	params := &packer.Params{
+		Name:       "atlas",
+		Format:     target.Love,
+		Input:      NewImageStream(t, map[string]image.Image{"red.png": newSolidImage(8, 8, color.White)}),
+		Output:     NewOutputRecorder(),
+		EmbedImage: true,
+	}
+
+	err := packer.Run(context.Background(), params)
+	expected := "EmbedImage is only supported by JSON formats, not 'love'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected the error '%s' but got '%v'", expected, err)
+	}
+}
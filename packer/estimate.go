@@ -0,0 +1,72 @@
+package packer
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// EstimateMinSize decodes the sizes of the assets of params.Input, as Run
+// would with the same Scale, Padding and other sizing parameters, and
+// returns the smallest power of two square atlas side that could possibly
+// fit them all. It is the square root of the total area of the padded
+// sprites, or the longest side of any sprite if that is longer, rounded up
+// to a power of two. It is a lower bound to pick a sensible Width and
+// Height from, packing the sprites may still need a larger atlas.
+// The Width, Height and AtlasSizes of params are ignored.
+func EstimateMinSize(ctx context.Context, params *Params) (int, error) {
+	if ctx == nil {
+		return 0, errContextNil
+	}
+	if params == nil {
+		return 0, errors.New("Params must not be nil")
+	}
+	if params.Input == nil {
+		return 0, errors.New("Input must not be nil")
+	}
+
+	estimateParams := *params
+	estimateParams.applySensibleDefaults()
+	// Any sprite may fit, the estimate is what decides the atlas size
+	estimateParams.Width, estimateParams.Height = math.MaxInt32, math.MaxInt32
+	estimateParams.AtlasSizes = nil
+
+	sprites, err := readAssetStream(ctx, &estimateParams, newProgressTracker(nil))
+	if err != nil {
+		return 0, err
+	}
+
+	area, longest := 0, 0
+	for _, spr := range sprites {
+		w, h := spr.Size()
+		area += w * h
+		if w > longest {
+			longest = w
+		}
+		if h > longest {
+			longest = h
+		}
+	}
+
+	side := int(math.Ceil(math.Sqrt(float64(area))))
+	if longest > side {
+		side = longest
+	}
+	// Padding overhanging the right and bottom edges is not part of the
+	// atlas, the square is only as much smaller as both of its sides
+	overhangX, overhangY := estimateParams.overhang()
+	if overhangY < overhangX {
+		overhangX = overhangY
+	}
+	side -= overhangX
+	return nextPowerOfTwo(side), nil
+}
+
+// nextPowerOfTwo returns the smallest power of two that is at least n
+func nextPowerOfTwo(n int) int {
+	pot := 1
+	for pot < n {
+		pot *= 2
+	}
+	return pot
+}
@@ -0,0 +1,50 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+)
+
+func TestEstimateMinSize(t *testing.T) {
+	tests := []struct {
+		sizes    []image.Point
+		padding  int
+		expected int
+	}{
+		// The total area is exactly a power of two square
+		{[]image.Point{{16, 16}, {16, 16}, {16, 16}, {16, 16}}, 0, 32},
+		// Padding increases the area past 32x32
+		{[]image.Point{{16, 16}, {16, 16}, {16, 16}, {16, 16}}, 1, 64},
+		// A long sprite needs more than the square root of the area
+		{[]image.Point{{40, 10}}, 0, 64},
+		{[]image.Point{{1, 1}}, 0, 1},
+	}
+
+	for _, test := range tests {
+		images := map[string]image.Image{}
+		for i, size := range test.sizes {
+			images[string(rune('a'+i))+".png"] = newSolidImage(size.X, size.Y, color.White)
+		}
+		params := &packer.Params{
+			Input:   NewImageStream(t, images),
+			Padding: test.padding,
+			// Sprites larger than the atlas size are still estimated
+			Width:  8,
+			Height: 8,
+		}
+
+		got, err := packer.EstimateMinSize(context.Background(), params)
+		if err != nil {
+			t.Errorf("Expected estimating %v to succeed without error but got '%s'", test.sizes, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("Expected the estimate for %v with %d padding to be %d but got %d",
+				test.sizes, test.padding, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// encodeTestJPEG encodes the image as a JPEG with an
+// EXIF APP1 segment recording the given orientation
+func encodeTestJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("Failed to encode test JPEG: %s", err)
+	}
+
+	tiff := &bytes.Buffer{}
+	tiff.WriteString("MM")
+	binary.Write(tiff, binary.BigEndian, uint16(42))
+	binary.Write(tiff, binary.BigEndian, uint32(8))
+	binary.Write(tiff, binary.BigEndian, uint16(1))
+	binary.Write(tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(tiff, binary.BigEndian, uint32(1))
+	binary.Write(tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(tiff, binary.BigEndian, uint32(0))
+
+	app1 := &bytes.Buffer{}
+	app1.Write([]byte{0xff, 0xe1})
+	binary.Write(app1, binary.BigEndian, uint16(2+6+tiff.Len()))
+	app1.WriteString("Exif\x00\x00")
+	app1.Write(tiff.Bytes())
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1.Bytes()...), data[2:]...)
+}
+
+func TestRespectEXIFRotatesJPEGUpright(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+
+	// Stored sideways, the left half becomes the top once rotated clockwise
+	stored := newSolidImage(32, 16, blue)
+	draw.Draw(stored, image.Rect(0, 0, 16, 16), image.NewUniform(red), image.ZP, draw.Src)
+	photo := encodeTestJPEG(t, stored, 6)
+
+	tests := []struct {
+		respectEXIF bool
+		quad        string
+	}{
+		{true, "love.graphics.newQuad(0,0,16,32,64,64)"},
+		{false, "love.graphics.newQuad(0,0,32,16,64,64)"},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:        "atlas",
+			Format:      target.Love,
+			Input:       NewBytesStream(map[string][]byte{"photo.jpg": photo}),
+			Output:      outputRecorder,
+			Width:       64,
+			Height:      64,
+			RespectEXIF: test.respectEXIF,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+		if !strings.Contains(gotStr, test.quad) {
+			t.Errorf("Expected descriptor with RespectEXIF %v to contain '%s' but got\n\n%s", test.respectEXIF, test.quad, gotStr)
+		}
+		if !test.respectEXIF {
+			continue
+		}
+
+		atlas, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+		if err != nil {
+			t.Fatalf("Expected output image to be a valid png but got '%s'", err)
+		}
+		if r, _, b, _ := atlas.At(8, 4).RGBA(); r < 0xf000 || b > 0x1000 {
+			t.Errorf("Expected the top of the upright sprite to be red but got %v", atlas.At(8, 4))
+		}
+		if r, _, b, _ := atlas.At(8, 28).RGBA(); r > 0x1000 || b < 0xf000 {
+			t.Errorf("Expected the bottom of the upright sprite to be blue but got %v", atlas.At(8, 28))
+		}
+	}
+}
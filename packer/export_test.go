@@ -0,0 +1,11 @@
+package packer
+
+import "reflect"
+
+// The types given to descriptor templates, which the sample data that
+// target.Format.Validate executes templates against must mirror
+var (
+	AtlasType    = reflect.TypeOf(atlas{})
+	AtlasSetType = reflect.TypeOf(atlasSet{})
+	SpriteType   = reflect.TypeOf(sprite{})
+)
@@ -6,9 +6,16 @@ import (
 	"golang.org/x/image/draw"
 )
 
-func fastDraw(dst *image.NRGBA, r image.Rectangle, src image.Image) {
+func fastDraw(dst *image.NRGBA, r image.Rectangle, src image.Image, scratch *canvasPool) {
+	// Sprites drawn at their own size are copied, as the scaler buffers
+	// eight times as many bytes as the image has pixels
+	if src.Bounds().Size() == r.Size() {
+		draw.Draw(dst, r, src, src.Bounds().Min, draw.Src)
+		return
+	}
 	w, h := r.Dx(), r.Dy()
-	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	img := scratch.get(w, h)
+	defer scratch.put(img)
 	draw.BiLinear.Scale(img, image.Rect(0, 0, w, h), src, src.Bounds(), draw.Src, nil)
 	drawCopySrc(dst, r, img, image.ZP)
 }
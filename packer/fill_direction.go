@@ -0,0 +1,27 @@
+package packer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// validateFillDirection checks that the FillDirection is known and that
+// the atlases are packed by a packer that fills it
+func (p *Params) validateFillDirection() error {
+	switch p.FillDirection {
+	case packing.FillFree:
+		return nil
+	case packing.FillRows, packing.FillColumns:
+	default:
+		return fmt.Errorf("Unknown FillDirection %s", p.FillDirection)
+	}
+	if p.Fit {
+		return errors.New("FillDirection does not support Fit, the shelves fill atlases of a fixed size")
+	}
+	if p.LayoutHint != nil {
+		return errors.New("FillDirection does not support LayoutHint, the sprites of the hint keep their positions")
+	}
+	return nil
+}
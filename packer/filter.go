@@ -0,0 +1,40 @@
+package packer
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// validateFilters checks that the Include and Exclude patterns are valid
+func (p *Params) validateFilters() error {
+	for _, patterns := range [][]string{p.Include, p.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("Invalid filter pattern '%s': %s", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// includes returns true if the asset is packed according to the Include
+// and Exclude patterns, an asset matching an Exclude pattern never is
+func (p *Params) includes(assetName string) bool {
+	assetPath := filepath.ToSlash(assetName)
+	if matchesAny(p.Exclude, assetPath) {
+		return false
+	}
+	return len(p.Include) == 0 || matchesAny(p.Include, assetPath)
+}
+
+// matchesAny returns true if the path matches any of the patterns
+func matchesAny(patterns []string, assetPath string) bool {
+	for _, pattern := range patterns {
+		// The patterns were validated by validateFilters
+		if ok, _ := path.Match(pattern, assetPath); ok {
+			return true
+		}
+	}
+	return false
+}
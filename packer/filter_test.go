@@ -0,0 +1,80 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestIncludeAndExcludeFilterAssets(t *testing.T) {
+	images := map[string]image.Image{
+		"ui/button.png":   newSolidImage(2, 2, color.White),
+		"ui/wip/icon.png": newSolidImage(2, 2, color.White),
+		"ui/panel.jpg":    newSolidImage(2, 2, color.White),
+		"hero.png":        newSolidImage(2, 2, color.White),
+	}
+	format := target.Format{
+		Name:     "names",
+		Template: template.Must(template.New("names").Parse("{{range .Sprites}}{{.DisplayName}} {{end}}")),
+		Ext:      "txt",
+	}
+
+	var tests = []struct {
+		name             string
+		include, exclude []string
+		expected         []string
+	}{
+		{"everything", nil, nil, []string{"hero", "ui/button", "ui/panel", "ui/wip/icon"}},
+		{"include", []string{"ui/*.png", "hero.png"}, nil, []string{"hero", "ui/button"}},
+		{"exclude", nil, []string{"ui/wip/*"}, []string{"hero", "ui/button", "ui/panel"}},
+		// Exclude takes precedence over Include
+		{"both", []string{"ui/*"}, []string{"ui/*.jpg"}, []string{"ui/button"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputRecorder := NewOutputRecorder()
+			params := &packer.Params{
+				Name:    "atlas",
+				Format:  format,
+				Input:   NewImageStream(t, images),
+				Output:  outputRecorder,
+				Width:   16,
+				Height:  16,
+				Include: tt.include,
+				Exclude: tt.exclude,
+			}
+
+			if err := packer.Run(context.Background(), params); err != nil {
+				t.Fatalf("Expected run to succeed without error but got '%s'", err)
+			}
+
+			got := strings.Fields(string(outputRecorder.Got()["atlas-1.txt"]))
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected the sprites %v to be packed but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInvalidFilterPatternIsRejected(t *testing.T) {
+	params := &packer.Params{
+		Format:  target.Love,
+		Input:   NewImageStream(t, map[string]image.Image{}),
+		Output:  NewOutputRecorder(),
+		Exclude: []string{"ui/["},
+	}
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "ui/[") {
+		t.Errorf("Expected an error naming the invalid pattern but got '%v'", err)
+	}
+}
@@ -0,0 +1,100 @@
+package packer
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"path"
+	"strings"
+	"sync"
+)
+
+// explodeGIF returns a sprite for each frame of an animated GIF that
+// was measured as the given sprite. Frames are named after the asset
+// with a frame number suffix and retain the frame delay. GIFs with a
+// single frame are returned unchanged. The frames share the decoded
+// GIF and are composited onto the canvas when they are drawn, so that
+// long animations don't hold a full canvas for every frame.
+func explodeGIF(spr *sprite) ([]*sprite, error) {
+	assetReader, err := spr.Asset.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read asset '%s': %s", spr.path, err)
+	}
+	defer assetReader.Close()
+	g, err := gif.DecodeAll(assetReader)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode asset '%s': %s", spr.path, err)
+	}
+	if len(g.Image) <= 1 {
+		return []*sprite{spr}, nil
+	}
+
+	ext := path.Ext(spr.path)
+	base := strings.TrimSuffix(spr.path, ext)
+	animation := &gifAnimation{g: g}
+	sprites := make([]*sprite, len(g.Image))
+	for i := range g.Image {
+		frameSprite := *spr
+		frameSprite.path = fmt.Sprintf("%s_%d%s", base, i, ext)
+		frameSprite.animation = animation
+		frameSprite.frame = i
+		frameSprite.delay = g.Delay[i] * 10
+		sprites[i] = &frameSprite
+	}
+	return sprites, nil
+}
+
+// gifAnimation composites the frames of a GIF onto its full canvas,
+// honouring the disposal method of the previous frames. Only the canvas
+// of the last composited frame is kept, frames are composited again
+// from the first when an earlier frame is requested.
+type gifAnimation struct {
+	mu     sync.Mutex
+	g      *gif.GIF
+	canvas *image.NRGBA
+	// next is the frame that is drawn onto the canvas next
+	next int
+}
+
+// frame returns a copy of the canvas with the frame composited onto it
+func (a *gifAnimation) frame(i int) *image.NRGBA {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.canvas == nil || i < a.next {
+		a.canvas = image.NewNRGBA(image.Rect(0, 0, a.g.Config.Width, a.g.Config.Height))
+		a.next = 0
+	}
+	var composited *image.NRGBA
+	for ; a.next <= i; a.next++ {
+		frame := a.g.Image[a.next]
+		var disposal byte
+		if a.next < len(a.g.Disposal) {
+			disposal = a.g.Disposal[a.next]
+		}
+
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(a.canvas)
+		}
+
+		draw.Draw(a.canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if a.next == i {
+			composited = cloneNRGBA(a.canvas)
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(a.canvas, frame.Bounds(), image.Transparent, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			a.canvas = previous
+		}
+	}
+	return composited
+}
+
+func cloneNRGBA(img *image.NRGBA) *image.NRGBA {
+	clone := image.NewNRGBA(img.Rect)
+	copy(clone.Pix, img.Pix)
+	return clone
+}
@@ -0,0 +1,124 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func newAnimatedGIF(t *testing.T, frames int) []byte {
+	palette := color.Palette{color.Transparent, color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+		frame.SetColorIndex(i, i, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, anim); err != nil {
+		t.Fatalf("Failed to encode test gif: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAnimatedGIFs(t *testing.T) {
+	tests := map[bool][]string{
+		false: {"walk"},
+		true:  {"walk_0", "walk_1", "walk_2"},
+	}
+
+	for explode, expectedNames := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:              "atlas",
+			Format:            target.Love,
+			Input:             NewBytesStream(map[string][]byte{"walk.gif": newAnimatedGIF(t, 3)}),
+			Output:            outputRecorder,
+			Width:             64,
+			Height:            64,
+			ExplodeAnimations: explode,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+		if n := strings.Count(gotStr, "love.graphics.newQuad"); n != len(expectedNames) {
+			t.Errorf("Expected %d quads with ExplodeAnimations=%t but got %d", len(expectedNames), explode, n)
+		}
+		for _, name := range expectedNames {
+			if !strings.Contains(gotStr, "quads['"+name+"']") {
+				t.Errorf("Expected descriptor to contain a quad for '%s' but got\n\n%s", name, gotStr)
+			}
+		}
+	}
+}
+
+func TestExplodedGIFFramesAreCompositedWithTheirDisposal(t *testing.T) {
+	positions := target.Format{
+		Name:     "positions",
+		Template: template.Must(template.New("positions").Parse("{{range .Sprites}}{{.Name}} {{.Left}} {{.Top}}\n{{end}}")),
+		Ext:      "txt",
+	}
+	for _, disposal := range []byte{gif.DisposalNone, gif.DisposalBackground} {
+		// Reading the palettes composites every frame before they are drawn
+		for _, palettes := range []bool{false, true} {
+			anim, err := gif.DecodeAll(bytes.NewReader(newAnimatedGIF(t, 4)))
+			if err != nil {
+				t.Fatalf("Failed to decode test gif: %s", err)
+			}
+			anim.Disposal = []byte{disposal, disposal, disposal, disposal}
+			buf := &bytes.Buffer{}
+			if err := gif.EncodeAll(buf, anim); err != nil {
+				t.Fatalf("Failed to encode test gif: %s", err)
+			}
+
+			outputRecorder := NewOutputRecorder()
+			params := &packer.Params{
+				Name:              "atlas",
+				Format:            positions,
+				Input:             NewBytesStream(map[string][]byte{"walk.gif": buf.Bytes()}),
+				Output:            outputRecorder,
+				Width:             64,
+				Height:            64,
+				ExplodeAnimations: true,
+				EmitPalettes:      palettes,
+			}
+			if err := packer.Run(context.Background(), params); err != nil {
+				t.Fatalf("Expected run to succeed without error but got '%s'", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+			if err != nil {
+				t.Fatalf("Failed to decode the atlas image: %s", err)
+			}
+			for _, line := range strings.Split(strings.TrimSpace(string(outputRecorder.Got()["atlas-1.txt"])), "\n") {
+				var frame, left, top int
+				if _, err := fmt.Sscanf(line, "walk_%d %d %d", &frame, &left, &top); err != nil {
+					t.Fatalf("Failed to read the position of '%s': %s", line, err)
+				}
+				// Each frame sets the pixel on the diagonal at its index,
+				// disposing the background leaves only that pixel drawn
+				for i := 0; i < 4; i++ {
+					drawn := i == frame || (disposal == gif.DisposalNone && i < frame)
+					if _, _, _, a := img.At(left+i, top+i).RGBA(); (a != 0) != drawn {
+						t.Errorf("Expected pixel %d of frame %d with disposal %d and EmitPalettes=%t drawn to be %t",
+							i, frame, disposal, palettes, drawn)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+// TestFormatsMatchGoldenFiles packs the fixtures in each format and
+// compares the descriptor with testdata/golden/<format>.golden, so that
+// the templates are checked against the data the packer gives them.
+// Run the tests with -update to regenerate the golden files after an
+// intended change.
+func TestFormatsMatchGoldenFiles(t *testing.T) {
+	formats := []target.Format{target.Love, target.LoveModule, target.Starling, target.Spine, target.Unity,
+		target.PhaserMulti, target.Binary, target.TSDef, target.TextureArray, target.NDJSON}
+
+	for _, format := range formats {
+		t.Run(format.Name, func(t *testing.T) {
+			outputRecorder := NewOutputRecorder()
+			params := &packer.Params{
+				Name:   "golden",
+				Format: format,
+				Input:  packer.NewFileStream("./fixtures"),
+				Output: outputRecorder,
+				Width:  1024,
+				Height: 1024,
+			}
+			if err := packer.Run(context.Background(), params); err != nil {
+				t.Fatalf("Expected run to succeed without error but got '%s'", err)
+			}
+
+			// The fixtures fit a single atlas, described by a single file
+			desc := "golden-1." + format.Ext
+			got, ok := outputRecorder.Got()[desc]
+			if !ok {
+				t.Fatalf("Expected '%s' to be written but got %v", desc, outputRecorder.Order())
+			}
+
+			golden := filepath.Join("testdata", "golden", format.Name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatalf("Failed to update golden file: %s", err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read golden file, run with -update to create it: %s", err)
+			}
+			if !bytes.Equal(got, expected) {
+				t.Errorf("Output does not match '%s'\n\nExpected:\n%s\nGot:\n%s", golden, expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package packer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// groupName returns the group of an asset, the first depth directories
+// of its name joined by "/". Assets in fewer directories are grouped by
+// as many as they have, assets in the input root have an empty group.
+func groupName(assetName string, depth int) string {
+	segments := strings.Split(filepath.ToSlash(assetName), "/")
+	dirs := segments[:len(segments)-1]
+	if len(dirs) > depth {
+		dirs = dirs[:depth]
+	}
+	return strings.Join(dirs, "/")
+}
+
+// assetGroup is a group of assets packed by a separate run, the assets
+// in the directory dir with the extension ext of SeparateByExtension
+type assetGroup struct {
+	dir, ext string
+}
+
+// String returns the directory and extension of the group as a pattern,
+// eg. "ui/*.png"
+func (g assetGroup) String() string {
+	if g.ext == "" {
+		if g.dir == "" {
+			return "."
+		}
+		return g.dir
+	}
+	return path.Join(g.dir, "*."+g.ext)
+}
+
+// groupExt returns the extension group of an asset, the lower case
+// extension of its name without the dot
+func groupExt(assetName string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(assetName), "."))
+}
+
+// groupAtlasName returns the name of the atlas set packed for a group,
+// which is the group itself for the folders of FolderAsAtlas. The
+// extension of the group follows the name, eg. "atlas-ui-png".
+func groupAtlasName(name string, group assetGroup, folderAsAtlas bool) string {
+	switch {
+	case group.dir == "":
+	case folderAsAtlas:
+		name = group.dir
+	default:
+		name = fmt.Sprintf("%s-%s", name, strings.Replace(group.dir, "/", "-", -1))
+	}
+	if group.ext != "" {
+		name = fmt.Sprintf("%s-%s", name, group.ext)
+	}
+	return name
+}
+
+// groupsAssets returns true if the assets are split into groups
+// that are each packed by a separate run, see runGroups
+func (p *Params) groupsAssets() bool {
+	return p.GroupByPrefixDepth > 0 || p.FolderAsAtlas || p.SeparateByExtension
+}
+
+// folderAsset is an asset of a FolderAsAtlas folder, named without the folder
+type folderAsset struct {
+	inner Asset
+	name  string
+}
+
+// Reader implements the Asset interface
+func (a *folderAsset) Reader() (io.ReadCloser, error) { return a.inner.Reader() }
+
+// Asset implements the Asset interface
+func (a *folderAsset) Asset() string { return a.name }
+
+// SourcePath implements the SourceAsset interface, assets
+// that are not read from files are identified by their name
+func (a *folderAsset) SourcePath() string {
+	if source, ok := a.inner.(SourceAsset); ok {
+		return source.SourcePath()
+	}
+	return a.inner.Asset()
+}
+
+// maxConcurrentGroups limits the number of groups that are packed at once,
+// so that the next group is decoded and packed while the atlases of the
+// previous group are still being encoded
+const maxConcurrentGroups = 2
+
+// runGroups partitions the input assets by groupName, and by groupExt for
+// SeparateByExtension, and packs each group
+// as an independent run, merging the results of every run. Up to
+// maxConcurrentGroups groups are packed concurrently, in order of name.
+// Every group writes to the output of the run, so the groups of a
+// Transactional run are committed together only if all groups succeed.
+func (p *Packer) runGroups(ctx context.Context, params *Params, output Outputter, commit func() error) (*RunResult, error) {
+	depth := params.GroupByPrefixDepth
+	if params.FolderAsAtlas {
+		depth = 1
+	}
+	groups := map[assetGroup][]Asset{}
+	assets, errc := params.Input.AssetStream(ctx)
+	for asset := range assets {
+		if !params.includes(asset.Asset()) {
+			continue
+		}
+		group := assetGroup{dir: groupName(asset.Asset(), depth)}
+		if params.SeparateByExtension {
+			group.ext = groupExt(asset.Asset())
+		}
+		if params.FolderAsAtlas && group.dir != "" {
+			name := strings.TrimPrefix(filepath.ToSlash(asset.Asset()), group.dir+"/")
+			asset = &folderAsset{inner: asset, name: name}
+		}
+		groups[group] = append(groups[group], asset)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	names := make([]assetGroup, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].dir != names[j].dir {
+			return names[i].dir < names[j].dir
+		}
+		return names[i].ext < names[j].ext
+	})
+	// The atlases of one group would be overwritten by another's
+	atlasGroups := make(map[string]assetGroup, len(names))
+	for _, group := range names {
+		atlasName := groupAtlasName(params.Name, group, params.FolderAsAtlas)
+		if other, ok := atlasGroups[atlasName]; ok {
+			if params.FolderAsAtlas && other.dir == "" {
+				return nil, fmt.Errorf("FolderAsAtlas can not pack the folder '%s' as its atlases are named like those of the input root", group.dir)
+			}
+			return nil, fmt.Errorf("The groups '%s' and '%s' would both be packed into the atlases named '%s'", other, group, atlasName)
+		}
+		atlasGroups[atlasName] = group
+	}
+
+	// The progress of concurrent groups is still delivered one at a time
+	onProgress := params.OnProgress
+	if onProgress != nil {
+		var mu sync.Mutex
+		onProgress = func(p Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			params.OnProgress(p)
+		}
+	}
+
+	ctx, cancelCtx := context.WithCancel(ctx)
+	defer cancelCtx()
+	results := make([]*RunResult, len(names))
+	var failure error
+	var failureOnce sync.Once
+	slots := make(chan struct{}, maxConcurrentGroups)
+	wg := &sync.WaitGroup{}
+	for i, group := range names {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		groupParams := *params
+		groupParams.Name = groupAtlasName(params.Name, group, params.FolderAsAtlas)
+		groupParams.Input = newAssetSliceStream(groups[group])
+		groupParams.Output = output
+		groupParams.Transactional = false
+		groupParams.GroupByPrefixDepth = 0
+		groupParams.FolderAsAtlas = false
+		groupParams.SeparateByExtension = false
+		// The assets were filtered by their full paths before they were partitioned
+		groupParams.Include = nil
+		groupParams.Exclude = nil
+		groupParams.ExtensionContainers = nil
+		if container, ok := params.ExtensionContainers["."+group.ext]; ok {
+			groupParams.ContainerFormat = container
+		}
+		// The whole run is retried when it grows
+		groupParams.AutoGrow = false
+		groupParams.DepFile = ""
+		groupParams.OnProgress = onProgress
+
+		wg.Add(1)
+		go func(i int, group assetGroup) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := p.Pack(ctx, &groupParams)
+			if err != nil {
+				// The first failure cancels the other groups
+				failureOnce.Do(func() {
+					failure = fmt.Errorf("Failed to pack group '%s': %w", group, err)
+					cancelCtx()
+				})
+				return
+			}
+			results[i] = result
+		}(i, group)
+	}
+	wg.Wait()
+	if failure != nil {
+		return nil, failure
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &RunResult{Hashes: map[string]string{}}
+	for _, groupResult := range results {
+		for filename, hash := range groupResult.Hashes {
+			result.Hashes[filename] = hash
+		}
+	}
+
+	if params.DepFile != "" {
+		var assets []Asset
+		targets := make([]string, 0, len(result.Hashes))
+		for _, group := range names {
+			assets = append(assets, groups[group]...)
+		}
+		for filename := range result.Hashes {
+			targets = append(targets, filename)
+		}
+		outputter := newHashingOutputter(output)
+		if err := outputDepFile(outputter, params.DepFile, targets, assets); err != nil {
+			return nil, err
+		}
+		result.Hashes[params.DepFile] = outputter.Hashes()[params.DepFile]
+	}
+
+	if err := commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newAssetSliceStream creates an asset streamer that streams the given assets
+func newAssetSliceStream(assets []Asset) AssetStreamer {
+	return AssetStreamerFunc(func(ctx context.Context) (<-chan Asset, <-chan error) {
+		stream := make(chan Asset)
+		errc := make(chan error, 1)
+
+		go func() {
+			defer close(stream)
+			defer close(errc)
+
+			for _, asset := range assets {
+				select {
+				case stream <- asset:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+		}()
+
+		return stream, errc
+	})
+}
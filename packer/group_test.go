@@ -0,0 +1,346 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestGroupByPrefixDepthPacksEachGroupSeparately(t *testing.T) {
+	sprite := newSolidImage(8, 8, color.White)
+	images := map[string]image.Image{
+		"ui/button.png":         sprite,
+		"ui/icons/star.png":     sprite,
+		"tiles/grass.png":       sprite,
+		"tiles/water/waves.png": sprite,
+		"logo.png":              sprite,
+	}
+
+	tests := map[int]map[string][]string{
+		1: {
+			"atlas-ui-1.lua":    {"button", "star"},
+			"atlas-tiles-1.lua": {"grass", "waves"},
+			"atlas-1.lua":       {"logo"},
+		},
+		2: {
+			"atlas-ui-1.lua":          {"button"},
+			"atlas-ui-icons-1.lua":    {"star"},
+			"atlas-tiles-1.lua":       {"grass"},
+			"atlas-tiles-water-1.lua": {"waves"},
+			"atlas-1.lua":             {"logo"},
+		},
+	}
+
+	for depth, expected := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:               "atlas",
+			Format:             target.Love,
+			Input:              NewImageStream(t, images),
+			Output:             outputRecorder,
+			Width:              64,
+			Height:             64,
+			GroupByPrefixDepth: depth,
+		}
+
+		result, err := packer.RunWithResult(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Expected run with depth %d to succeed without error but got '%s'", depth, err)
+		}
+
+		got := outputRecorder.Got()
+		if len(got) != len(expected)*2 {
+			t.Errorf("Expected %d files with depth %d but got %d", len(expected)*2, depth, len(got))
+		}
+		for desc, names := range expected {
+			buf, ok := got[desc]
+			if !ok {
+				t.Errorf("Expected '%s' to be written with depth %d", desc, depth)
+				continue
+			}
+			if _, ok := got[strings.TrimSuffix(desc, ".lua")+".png"]; !ok {
+				t.Errorf("Expected an image to accompany '%s' with depth %d", desc, depth)
+			}
+			gotStr := string(buf)
+			if n := strings.Count(gotStr, "love.graphics.newQuad"); n != len(names) {
+				t.Errorf("Expected '%s' to contain %d sprites but got %d", desc, len(names), n)
+			}
+			for _, name := range names {
+				if !strings.Contains(gotStr, "quads['"+name+"']") {
+					t.Errorf("Expected '%s' to contain '%s' but got\n\n%s", desc, name, gotStr)
+				}
+			}
+			if _, ok := result.Hashes[desc]; !ok {
+				t.Errorf("Expected the result to include a hash of '%s'", desc)
+			}
+		}
+	}
+}
+
+func TestGroupByPrefixDepthReportsTheFailedGroup(t *testing.T) {
+	sprite := newSolidImage(8, 8, color.White)
+	images := map[string]image.Image{
+		"a/one.png": sprite,
+		"b/big.png": newSolidImage(128, 128, color.White),
+		"c/two.png": sprite,
+		"d/six.png": sprite,
+	}
+
+	// Progress is delivered one call at a time even though groups are concurrent
+	var calls, concurrent int32
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:               "atlas",
+		Format:             target.Love,
+		Input:              NewImageStream(t, images),
+		Output:             outputRecorder,
+		Width:              64,
+		Height:             64,
+		GroupByPrefixDepth: 1,
+		Transactional:      true,
+		OnProgress: func(p packer.Progress) {
+			if atomic.AddInt32(&calls, 1) > 1 {
+				atomic.StoreInt32(&concurrent, 1)
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&calls, -1)
+		},
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "group 'b'") {
+		t.Errorf("Expected an error naming the group that failed but got '%v'", err)
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected nothing to be written when a group fails but got %d files", len(got))
+	}
+	if atomic.LoadInt32(&concurrent) != 0 {
+		t.Errorf("Expected OnProgress never to be called concurrently")
+	}
+}
+
+func TestFolderAsAtlasPacksEachFolderIntoAtlasesNamedAfterIt(t *testing.T) {
+	sprite := newSolidImage(8, 8, color.White)
+	images := map[string]image.Image{
+		"ui/button.png":     sprite,
+		"ui/icons/star.png": sprite,
+		"tiles/grass.png":   sprite,
+		"logo.png":          sprite,
+	}
+	format := target.Format{
+		Name:     "names",
+		Template: template.Must(template.New("names").Parse("{{range .Sprites}}{{.DisplayName}} {{end}}")),
+		Ext:      "txt",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        format,
+		Input:         NewImageStream(t, images),
+		Output:        outputRecorder,
+		Width:         64,
+		Height:        64,
+		FolderAsAtlas: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	// Sprite names drop the folder that names their atlas
+	expected := map[string][]string{
+		"ui-1.txt":    {"button", "icons/star"},
+		"tiles-1.txt": {"grass"},
+		"atlas-1.txt": {"logo"},
+	}
+	got := outputRecorder.Got()
+	for desc, names := range expected {
+		buf, ok := got[desc]
+		if !ok {
+			t.Errorf("Expected '%s' to be written but got %v", desc, outputRecorder.Order())
+			continue
+		}
+		sprites := strings.Fields(string(buf))
+		sort.Strings(sprites)
+		if !reflect.DeepEqual(sprites, names) {
+			t.Errorf("Expected '%s' to describe %v but got %v", desc, names, sprites)
+		}
+	}
+}
+
+func TestFolderAsAtlasFiltersAssetsByTheirFullPaths(t *testing.T) {
+	sprite := newSolidImage(8, 8, color.White)
+	images := map[string]image.Image{
+		"ui/button.png":   sprite,
+		"ui/wip.png":      sprite,
+		"tiles/grass.png": sprite,
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        spriteNamesFormat,
+		Input:         NewImageStream(t, images),
+		Output:        outputRecorder,
+		Width:         64,
+		Height:        64,
+		FolderAsAtlas: true,
+		Include:       []string{"ui/*"},
+		Exclude:       []string{"ui/wip.png"},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	if desc := string(got["ui-1.txt"]); desc != "button\n" {
+		t.Errorf("Expected the ui atlas to describe only the included sprite but got %q", desc)
+	}
+	if _, ok := got["tiles-1.txt"]; ok {
+		t.Errorf("Expected no atlases of the folder that is not included")
+	}
+}
+
+func TestSeparateByExtensionPacksEachExtensionIntoItsOwnAtlases(t *testing.T) {
+	encodePNG := func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }
+	encodeJPEG := func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }
+	files := encodeTestImages(t, map[string]func(*bytes.Buffer, image.Image) error{
+		"icon.png":       encodePNG,
+		"logo.PNG":       encodePNG,
+		"photo.jpg":      encodeJPEG,
+		"ui/button.png":  encodePNG,
+		"ui/splash.jpg":  encodeJPEG,
+		"ui/banner.jpeg": encodeJPEG,
+	})
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:                 "atlas",
+		Format:               spriteNamesFormat,
+		Input:                NewBytesStream(files),
+		Output:               outputRecorder,
+		Width:                64,
+		Height:               64,
+		SortDescriptorByName: true,
+		FolderAsAtlas:        true,
+		SeparateByExtension:  true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expected := map[string]string{
+		"atlas-png-1.txt": "icon\nlogo\n",
+		"atlas-jpg-1.txt": "photo\n",
+		"ui-png-1.txt":    "button\n",
+		"ui-jpg-1.txt":    "splash\n",
+		"ui-jpeg-1.txt":   "banner\n",
+	}
+	got := outputRecorder.Got()
+	for desc, names := range expected {
+		if buf, ok := got[desc]; !ok {
+			t.Errorf("Expected '%s' to be written but got %v", desc, outputRecorder.Order())
+		} else if string(buf) != names {
+			t.Errorf("Expected '%s' to describe %q but got %q", desc, names, buf)
+		}
+	}
+	if _, ok := got["atlas-1.txt"]; ok {
+		t.Errorf("Expected no atlases of every extension to be written")
+	}
+}
+
+func TestExtensionContainersSetTheContainerOfEachExtension(t *testing.T) {
+	encodePNG := func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }
+	encodeJPEG := func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }
+	files := encodeTestImages(t, map[string]func(*bytes.Buffer, image.Image) error{
+		"icon.png":  encodePNG,
+		"photo.jpg": encodeJPEG,
+	})
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:                "atlas",
+		Format:              target.Love,
+		Input:               NewBytesStream(files),
+		Output:              outputRecorder,
+		Width:               64,
+		Height:              64,
+		SeparateByExtension: true,
+		ExtensionContainers: map[string]packer.ContainerFormat{".jpg": packer.ContainerKTX},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	for _, filename := range []string{"atlas-png-1.png", "atlas-jpg-1.ktx"} {
+		if _, ok := got[filename]; !ok {
+			t.Errorf("Expected '%s' to be written but got %v", filename, outputRecorder.Order())
+		}
+	}
+}
+
+func TestExtensionContainersWithoutSeparateByExtensionResultsInError(t *testing.T) {
+	params := &packer.Params{
+		Name:                "atlas",
+		Format:              target.Love,
+		Input:               NewImageStream(t, map[string]image.Image{"a.png": newSolidImage(8, 8, color.White)}),
+		Output:              NewOutputRecorder(),
+		ExtensionContainers: map[string]packer.ContainerFormat{".png": packer.ContainerKTX},
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "only supported with SeparateByExtension") {
+		t.Errorf("Expected ExtensionContainers to be rejected without SeparateByExtension but got '%v'", err)
+	}
+}
+
+func TestSeparateByExtensionRejectsGroupsNamedLikeAnother(t *testing.T) {
+	sprite := newSolidImage(8, 8, color.White)
+	params := &packer.Params{
+		Name:                "atlas",
+		Format:              target.Love,
+		Input:               NewImageStream(t, map[string]image.Image{"png/a": sprite, "b.png": sprite}),
+		Output:              NewOutputRecorder(),
+		GroupByPrefixDepth:  1,
+		SeparateByExtension: true,
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "'atlas-png'") {
+		t.Errorf("Expected an error naming the atlases of both groups but got '%v'", err)
+	}
+}
+
+func TestFolderAsAtlasRejectsAFolderNamedLikeTheRootAtlases(t *testing.T) {
+	sprite := newSolidImage(8, 8, color.White)
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        target.Love,
+		Input:         NewImageStream(t, map[string]image.Image{"atlas/a.png": sprite, "b.png": sprite}),
+		Output:        NewOutputRecorder(),
+		FolderAsAtlas: true,
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "'atlas'") {
+		t.Errorf("Expected an error naming the conflicting folder but got '%v'", err)
+	}
+}
@@ -0,0 +1,36 @@
+package packer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	// Register the decoders of the common image types so that assets
+	// decode without the caller importing them, image/png and image/gif
+	// are registered by the packer's own imports
+	_ "image/jpeg"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+// checkAllowedType returns an error if AllowedTypes is set and the asset
+// is not one of the types. The type of an asset is the format name that
+// image.DecodeConfig reports, eg. "jpeg", or the file extension without
+// its dot for rasterized assets, eg. "svg".
+func (p *Params) checkAllowedType(assetPath, format string, rasterized bool) error {
+	if len(p.AllowedTypes) == 0 {
+		return nil
+	}
+	if rasterized {
+		format = strings.TrimPrefix(strings.ToLower(path.Ext(assetPath)), ".")
+	}
+	for _, t := range p.AllowedTypes {
+		// jpg is accepted for jpeg as it is the more common extension
+		if strings.EqualFold(t, format) || (format == "jpeg" && strings.EqualFold(t, "jpg")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Asset '%s' is a %s image, which is not one of the AllowedTypes (%s)",
+		assetPath, format, strings.Join(p.AllowedTypes, ", "))
+}
@@ -0,0 +1,101 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"text/template"
+
+	"golang.org/x/image/bmp"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+var spriteNamesFormat = target.Format{
+	Name:     "names",
+	Template: template.Must(template.New("names").Parse("{{range .Sprites}}{{.DisplayName}}\n{{end}}")),
+	Ext:      "txt",
+}
+
+// encodeTestImages encodes a solid white image with each encoder
+func encodeTestImages(t *testing.T, encoders map[string]func(*bytes.Buffer, image.Image) error) map[string][]byte {
+	files := map[string][]byte{}
+	for name, encode := range encoders {
+		buf := &bytes.Buffer{}
+		if err := encode(buf, newSolidImage(4, 4, color.White)); err != nil {
+			t.Fatalf("Failed to encode test image '%s': %s", name, err)
+		}
+		files[name] = buf.Bytes()
+	}
+	return files
+}
+
+func TestCommonImageTypesDecode(t *testing.T) {
+	files := encodeTestImages(t, map[string]func(*bytes.Buffer, image.Image) error{
+		"a.png": func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) },
+		"b.jpg": func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) },
+		"c.bmp": func(buf *bytes.Buffer, img image.Image) error { return bmp.Encode(buf, img) },
+	})
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:                 "atlas",
+		Format:               spriteNamesFormat,
+		Input:                NewBytesStream(files),
+		Output:               outputRecorder,
+		Width:                16,
+		Height:               16,
+		SortDescriptorByName: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	expected := "a\nb\nc\n"
+	if got := string(outputRecorder.Got()["atlas-1.txt"]); got != expected {
+		t.Errorf("Expected the sprites\n\n%s\nbut got\n\n%s", expected, got)
+	}
+}
+
+func TestAllowedTypesRejectsOtherTypes(t *testing.T) {
+	files := encodeTestImages(t, map[string]func(*bytes.Buffer, image.Image) error{
+		"a.png": func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) },
+		"b.bmp": func(buf *bytes.Buffer, img image.Image) error { return bmp.Encode(buf, img) },
+	})
+
+	params := &packer.Params{
+		Name:         "atlas",
+		Format:       target.Love,
+		Input:        NewBytesStream(files),
+		Output:       NewOutputRecorder(),
+		Width:        16,
+		Height:       16,
+		AllowedTypes: []string{"png", "jpg"},
+	}
+
+	err := packer.Run(context.Background(), params)
+	expected := "Asset 'b.bmp' is a bmp image, which is not one of the AllowedTypes (png, jpg)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected the error '%s' but got '%v'", expected, err)
+	}
+}
+
+func TestUnsupportedImageTypesAreReported(t *testing.T) {
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  NewBytesStream(map[string][]byte{"notes.txt": []byte("not an image")}),
+		Output: NewOutputRecorder(),
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "Asset 'notes.txt' is not an image of a supported type") {
+		t.Errorf("Expected an unsupported type error but got '%v'", err)
+	}
+}
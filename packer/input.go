@@ -3,9 +3,13 @@ package packer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"time"
 )
 
 // Asset represents a single input source into the texture packer.
@@ -33,6 +37,20 @@ func (f AssetStreamerFunc) AssetStream(ctx context.Context) (<-chan Asset, <-cha
 	return f(ctx)
 }
 
+// ModTimeAsset is an Asset that can report when its content was last
+// modified, the assets of the file streams implement it.
+type ModTimeAsset interface {
+	Asset
+	ModTime() (time.Time, error)
+}
+
+// SourceAsset is an Asset read from a file, that can report the path
+// of the file, the assets of the file streams implement it.
+type SourceAsset interface {
+	Asset
+	SourcePath() string
+}
+
 type fileAsset struct {
 	Name string
 	path string
@@ -46,6 +64,18 @@ func (a *fileAsset) Asset() string {
 	return a.Name
 }
 
+func (a *fileAsset) SourcePath() string {
+	return a.path
+}
+
+func (a *fileAsset) ModTime() (time.Time, error) {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
 var errContextNil = errors.New("Context must not be nil")
 
 // NewFileStream creates an asset streamer that streams files from a given
@@ -158,3 +188,135 @@ func NewFileGlobStream(globPattern string) AssetStreamer {
 		return stream, errc
 	})
 }
+
+// fsAsset is an asset read from a file of an fs.FS
+type fsAsset struct {
+	fsys fs.FS
+	path string
+}
+
+func (a *fsAsset) Reader() (io.ReadCloser, error) {
+	return a.fsys.Open(a.path)
+}
+
+func (a *fsAsset) Asset() string {
+	return a.path
+}
+
+func (a *fsAsset) ModTime() (time.Time, error) {
+	info, err := fs.Stat(a.fsys, a.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// NewFSStream creates an asset streamer that streams the files of the
+// file system, eg. an embed.FS, whose paths match any of the path.Match
+// patterns, eg. "ui/*.png". Every file is streamed when no pattern is
+// given. The file system is walked from its root and the assets are
+// named by their slash separated paths and read with fsys.Open.
+func NewFSStream(fsys fs.FS, patterns ...string) AssetStreamer {
+	return AssetStreamerFunc(func(ctx context.Context) (<-chan Asset, <-chan error) {
+		stream := make(chan Asset)
+		errc := make(chan error, 1)
+		go func() {
+			defer close(stream)
+			defer close(errc)
+
+			if ctx == nil {
+				errc <- errContextNil
+				return
+			}
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					errc <- fmt.Errorf("Invalid pattern '%s': %s", pattern, err)
+					return
+				}
+			}
+
+			// No select needed for this send, since errc is buffered.
+			errc <- fs.WalkDir(fsys, ".", func(assetPath string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				if len(patterns) > 0 && !matchesAny(patterns, assetPath) {
+					return nil
+				}
+
+				select {
+				case stream <- &fsAsset{fsys: fsys, path: assetPath}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				return nil
+			})
+		}()
+		return stream, errc
+	})
+}
+
+// NewModifiedSinceStream creates an asset streamer that streams only the
+// assets of the inner streamer that were modified after since, for
+// incremental builds that repack just the changed sprites. Every asset
+// of the inner streamer must implement ModTimeAsset, otherwise the
+// stream fails.
+func NewModifiedSinceStream(inner AssetStreamer, since time.Time) AssetStreamer {
+	return AssetStreamerFunc(func(ctx context.Context) (<-chan Asset, <-chan error) {
+		stream := make(chan Asset)
+		errc := make(chan error, 1)
+
+		go func() {
+			defer close(stream)
+			defer close(errc)
+
+			if ctx == nil {
+				errc <- errContextNil
+				return
+			}
+
+			// Stop the inner stream if this one finishes early
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			assets, innerErrc := inner.AssetStream(ctx)
+			for asset := range assets {
+				modTimeAsset, ok := asset.(ModTimeAsset)
+				if !ok {
+					errc <- fmt.Errorf("Asset '%s' does not report a modification time", asset.Asset())
+					return
+				}
+				modTime, err := modTimeAsset.ModTime()
+				if err != nil {
+					errc <- fmt.Errorf("Failed to read the modification time of asset '%s': %s", asset.Asset(), err)
+					return
+				}
+				if !modTime.After(since) {
+					continue
+				}
+				select {
+				case stream <- asset:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+
+			err := <-innerErrc
+			if err == nil {
+				err = ctx.Err()
+			}
+			if err != nil {
+				errc <- err
+			}
+		}()
+
+		return stream, errc
+	})
+}
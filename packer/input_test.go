@@ -1,10 +1,21 @@
 package packer_test
 
 import (
+	"bytes"
 	"context"
-	"testing"
-
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/psucodervn/lovepac/packer"
 )
@@ -62,6 +73,78 @@ func TestFilenameStream(t *testing.T) {
 	testAssetStreamer(t, assetStreamer, expect)
 }
 
+func TestFSStream(t *testing.T) {
+	expect := map[string]struct{}{
+		"button_active.png": {},
+		"button_hover.png":  {},
+		"button.png":        {},
+	}
+
+	assetStreamer := packer.NewFSStream(os.DirFS("./fixtures"), "button*.png")
+	testAssetStreamer(t, assetStreamer, expect)
+
+	t.Run("Asset streamer walks nested directories", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"ui/icons/ok.png": {Data: []byte("ok")},
+			"ui/readme.txt":   {Data: []byte("readme")},
+			"hero.png":        {Data: []byte("hero")},
+		}
+		testAssetStreamerSendsAllFiles(t, packer.NewFSStream(fsys, "*.png", "ui/*/*.png"), map[string]struct{}{
+			"ui/icons/ok.png": {},
+			"hero.png":        {},
+		})
+	})
+
+	t.Run("Asset streamer reports invalid patterns", func(t *testing.T) {
+		assets, errc := packer.NewFSStream(fstest.MapFS{}, "[").AssetStream(context.Background())
+		for range assets {
+		}
+		if err := <-errc; err == nil || !strings.Contains(err.Error(), "Invalid pattern '['") {
+			t.Errorf("Expected an invalid pattern error but got '%v'", err)
+		}
+	})
+}
+
+func TestModifiedSinceStream(t *testing.T) {
+	dir := t.TempDir()
+	since := time.Now().Add(-time.Hour)
+	modTimes := map[string]time.Time{
+		"old.png":     since.Add(-time.Minute),
+		"same.png":    since,
+		"changed.png": since.Add(time.Minute),
+		"new.png":     time.Now(),
+	}
+	for name, modTime := range modTimes {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to write test file: %s", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set test file modification time: %s", err)
+		}
+	}
+
+	expect := map[string]struct{}{
+		"changed.png": {},
+		"new.png":     {},
+	}
+
+	assetStreamer := packer.NewModifiedSinceStream(packer.NewFileStream(dir), since)
+	testAssetStreamer(t, assetStreamer, expect)
+
+	// Additional tests
+	t.Run("Asset streamer reports assets without a modification time", func(t *testing.T) {
+		inner := NewBytesStream(map[string][]byte{"memory.png": nil})
+		assets, errc := packer.NewModifiedSinceStream(inner, since).AssetStream(context.Background())
+		for asset := range assets {
+			t.Errorf("Found unexpected asset named '%s'", asset.Asset())
+		}
+		if err := <-errc; err == nil || !strings.Contains(err.Error(), "does not report a modification time") {
+			t.Errorf("Expected a missing modification time error but got '%v'", err)
+		}
+	})
+}
+
 // Common AssetStreamer test suite //
 // ******************************* //
 
@@ -147,3 +230,65 @@ func testAssetStreamerReportsNilContext(t *testing.T, assetStream packer.AssetSt
 		t.Errorf("Expected 'context nil' error but got nil")
 	}
 }
+
+// In-memory AssetStreamer for generated test images //
+// ************************************************ //
+
+type bytesAsset struct {
+	name string
+	data []byte
+}
+
+func (a *bytesAsset) Reader() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(a.data)), nil
+}
+
+func (a *bytesAsset) Asset() string { return a.name }
+
+// NewImageStream encodes the given images as png and streams
+// them as assets named by their map keys.
+func NewImageStream(t *testing.T, images map[string]image.Image) packer.AssetStreamer {
+	files := make(map[string][]byte, len(images))
+	for name, img := range images {
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			t.Fatalf("Failed to encode test image '%s': %s", name, err)
+		}
+		files[name] = buf.Bytes()
+	}
+	return NewBytesStream(files)
+}
+
+// NewBytesStream streams the given file contents as
+// assets named by their map keys.
+func NewBytesStream(files map[string][]byte) packer.AssetStreamer {
+	assets := make([]packer.Asset, 0, len(files))
+	for name, data := range files {
+		assets = append(assets, &bytesAsset{name: name, data: data})
+	}
+
+	return packer.AssetStreamerFunc(func(ctx context.Context) (<-chan packer.Asset, <-chan error) {
+		stream := make(chan packer.Asset)
+		errc := make(chan error, 1)
+		go func() {
+			defer close(stream)
+			defer close(errc)
+			for _, asset := range assets {
+				select {
+				case stream <- asset:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+		}()
+		return stream, errc
+	})
+}
+
+// newSolidImage creates a w x h image filled with the given color
+func newSolidImage(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+	return img
+}
@@ -0,0 +1,99 @@
+package packer
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// keepTogetherGroup returns the 1 based index of the first KeepTogether
+// group with a pattern matching the asset path, or 0 if there is none
+func keepTogetherGroup(groups [][]string, assetPath string) int {
+	for i, patterns := range groups {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, assetPath); ok {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// blockGroup returns the KeepTogether group of a block, 0 if it has none
+func blockGroup(block packing.Block) int {
+	switch b := block.(type) {
+	case *sprite:
+		return b.keepTogether
+	case *sizeBlock:
+		return b.group
+	}
+	return 0
+}
+
+// packUnit is a set of blocks that must be placed on the same atlas,
+// most units are a single block that is not kept together with others
+type packUnit struct {
+	blocks []packing.Block
+	group  int
+}
+
+// groupUnits gathers the blocks of each KeepTogether group into a single
+// unit, positioned where the first block of the group was. The order of
+// the blocks is otherwise retained.
+func groupUnits(blocks []packing.Block) []*packUnit {
+	units := make([]*packUnit, 0, len(blocks))
+	groups := map[int]*packUnit{}
+	for _, block := range blocks {
+		group := blockGroup(block)
+		if group == 0 {
+			units = append(units, &packUnit{blocks: []packing.Block{block}})
+			continue
+		}
+		if unit, ok := groups[group]; ok {
+			unit.blocks = append(unit.blocks, block)
+			continue
+		}
+		unit := &packUnit{blocks: []packing.Block{block}, group: group}
+		groups[group] = unit
+		units = append(units, unit)
+	}
+	return units
+}
+
+// packAtlas fills a single atlas with as many of the units as fit, each
+// unit is placed entirely or not at all. It returns the packer holding
+// the placed blocks, the blocks that were placed and the units that did
+// not fit. An error is returned if a group does not fit an empty atlas.
+func packAtlas(newPacker func() packing.Packer, units []*packUnit) (packing.Packer, []packing.Block, []*packUnit, error) {
+	packer := newPacker()
+	var completed []packing.Block
+	var incomplete []*packUnit
+	for _, unit := range units {
+		placed := 0
+		for _, block := range unit.blocks {
+			if packer.Pack(block) != nil {
+				break
+			}
+			placed++
+		}
+		if placed == len(unit.blocks) {
+			completed = append(completed, unit.blocks...)
+			continue
+		}
+		if unit.group != 0 && len(completed) == 0 {
+			return nil, nil, nil, fmt.Errorf("The %d sprites of KeepTogether group %d do not fit on a single atlas",
+				len(unit.blocks), unit.group-1)
+		}
+		if placed > 0 {
+			// Part of the group was placed, so rebuild the atlas without it.
+			// Packing is deterministic, the completed blocks are placed as before.
+			packer = newPacker()
+			for _, block := range completed {
+				packer.Pack(block)
+			}
+		}
+		incomplete = append(incomplete, unit)
+	}
+	return packer, completed, incomplete, nil
+}
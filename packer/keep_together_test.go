@@ -0,0 +1,95 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestKeepTogetherPacksGroupOnOneAtlas(t *testing.T) {
+	// Packed largest first 'big' and 'ui/a' fill the first
+	// atlas, which would leave 'ui/b' alone on the second
+	images := map[string]image.Image{
+		"big.png":  newSolidImage(64, 33, color.White),
+		"ui/a.png": newSolidImage(64, 31, color.White),
+		"ui/b.png": newSolidImage(64, 31, color.White),
+	}
+
+	tests := []struct {
+		keepTogether [][]string
+	}{
+		{nil},
+		{[][]string{{"ui/*"}}},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:         "atlas",
+			Format:       target.Love,
+			Input:        NewImageStream(t, images),
+			Output:       outputRecorder,
+			Width:        64,
+			Height:       64,
+			KeepTogether: test.keepTogether,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		got := outputRecorder.Got()
+		atlasOf := map[string]string{}
+		for _, filename := range []string{"atlas-1.lua", "atlas-2.lua"} {
+			buf, ok := got[filename]
+			if !ok {
+				t.Fatalf("Expected '%s' to be written", filename)
+			}
+			for _, name := range []string{"big", "a", "b"} {
+				if strings.Contains(string(buf), "quads['"+name+"']") {
+					atlasOf[name] = filename
+				}
+			}
+		}
+
+		together := atlasOf["a"] == atlasOf["b"]
+		if test.keepTogether == nil && together {
+			t.Errorf("Expected 'a' and 'b' to be split across atlases without KeepTogether")
+		}
+		if test.keepTogether != nil && !together {
+			t.Errorf("Expected 'a' and 'b' to be packed onto the same atlas but got %v", atlasOf)
+		}
+		if test.keepTogether != nil && atlasOf["big"] == atlasOf["a"] {
+			t.Errorf("Expected 'big' to be packed alone but got %v", atlasOf)
+		}
+	}
+}
+
+func TestKeepTogetherFailsWhenGroupDoesNotFitOneAtlas(t *testing.T) {
+	images := map[string]image.Image{
+		"ui/a.png": newSolidImage(64, 31, color.White),
+		"ui/b.png": newSolidImage(64, 31, color.White),
+		"ui/c.png": newSolidImage(64, 31, color.White),
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:         "atlas",
+		Format:       target.Love,
+		Input:        NewImageStream(t, images),
+		Output:       outputRecorder,
+		Width:        64,
+		Height:       64,
+		KeepTogether: [][]string{{"ui/*.png"}},
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "KeepTogether group 0 do not fit") {
+		t.Errorf("Expected an error that the group does not fit but got '%v'", err)
+	}
+}
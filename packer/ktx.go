@@ -0,0 +1,99 @@
+package packer
+
+import (
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// ContainerFormat is the file format that atlas images are written in
+type ContainerFormat int
+
+const (
+	// ContainerPNG writes atlas images as PNG files. This is the default.
+	ContainerPNG ContainerFormat = iota
+	// ContainerKTX writes atlas images as KTX 1.1 texture containers
+	// holding a single level of uncompressed 8 bit RGBA pixels with
+	// straight alpha, for GPU loaders that upload them as is. The rows
+	// are stored top first, as recorded by the KTXorientation key.
+	ContainerKTX
+)
+
+// ext returns the file extension of the images of the container format
+func (c ContainerFormat) ext() string {
+	if c == ContainerKTX {
+		return "ktx"
+	}
+	return "png"
+}
+
+// encodeImage encodes the image in the container format of the atlas
+func (a *atlas) encodeImage(w io.Writer, img image.Image) error {
+	if a.container == ContainerKTX {
+		return encodeKTX(w, img)
+	}
+	return encodePNG(w, img, a.dpi, a.colorProfile, a.pngCompression, a.pngBuffers)
+}
+
+// ktxIdentifier starts every KTX 1.1 file
+var ktxIdentifier = []byte{0xab, 'K', 'T', 'X', ' ', '1', '1', 0xbb, '\r', '\n', 0x1a, '\n'}
+
+// The OpenGL enums of the type and format of uncompressed RGBA pixels
+const (
+	glUnsignedByte = 0x1401
+	glRGBA         = 0x1908
+	glRGBA8        = 0x8058
+)
+
+// ktxOrientation is the key value pair recording that rows are stored
+// top first and columns left first, as they are in image.Image
+const ktxOrientation = "KTXorientation\x00S=r,T=d\x00"
+
+// encodeKTX encodes the image as a KTX 1.1 texture with a single
+// mipmap level of uncompressed RGBA pixels
+func encodeKTX(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	rgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+
+	// Each key value pair is padded to a multiple of 4 bytes
+	keyValueData := make([]byte, 4, 4+len(ktxOrientation)+3)
+	binary.LittleEndian.PutUint32(keyValueData, uint32(len(ktxOrientation)))
+	keyValueData = append(keyValueData, ktxOrientation...)
+	for len(keyValueData)%4 != 0 {
+		keyValueData = append(keyValueData, 0)
+	}
+
+	header := []uint32{
+		0x04030201, // endianness
+		glUnsignedByte,
+		1, // glTypeSize
+		glRGBA,
+		glRGBA8,
+		glRGBA,
+		uint32(b.Dx()),
+		uint32(b.Dy()),
+		0, // pixelDepth
+		0, // numberOfArrayElements
+		1, // numberOfFaces
+		1, // numberOfMipmapLevels
+		uint32(len(keyValueData)),
+	}
+
+	if _, err := w.Write(ktxIdentifier); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := w.Write(keyValueData); err != nil {
+		return err
+	}
+	// Rows of 4 byte pixels are always aligned to 4 bytes, so need no padding
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(rgba.Pix))); err != nil {
+		return err
+	}
+	_, err := w.Write(rgba.Pix)
+	return err
+}
@@ -0,0 +1,66 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestContainerKTXWritesUncompressedRGBATexture(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:            "atlas",
+		Format:          target.Starling,
+		Input:           NewImageStream(t, map[string]image.Image{"red.png": newSolidImage(4, 4, red)}),
+		Output:          outputRecorder,
+		Width:           8,
+		Height:          16,
+		ContainerFormat: packer.ContainerKTX,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	if desc := string(got["atlas-1.xml"]); !strings.Contains(desc, `imagePath="atlas-1.ktx"`) {
+		t.Errorf("Expected the descriptor to describe the KTX image but got\n\n%s", desc)
+	}
+	data, ok := got["atlas-1.ktx"]
+	if !ok {
+		t.Fatalf("Expected the atlas to be written as a KTX file")
+	}
+
+	identifier := []byte{0xab, 'K', 'T', 'X', ' ', '1', '1', 0xbb, '\r', '\n', 0x1a, '\n'}
+	if !bytes.HasPrefix(data, identifier) {
+		t.Fatalf("Expected the KTX identifier but got % x", data[:12])
+	}
+	var header [13]uint32
+	if err := binary.Read(bytes.NewReader(data[12:]), binary.LittleEndian, &header); err != nil {
+		t.Fatalf("Expected a complete KTX header but got '%s'", err)
+	}
+	expected := [13]uint32{0x04030201, 0x1401, 1, 0x1908, 0x8058, 0x1908, 8, 16, 0, 0, 1, 1, 28}
+	if header != expected {
+		t.Errorf("Expected the KTX header %v but got %v", expected, header)
+	}
+
+	pixels := data[12+13*4+28:]
+	if size := binary.LittleEndian.Uint32(pixels); size != 8*16*4 || len(pixels) != 4+8*16*4 {
+		t.Fatalf("Expected a single level of %d bytes but the level is %d bytes with %d bytes left", 8*16*4, size, len(pixels)-4)
+	}
+	pixels = pixels[4:]
+	if !bytes.Equal(pixels[:4], []byte{255, 0, 0, 255}) {
+		t.Errorf("Expected the top left pixel to be the red sprite but got %v", pixels[:4])
+	}
+	if last := pixels[len(pixels)-4:]; !bytes.Equal(last, []byte{0, 0, 0, 0}) {
+		t.Errorf("Expected the bottom right pixel to be transparent but got %v", last)
+	}
+}
@@ -0,0 +1,121 @@
+package packer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// layoutPin is a sprite that is placed where a LayoutHint recorded it,
+// x and y are the position of its block including the padding before it
+type layoutPin struct {
+	spr  *sprite
+	x, y int
+}
+
+// validateLayoutHint checks that the parameters support a LayoutHint
+func (p *Params) validateLayoutHint() error {
+	if p.LayoutHint == nil {
+		return nil
+	}
+	if p.Fit {
+		return errors.New("LayoutHint does not support Fit, the atlases must keep their size")
+	}
+	if p.groupsAssets() {
+		return errors.New("LayoutHint does not support GroupByPrefixDepth, FolderAsAtlas or SeparateByExtension")
+	}
+	if p.MaxFileBytes > 0 {
+		return errors.New("LayoutHint does not support MaxFileBytes, the atlases must keep their size")
+	}
+	if p.MinLastAtlasOccupancy > 0 {
+		return errors.New("LayoutHint does not support MinLastAtlasOccupancy, the atlases must keep their size")
+	}
+	return nil
+}
+
+// pinLayout reads the source map of params.LayoutHint and pins every
+// sprite that is unchanged since, with the same size and content hash
+// if the source map has one, to the atlas and position the source map
+// records. Sprites that no longer fit there, eg. as the padding changed,
+// are not pinned. It returns the pins of each 0 based atlas index and
+// the unpinned sprites.
+func pinLayout(params *Params, strategy Strategy, sprites []packing.Block) (map[int][]layoutPin, []packing.Block, error) {
+	var entries map[string]sourceMapEntry
+	if err := json.NewDecoder(params.LayoutHint).Decode(&entries); err != nil {
+		return nil, nil, fmt.Errorf("Failed to read the LayoutHint: %s", err)
+	}
+
+	// Every atlas of the earlier run held a sprite, so its atlases
+	// are named by the first as many indices as there are atlases
+	hintAtlases := map[string]bool{}
+	for _, entry := range entries {
+		hintAtlases[entry.Atlas] = true
+	}
+	atlasIndex := map[string]int{}
+	for i := 0; i < len(hintAtlases); i++ {
+		atlasIndex[fmt.Sprintf("%s.%s", params.ImageNameFormatter(params.Name, i+1), params.ContainerFormat.ext())] = i
+	}
+
+	pins := map[int][]layoutPin{}
+	packers := map[int]*packing.RectPacker{}
+	var unpinned []packing.Block
+	for _, block := range sprites {
+		spr := block.(*sprite)
+		entry, ok := entries[spr.path]
+		index, known := atlasIndex[entry.Atlas]
+		if !ok || !known || entry.W != spr.w || entry.H != spr.h {
+			unpinned = append(unpinned, block)
+			continue
+		}
+		// Entries of source maps written before assets were
+		// hashed are only compared by size
+		if entry.Hash != "" {
+			hash, err := hashAsset(spr.Asset)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Failed to read asset '%s': %s", spr.path, err)
+			}
+			if hash != entry.Hash {
+				unpinned = append(unpinned, block)
+				continue
+			}
+		}
+
+		packer, ok := packers[index]
+		if !ok {
+			packer = newRectPacker(params, strategy, index)
+			packers[index] = packer
+		}
+		offsetX, offsetY := spr.offset()
+		pin := layoutPin{spr: spr, x: entry.X - offsetX, y: entry.Y - offsetY}
+		w, h := spr.Size()
+		if packer.Reserve(pin.x, pin.y, w, h) != nil {
+			unpinned = append(unpinned, block)
+			continue
+		}
+		pins[index] = append(pins[index], pin)
+	}
+	return pins, unpinned, nil
+}
+
+// newRectPacker returns the packer of the atlas with the 0 based index
+// that pinned sprites are reserved in before the others are packed
+func newRectPacker(params *Params, strategy Strategy, index int) *packing.RectPacker {
+	w, h := params.maxAtlasSize(index)
+	overhangX, overhangY := params.overhang()
+	return packing.NewRectPacker(w+overhangX, h+overhangY, strategy.Heuristic)
+}
+
+// newPinnedPacker returns the packer of the atlas with the 0 based index,
+// with the pinned sprites of the atlas already placed
+func newPinnedPacker(params *Params, strategy Strategy, index int, pins []layoutPin) packing.Packer {
+	packer := newRectPacker(params, strategy, index)
+	for _, pin := range pins {
+		w, h := pin.spr.Size()
+		// Pins were checked to fit together by pinLayout
+		packer.Reserve(pin.x, pin.y, w, h)
+		pin.spr.Place(pin.x, pin.y)
+	}
+	return packer
+}
@@ -0,0 +1,121 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// placement is the part of a source map entry that locates a sprite
+type placement struct {
+	Atlas string `json:"atlas"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+}
+
+func TestLayoutHintKeepsUnchangedSpritesInPlace(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	images := map[string]image.Image{
+		"a.png": newSolidImage(20, 20, white),
+		"b.png": newSolidImage(10, 10, white),
+		"c.png": newSolidImage(30, 30, white),
+	}
+
+	pack := func(images map[string]image.Image, hint []byte) (map[string]placement, []byte) {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:          "atlas",
+			Format:        target.Love,
+			Input:         NewImageStream(t, images),
+			Output:        outputRecorder,
+			Width:         64,
+			Height:        64,
+			Padding:       1,
+			EmitSourceMap: true,
+		}
+		if hint != nil {
+			params.LayoutHint = bytes.NewReader(hint)
+		}
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+		sourceMap := outputRecorder.Got()["atlas.sourcemap.json"]
+		placements := map[string]placement{}
+		if err := json.Unmarshal(sourceMap, &placements); err != nil {
+			t.Fatalf("Expected the source map to be valid JSON but got '%s'", err)
+		}
+		return placements, sourceMap
+	}
+
+	before, hint := pack(images, nil)
+
+	// A new largest sprite would otherwise be packed first and move the others,
+	// and the changed sprite is free to move
+	images["b.png"] = newSolidImage(10, 10, color.NRGBA{255, 0, 0, 255})
+	images["d.png"] = newSolidImage(50, 20, white)
+	if unhinted, _ := pack(images, nil); unhinted["c.png"] == before["c.png"] {
+		t.Fatalf("Expected the new sprite to move the others without a hint")
+	}
+
+	after, _ := pack(images, hint)
+	for _, name := range []string{"a.png", "c.png"} {
+		if after[name] != before[name] {
+			t.Errorf("Expected unchanged sprite '%s' to stay at %+v but it moved to %+v", name, before[name], after[name])
+		}
+	}
+	if _, ok := after["d.png"]; !ok {
+		t.Errorf("Expected the new sprite to be packed around the pinned sprites")
+	}
+
+	// Source maps written before assets were hashed pin sprites of the same size
+	after, _ = pack(images, withoutHashes(t, hint))
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		if after[name] != before[name] {
+			t.Errorf("Expected sprite '%s' to stay at %+v with a hint without hashes but it moved to %+v", name, before[name], after[name])
+		}
+	}
+}
+
+func TestLayoutHintWithFitResultsInError(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:     target.Love,
+		Input:      packer.NewFilenameStream("./fixtures", "button.png"),
+		Output:     outputRecorder,
+		Fit:        true,
+		LayoutHint: bytes.NewReader([]byte("{}")),
+	}
+
+	if err := packer.Run(context.Background(), params); err == nil {
+		t.Errorf("Expected LayoutHint with Fit to result in an error")
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected nothing to be written but got %d files", len(got))
+	}
+}
+
+func TestLayoutHintWithMinLastAtlasOccupancyResultsInError(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:                target.Love,
+		Input:                 packer.NewFilenameStream("./fixtures", "button.png"),
+		Output:                outputRecorder,
+		MinLastAtlasOccupancy: 0.5,
+		LayoutHint:            bytes.NewReader([]byte("{}")),
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "MinLastAtlasOccupancy") {
+		t.Errorf("Expected LayoutHint with MinLastAtlasOccupancy to result in an error but got '%v'", err)
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected nothing to be written but got %d files", len(got))
+	}
+}
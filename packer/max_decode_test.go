@@ -0,0 +1,80 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// newPNGBomb returns a small png whose header declares a w x h image
+func newPNGBomb(t *testing.T, w, h int) []byte {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, newSolidImage(1, 1, color.White)); err != nil {
+		t.Fatalf("Failed to encode test image: %s", err)
+	}
+	data := buf.Bytes()
+	// The IHDR data follows the signature, chunk length and type
+	ihdr := data[8+4 : 8+4+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(w))
+	binary.BigEndian.PutUint32(ihdr[8:12], uint32(h))
+	binary.BigEndian.PutUint32(data[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestMaxDecodeDimensionRejectsLargeImagesBeforeDecoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string][]byte
+		max      int
+		expected string
+	}{
+		{
+			name:     "default",
+			files:    map[string][]byte{"bomb.png": newPNGBomb(t, 50000, 50000)},
+			expected: "Asset 'bomb.png' is 50000x50000, larger than the MaxDecodeDimension of 16384 pixels",
+		},
+		{
+			name:     "custom",
+			files:    map[string][]byte{"wide.png": newPNGBomb(t, 9, 1)},
+			max:      8,
+			expected: "Asset 'wide.png' is 9x1, larger than the MaxDecodeDimension of 8 pixels",
+		},
+	}
+
+	for _, test := range tests {
+		params := &packer.Params{
+			Name:               "atlas",
+			Format:             target.Love,
+			Input:              NewBytesStream(test.files),
+			Output:             NewOutputRecorder(),
+			MaxDecodeDimension: test.max,
+		}
+		err := packer.Run(context.Background(), params)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("Expected the %s limit to fail with '%s' but got '%v'", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestMaxDecodeDimensionAllowsImagesAtTheLimit(t *testing.T) {
+	params := &packer.Params{
+		Name:               "atlas",
+		Format:             target.Love,
+		Input:              NewImageStream(t, map[string]image.Image{"sprite.png": newSolidImage(8, 8, color.White)}),
+		Output:             NewOutputRecorder(),
+		Width:              16,
+		Height:             16,
+		MaxDecodeDimension: 8,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Errorf("Expected an image as large as MaxDecodeDimension to be packed but got '%s'", err)
+	}
+}
@@ -0,0 +1,94 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// newNoiseImage returns an image of random opaque pixels, which PNG
+// can not compress so that its encoded size is predictable
+func newNoiseImage(w, h int, seed int64) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rand.New(rand.NewSource(seed)).Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+func TestMaxFileBytesSplitsAtlasesThatAreTooLarge(t *testing.T) {
+	// Each sprite encodes to about 4KB, so only two fit within the limit
+	images := map[string]image.Image{}
+	for i := 0; i < 4; i++ {
+		images[fmt.Sprintf("%d.png", i)] = newNoiseImage(32, 32, int64(i))
+	}
+	maxFileBytes := 8 << 10
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:         "atlas",
+		Format:       target.Love,
+		Input:        NewImageStream(t, images),
+		Output:       outputRecorder,
+		Width:        64,
+		Height:       64,
+		MaxFileBytes: maxFileBytes,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	atlasImages := 0
+	for filename, buf := range got {
+		if !strings.HasSuffix(filename, ".png") {
+			continue
+		}
+		atlasImages++
+		if len(buf) > maxFileBytes {
+			t.Errorf("Expected '%s' to be at most %d bytes but it is %d", filename, maxFileBytes, len(buf))
+		}
+	}
+	if atlasImages != 2 {
+		t.Fatalf("Expected the sprites to be split over 2 atlases but got %d", atlasImages)
+	}
+
+	// The first atlas was halved, the remaining sprites fit the second at full size
+	cfg, err := png.DecodeConfig(bytes.NewReader(got["atlas-1.png"]))
+	if err != nil {
+		t.Fatalf("Expected the first atlas to be a valid png but got '%s'", err)
+	}
+	if cfg.Width != 32 || cfg.Height != 64 {
+		t.Errorf("Expected the first atlas to be shrunk to 32x64 but it is %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestMaxFileBytesSmallerThanASpriteResultsInError(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:       target.Love,
+		Input:        NewImageStream(t, map[string]image.Image{"noise.png": newNoiseImage(32, 32, 1)}),
+		Output:       outputRecorder,
+		Width:        64,
+		Height:       64,
+		MaxFileBytes: 1 << 10,
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "'noise.png'") {
+		t.Errorf("Expected an error naming the sprite that can not fit MaxFileBytes but got '%v'", err)
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected nothing to be written but got %d files", len(got))
+	}
+}
@@ -0,0 +1,77 @@
+package packer
+
+import (
+	"io"
+	"sync"
+)
+
+// MeasuringOutputter wraps an Outputter and measures the files written
+// through it, eg. to enforce a budget on the total size of the atlases.
+type MeasuringOutputter struct {
+	Outputter
+	mu    sync.Mutex
+	sizes map[string]int64
+}
+
+// NewMeasuringOutputter creates a MeasuringOutputter that writes to the given outputter
+func NewMeasuringOutputter(outputter Outputter) *MeasuringOutputter {
+	return &MeasuringOutputter{Outputter: outputter, sizes: map[string]int64{}}
+}
+
+// GetWriter implements the Outputter interface. A file that is written
+// again without appending replaces the size of its previous contents.
+func (m *MeasuringOutputter) GetWriter(filename string, append bool) (io.WriteCloser, error) {
+	writer, err := m.Outputter.GetWriter(filename, append)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	if _, ok := m.sizes[filename]; !ok || !append {
+		m.sizes[filename] = 0
+	}
+	m.mu.Unlock()
+	return &measuringWriter{WriteCloser: writer, filename: filename, outputter: m}, nil
+}
+
+// BytesWritten returns the total size of every file written
+func (m *MeasuringOutputter) BytesWritten() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var total int64
+	for _, size := range m.sizes {
+		total += size
+	}
+	return total
+}
+
+// FileCount returns the number of distinct files written
+func (m *MeasuringOutputter) FileCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sizes)
+}
+
+// Sizes returns the size of each file written
+func (m *MeasuringOutputter) Sizes() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sizes := make(map[string]int64, len(m.sizes))
+	for filename, size := range m.sizes {
+		sizes[filename] = size
+	}
+	return sizes
+}
+
+type measuringWriter struct {
+	io.WriteCloser
+	filename  string
+	outputter *MeasuringOutputter
+}
+
+func (w *measuringWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.outputter.mu.Lock()
+	w.outputter.sizes[w.filename] += int64(n)
+	w.outputter.mu.Unlock()
+	return n, err
+}
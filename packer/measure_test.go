@@ -0,0 +1,65 @@
+package packer_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestMeasuringOutputterCountsFilesAndBytes(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	measuring := packer.NewMeasuringOutputter(outputRecorder)
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  packer.NewFilenameStream("./fixtures", "button.png", "button_hover.png"),
+		Output: measuring,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	var total int64
+	for filename, buf := range got {
+		total += int64(len(buf))
+		if size := measuring.Sizes()[filename]; size != int64(len(buf)) {
+			t.Errorf("Expected '%s' to measure %d bytes but got %d", filename, len(buf), size)
+		}
+	}
+	if measuring.FileCount() != len(got) {
+		t.Errorf("Expected %d files to be counted but got %d", len(got), measuring.FileCount())
+	}
+	if measuring.BytesWritten() != total {
+		t.Errorf("Expected %d bytes to be counted but got %d", total, measuring.BytesWritten())
+	}
+}
+
+func TestMeasuringOutputterReplacesRewrittenFiles(t *testing.T) {
+	measuring := packer.NewMeasuringOutputter(NewOutputRecorder())
+
+	write := func(append bool, content string) {
+		writer, err := measuring.GetWriter("file.txt", append)
+		if err != nil {
+			t.Fatalf("Failed to get writer: %s", err)
+		}
+		io.WriteString(writer, content)
+		writer.Close()
+	}
+	write(false, "12345")
+	write(true, "678")
+	if n := measuring.BytesWritten(); n != 8 {
+		t.Errorf("Expected appended bytes to be added to the file size 8 but got %d", n)
+	}
+	write(false, "12")
+	if n := measuring.BytesWritten(); n != 2 {
+		t.Errorf("Expected a rewritten file to replace its size with 2 but got %d", n)
+	}
+	if n := measuring.FileCount(); n != 1 {
+		t.Errorf("Expected 1 file but got %d", n)
+	}
+}
@@ -0,0 +1,21 @@
+package packer
+
+// Version is the version of lovepac recorded in the meta of descriptors
+var Version = "3.0"
+
+// appURL identifies lovepac as the app that wrote a descriptor
+const appURL = "https://github.com/psucodervn/lovepac"
+
+// meta is the template data describing the build that wrote a
+// descriptor, for formats with a meta block
+type meta struct {
+	App     string
+	Version string
+	// BuildTag is Params.BuildTag, empty unless specified
+	BuildTag string
+}
+
+// meta returns the meta of the descriptors written with the parameters
+func (p *Params) meta() meta {
+	return meta{App: appURL, Version: Version, BuildTag: p.BuildTag}
+}
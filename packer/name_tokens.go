@@ -0,0 +1,77 @@
+package packer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// nameTokens are the tokens of Params.Name that are replaced by the
+// values of each atlas
+var nameTokens = []string{"{index}", "{width}", "{height}", "{hash}"}
+
+// hasNameTokens returns true if the name contains any of the nameTokens
+func hasNameTokens(name string) bool {
+	for _, token := range nameTokens {
+		if strings.Contains(name, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// runName returns the name of the files that describe every atlas of the
+// run, eg. combined descriptors. When the Name contains tokens it is the
+// text before the first token without trailing separators.
+func (p *Params) runName() string {
+	name := p.Name
+	for _, token := range nameTokens {
+		if i := strings.Index(name, token); i >= 0 {
+			name = name[:i]
+		}
+	}
+	if name = strings.TrimRight(name, "-_. "); name == "" {
+		return DefaultAtlasName
+	}
+	return name
+}
+
+// expandName replaces the tokens of the name with the values of the
+// atlas with the 1 based index. The hash is only computed when the
+// name contains the {hash} token, as it reads every asset of the atlas.
+func expandName(name string, index, width, height int, sprites []packing.Block) (string, error) {
+	replacements := []string{
+		"{index}", strconv.Itoa(index),
+		"{width}", strconv.Itoa(width),
+		"{height}", strconv.Itoa(height),
+	}
+	if strings.Contains(name, "{hash}") {
+		hash, err := layoutHash(width, height, sprites)
+		if err != nil {
+			return "", err
+		}
+		replacements = append(replacements, "{hash}", hash)
+	}
+	return strings.NewReplacer(replacements...).Replace(name), nil
+}
+
+// layoutHash returns the first 8 hex digits of a SHA-256 hash of the size
+// of an atlas and the names, placements and content of its sprites, which
+// changes whenever the image of the atlas does
+func layoutHash(width, height int, sprites []packing.Block) (string, error) {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "%dx%d\n", width, height)
+	for _, block := range sprites {
+		spr := block.(*sprite)
+		hash, err := hashAsset(spr.Asset)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read asset '%s': %s", spr.path, err)
+		}
+		fmt.Fprintf(hasher, "%s %d,%d %dx%d %s\n", spr.path, spr.x, spr.y, spr.w, spr.h, hash)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))[:8], nil
+}
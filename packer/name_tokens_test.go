@@ -0,0 +1,75 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"regexp"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestNameTokensAreExpandedForEachAtlas(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "sheet_{index}_{width}x{height}",
+		Format: target.PhaserMulti,
+		Input: NewImageStream(t, map[string]image.Image{
+			"big.png":   newSolidImage(16, 16, color.White),
+			"small.png": newSolidImage(4, 4, color.White),
+		}),
+		Output:           outputRecorder,
+		Width:            16,
+		Height:           16,
+		Fit:              true,
+		EmitPerAtlasDesc: true,
+		EmitCombinedDesc: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	// The combined descriptor is named by the text before the first token
+	for _, filename := range []string{"sheet_1_16x16.png", "sheet_1_16x16.json", "sheet_2_4x4.png", "sheet_2_4x4.json", "sheet.json"} {
+		if _, ok := got[filename]; !ok {
+			t.Errorf("Expected '%s' to be written but got %v", filename, outputRecorder.Order())
+		}
+	}
+}
+
+func TestNameHashTokenChangesWithContent(t *testing.T) {
+	hashed := regexp.MustCompile(`^atlas-([0-9a-f]{8})\.png$`)
+	pack := func(c color.Color) string {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas-{hash}",
+			Format: target.Love,
+			Input:  NewImageStream(t, map[string]image.Image{"icon.png": newSolidImage(4, 4, c)}),
+			Output: outputRecorder,
+			Width:  8,
+			Height: 8,
+		}
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+		for _, filename := range outputRecorder.Order() {
+			if match := hashed.FindStringSubmatch(filename); match != nil {
+				return match[1]
+			}
+		}
+		t.Fatalf("Expected an image named by its hash but got %v", outputRecorder.Order())
+		return ""
+	}
+
+	white := pack(color.White)
+	if again := pack(color.White); again != white {
+		t.Errorf("Expected the same content to hash to %s but got %s", white, again)
+	}
+	if black := pack(color.Black); black == white {
+		t.Errorf("Expected different content to hash differently but both got %s", white)
+	}
+}
@@ -0,0 +1,85 @@
+package packer
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// ninePatchExt is the Android style suffix used to identify images that
+// carry 9-slice guides in a 1px border around the image content.
+const ninePatchExt = ".9.png"
+
+func isNinePatch(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ninePatchExt)
+}
+
+// border contains 9-slice insets, measured in pixels from each edge
+// of the sprite, within which the sprite should not be stretched.
+type border struct {
+	left, top, right, bottom int
+}
+
+// scale returns a copy of the border with each inset scaled and rounded
+func (b border) scale(scale float64, rounding ScaleRounding) border {
+	return border{
+		left:   rounding.scale(b.left, scale),
+		top:    rounding.scale(b.top, scale),
+		right:  rounding.scale(b.right, scale),
+		bottom: rounding.scale(b.bottom, scale),
+	}
+}
+
+// readNinePatchBorder reads the stretch guides from the top row and left
+// column of a nine-patch image. Guides are opaque black pixels, the pixels
+// before and after a guide are the insets of the border. The returned
+// border is relative to the image content, excluding the 1px guide area.
+func readNinePatchBorder(img image.Image) border {
+	bounds := img.Bounds()
+	contentW, contentH := bounds.Dx()-2, bounds.Dy()-2
+	if contentW <= 0 || contentH <= 0 {
+		return border{}
+	}
+
+	var b border
+	if first, last, ok := findGuide(img, bounds.Min.X+1, bounds.Min.Y, 1, 0, contentW); ok {
+		b.left = first
+		b.right = contentW - last - 1
+	}
+	if first, last, ok := findGuide(img, bounds.Min.X, bounds.Min.Y+1, 0, 1, contentH); ok {
+		b.top = first
+		b.bottom = contentH - last - 1
+	}
+	return b
+}
+
+// findGuide walks n pixels from (x, y) in the direction (dx, dy) and
+// returns the offset of the first and last guide pixels found.
+func findGuide(img image.Image, x, y, dx, dy, n int) (int, int, bool) {
+	first, last := -1, -1
+	for i := 0; i < n; i++ {
+		if isGuidePixel(img.At(x+i*dx, y+i*dy)) {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	return first, last, first >= 0
+}
+
+func isGuidePixel(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	return a == 0xffff && r == 0 && g == 0 && b == 0
+}
+
+// stripNinePatchGuides returns the image content without the 1px guides
+func stripNinePatchGuides(img image.Image) image.Image {
+	subImager, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return img
+	}
+	return subImager.SubImage(img.Bounds().Inset(1))
+}
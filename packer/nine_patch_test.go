@@ -0,0 +1,52 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestNinePatchBordersAreReadAndGuidesStripped(t *testing.T) {
+	// 32x32 content surrounded by the 1px guide area. The horizontal
+	// guide leaves 8px either side and the vertical guide leaves
+	// 4px at the top and 6px at the bottom.
+	img := newSolidImage(34, 34, color.NRGBA{255, 0, 0, 255})
+	for x := 0; x < 34; x++ {
+		img.Set(x, 0, color.Transparent)
+	}
+	for y := 0; y < 34; y++ {
+		img.Set(0, y, color.Transparent)
+	}
+	for x := 1 + 8; x < 1+32-8; x++ {
+		img.Set(x, 0, color.Black)
+	}
+	for y := 1 + 4; y < 1+32-6; y++ {
+		img.Set(0, y, color.Black)
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Unity,
+		Input:  NewImageStream(t, map[string]image.Image{"panel.9.png": img}),
+		Output: outputRecorder,
+		Width:  64,
+		Height: 64,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expectedString := "panel;0;32;32;32; 0.5;0.5; 8;8;4;6"
+	gotStr := string(outputRecorder.Got()["atlas-1.tpsheet"])
+	if !strings.Contains(gotStr, expectedString) {
+		t.Errorf("Expected descriptor to contain the following sub-string\n\n%s\n%s\n\n%s",
+			expectedString, createUnderlineString(expectedString), gotStr)
+	}
+}
@@ -0,0 +1,29 @@
+package packer
+
+import (
+	"fmt"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// validateMinLastAtlasOccupancy checks that the threshold is a fraction
+func (p *Params) validateMinLastAtlasOccupancy() error {
+	if p.MinLastAtlasOccupancy < 0 || p.MinLastAtlasOccupancy > 1 {
+		return fmt.Errorf("MinLastAtlasOccupancy must be between 0 and 1, got %g", p.MinLastAtlasOccupancy)
+	}
+	return nil
+}
+
+// occupancy returns the fraction of a w x h atlas covered by the sprites,
+// not counting their padding
+func occupancy(sprites []packing.Block, w, h int) float64 {
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	area := 0
+	for _, block := range sprites {
+		spr := block.(*sprite)
+		area += spr.w * spr.h
+	}
+	return float64(area) / float64(w*h)
+}
@@ -0,0 +1,61 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+)
+
+func TestMinLastAtlasOccupancyShrinksTheLastAtlas(t *testing.T) {
+	// 16 icons fill the first atlas, the last one spills onto a second
+	images := map[string]image.Image{}
+	for i := 0; i < 17; i++ {
+		images[fmt.Sprintf("icon%02d.png", i)] = newSolidImage(16, 16, color.White)
+	}
+
+	for _, test := range []struct {
+		minOccupancy float64
+		size         int
+	}{
+		{0, 64},
+		{0.25, 32},
+		// The sprite can't fit an atlas any smaller than itself
+		{1, 16},
+	} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:                  "atlas",
+			Format:                spriteNamesFormat,
+			Input:                 NewImageStream(t, images),
+			Output:                outputRecorder,
+			Width:                 64,
+			Height:                64,
+			MinLastAtlasOccupancy: test.minOccupancy,
+		}
+		result, err := packer.RunWithResult(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		for filename, size := range map[string]int{"atlas-1.png": 64, "atlas-2.png": test.size} {
+			img, err := png.Decode(bytes.NewReader(outputRecorder.Got()[filename]))
+			if err != nil {
+				t.Fatalf("Expected %s to decode but got '%s'", filename, err)
+			}
+			if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+				t.Errorf("Expected %s to be %dx%d with MinLastAtlasOccupancy %g but got %dx%d",
+					filename, size, size, test.minOccupancy, b.Dx(), b.Dy())
+			}
+		}
+		expected := float64(16*16) / float64(test.size*test.size)
+		if len(result.Occupancy) != 2 || result.Occupancy[0] != 1 || result.Occupancy[1] != expected {
+			t.Errorf("Expected the occupancies [1 %g] but got %v", expected, result.Occupancy)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package packer
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// DefaultOptimizeBudget is the time Optimize may spend
+// trying strategies when no budget is specified
+var DefaultOptimizeBudget = 2 * time.Second
+
+// SortOrder names the order sprites are packed in, largest first
+type SortOrder string
+
+// Sort orders by area, longest side, width and height
+const (
+	SortByArea    SortOrder = "area"
+	SortByMaxSide SortOrder = "maxside"
+	SortByWidth   SortOrder = "width"
+	SortByHeight  SortOrder = "height"
+)
+
+var sortOrders = []SortOrder{SortByArea, SortByMaxSide, SortByWidth, SortByHeight}
+
+var heuristics = []packing.Heuristic{
+	packing.FirstFit,
+	packing.BestAreaFit,
+	packing.BestShortSideFit,
+	packing.BottomLeft,
+}
+
+// Strategy is the combination of sprite sort order
+// and placement heuristic used to pack the atlases
+type Strategy struct {
+	Sort      SortOrder
+	Heuristic packing.Heuristic
+}
+
+func (s Strategy) String() string {
+	return fmt.Sprintf("%s/%s", s.Sort, s.Heuristic)
+}
+
+// defaultStrategy returns the strategy used when not optimizing
+// TODO allow sorting algorithm to be specified
+func defaultStrategy(params *Params) Strategy {
+	if params.Fit {
+		// The growing packer works best with the largest sides first
+		return Strategy{Sort: SortByMaxSide}
+	}
+	switch params.FillDirection {
+	case packing.FillRows:
+		return Strategy{Sort: SortByHeight}
+	case packing.FillColumns:
+		return Strategy{Sort: SortByWidth}
+	}
+	return Strategy{Sort: SortByArea, Heuristic: params.PlacementHeuristic}
+}
+
+// sortBlocks sorts the blocks in the given order, blocks
+// that compare equal keep their existing order
+func sortBlocks(blocks []packing.Block, order SortOrder) {
+	switch order {
+	case SortByMaxSide:
+		sort.Stable(packing.ByMaxSide(blocks))
+	case SortByWidth:
+		sort.Stable(packing.ByWidth(blocks))
+	case SortByHeight:
+		sort.Stable(packing.ByHeight(blocks))
+	default:
+		sort.Stable(packing.ByArea(blocks))
+	}
+}
+
+// newAtlasPacker returns the packer used to fill the atlas with the 0 based
+// index, shrunk as in Params.pageSize and extended by the padding that may
+// overhang the edges of the atlas
+func newAtlasPacker(params *Params, strategy Strategy, index, shrink int) packing.Packer {
+	w, h := params.pageSize(index, shrink)
+	overhangX, overhangY := params.overhang()
+	w, h = w+overhangX, h+overhangY
+	if params.Fit {
+		return packing.NewGrowingPacker(w, h, params.MaxAspectRatio)
+	}
+	if params.FillDirection != packing.FillFree {
+		return packing.NewShelfPacker(w, h, params.FillDirection)
+	}
+	return packing.NewBinPackerWithRules(w, h, strategy.Heuristic, params.SplitRule)
+}
+
+// sizeBlock is a copy of the size of a block, used to
+// try a strategy without placing the block itself
+type sizeBlock struct{ w, h, group int }
+
+func (b *sizeBlock) Size() (int, int) { return b.w, b.h }
+func (b *sizeBlock) Place(x, y int)   {}
+
+// tryStrategy packs the sizes of the sprites using the strategy and
+// returns the number of atlases and the total area of the atlases
+// needed, ok is false when the sprites could not be packed
+func tryStrategy(sprites []packing.Block, params *Params, strategy Strategy) (atlases, area int, ok bool) {
+	blocks := make([]packing.Block, len(sprites))
+	for i, spr := range sprites {
+		w, h := spr.Size()
+		blocks[i] = &sizeBlock{w, h, blockGroup(spr)}
+	}
+	sortBlocks(blocks, strategy.Sort)
+
+	units := groupUnits(blocks)
+	newPacker := func() packing.Packer { return newAtlasPacker(params, strategy, atlases, 0) }
+	for len(units) > 0 {
+		packer, completed, remaining, err := packAtlas(newPacker, units)
+		if err != nil || len(completed) == 0 {
+			return 0, 0, false
+		}
+		w, h := atlasSize(packer, params, atlases, 0)
+		area += w * h
+		atlases++
+		units = remaining
+	}
+	return atlases, area, true
+}
+
+// optimizeStrategy tries every combination of sort order and heuristic,
+// starting with the default strategy, until the time budget runs out and
+// returns the one needing the fewest atlases, then the least total area.
+// The heuristic is ignored in Fit mode so only the sort orders are tried.
+// When Params.Seed is set the strategies after the default are tried in
+// an order shuffled by rng.
+func optimizeStrategy(ctx context.Context, sprites []packing.Block, params *Params, rng *rand.Rand) Strategy {
+	budget := params.OptimizeBudget
+	if budget <= 0 {
+		budget = DefaultOptimizeBudget
+	}
+	deadline := time.Now().Add(budget)
+
+	candidates := []Strategy{defaultStrategy(params)}
+	for _, order := range sortOrders {
+		if params.Fit {
+			candidates = append(candidates, Strategy{Sort: order})
+			continue
+		}
+		for _, heuristic := range heuristics {
+			candidates = append(candidates, Strategy{Sort: order, Heuristic: heuristic})
+		}
+	}
+
+	if params.Seed != 0 {
+		others := candidates[1:]
+		rng.Shuffle(len(others), func(i, j int) { others[i], others[j] = others[j], others[i] })
+	}
+
+	best := candidates[0]
+	bestAtlases, bestArea, found := tryStrategy(sprites, params, best)
+	for _, candidate := range candidates[1:] {
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			break
+		}
+		atlases, area, ok := tryStrategy(sprites, params, candidate)
+		if !ok {
+			continue
+		}
+		if !found || atlases < bestAtlases || (atlases == bestAtlases && area < bestArea) {
+			best, bestAtlases, bestArea, found = candidate, atlases, area, true
+		}
+	}
+	return best
+}
@@ -0,0 +1,99 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestOptimizeReducesAtlasCount(t *testing.T) {
+	// Packing these largest area first needs two
+	// atlases, but they can all fit into one
+	images := map[string]image.Image{
+		"a.png": newSolidImage(24, 16, color.White),
+		"b.png": newSolidImage(8, 24, color.White),
+		"c.png": newSolidImage(64, 40, color.White),
+	}
+
+	tests := []struct {
+		optimize bool
+		atlases  int
+	}{
+		{false, 2},
+		{true, 1},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:     "atlas",
+			Format:   target.Love,
+			Input:    NewImageStream(t, images),
+			Output:   outputRecorder,
+			Width:    64,
+			Height:   64,
+			Optimize: test.optimize,
+		}
+
+		result, err := packer.RunWithResult(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		got := outputRecorder.Got()
+		if n := len(got) / 2; n != test.atlases {
+			t.Errorf("Expected %d atlases with Optimize %t but got %d", test.atlases, test.optimize, n)
+		}
+
+		defaultStrategy := packer.Strategy{Sort: packer.SortByArea}
+		if test.optimize && result.Strategy == defaultStrategy {
+			t.Errorf("Expected Optimize to report a strategy other than '%s'", defaultStrategy)
+		}
+		if !test.optimize && result.Strategy != defaultStrategy {
+			t.Errorf("Expected the default strategy '%s' but got '%s'", defaultStrategy, result.Strategy)
+		}
+	}
+}
+
+func TestSeedMakesOptimizeReproducible(t *testing.T) {
+	images := map[string]image.Image{
+		"a.png": newSolidImage(24, 16, color.White),
+		"b.png": newSolidImage(8, 24, color.White),
+		"c.png": newSolidImage(64, 40, color.White),
+		"d.png": newSolidImage(30, 12, color.White),
+		"e.png": newSolidImage(12, 30, color.White),
+	}
+
+	run := func() *packer.RunResult {
+		params := &packer.Params{
+			Name:           "atlas",
+			Format:         target.Love,
+			Input:          NewImageStream(t, images),
+			Output:         NewOutputRecorder(),
+			Width:          64,
+			Height:         64,
+			Optimize:       true,
+			OptimizeBudget: time.Minute,
+			Seed:           42,
+		}
+		result, err := packer.RunWithResult(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+		return result
+	}
+
+	first, second := run(), run()
+	if first.Strategy != second.Strategy {
+		t.Errorf("Expected the same seed to choose the same strategy but got '%s' and '%s'", first.Strategy, second.Strategy)
+	}
+	if !reflect.DeepEqual(first.Hashes, second.Hashes) {
+		t.Errorf("Expected the same seed to give identical output but got %v and %v", first.Hashes, second.Hashes)
+	}
+}
@@ -0,0 +1,23 @@
+package packer
+
+// OriginMode selects the corner of the atlas that the sprite
+// coordinates given to descriptor templates are measured from
+type OriginMode int
+
+const (
+	// OriginTopLeft measures Top downwards from the top edge
+	// of the atlas, as image coordinates are. This is the default.
+	OriginTopLeft OriginMode = iota
+	// OriginBottomLeft measures Top upwards from the bottom edge of
+	// the atlas to the bottom edge of the sprite, as OpenGL does.
+	OriginBottomLeft
+)
+
+// top returns the distance of a sprite at y with height h from
+// the origin edge of an atlas of the given height
+func (o OriginMode) top(y, h, atlasHeight int) int {
+	if o == OriginBottomLeft {
+		return atlasHeight - y - h
+	}
+	return y
+}
@@ -6,8 +6,11 @@ import (
 	"sync"
 )
 
+// OutputRecorder is an Outputter that records the contents of every
+// file in memory and the order in which the files were requested
 type OutputRecorder struct {
 	writers map[string]*bufferWithClose
+	order   []string
 	*sync.Mutex
 }
 
@@ -17,24 +20,37 @@ type bufferWithClose struct {
 
 func (b *bufferWithClose) Close() error { return nil }
 
-func (r *OutputRecorder) GetWriter(filename string) (io.WriteCloser, error) {
-	buffer := &bufferWithClose{bytes.NewBufferString("")}
+func (r *OutputRecorder) GetWriter(filename string, appendFile bool) (io.WriteCloser, error) {
 	r.Lock()
+	defer r.Unlock()
+	if buffer, ok := r.writers[filename]; ok && appendFile {
+		return buffer, nil
+	}
+	buffer := &bufferWithClose{bytes.NewBufferString("")}
 	r.writers[filename] = buffer
-	r.Unlock()
+	r.order = append(r.order, filename)
 	return buffer, nil
 }
 
-func (r *OutputRecorder) Got() map[string]*bytes.Buffer {
+// Got returns the contents written to each file
+func (r *OutputRecorder) Got() map[string][]byte {
 	r.Lock()
-	results := map[string]*bytes.Buffer{}
+	results := map[string][]byte{}
 	for key, val := range r.writers {
-		results[key] = val.Buffer
+		results[key] = val.Bytes()
 	}
 	r.Unlock()
 	return results
 }
 
+// Order returns the filenames in the order they were first requested,
+// a file that is requested again without append is listed again
+func (r *OutputRecorder) Order() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.order...)
+}
+
 func NewOutputRecorder() *OutputRecorder {
-	return &OutputRecorder{map[string]*bufferWithClose{}, &sync.Mutex{}}
+	return &OutputRecorder{writers: map[string]*bufferWithClose{}, Mutex: &sync.Mutex{}}
 }
@@ -0,0 +1,26 @@
+package packer
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// verifyNoOverlap checks that no two of the sprites packed on the atlas
+// with the 1 based index overlap, including the padding reserved around
+// them, see Params.VerifyNoOverlap
+func verifyNoOverlap(sprites []packing.Block, index int) error {
+	rects := make([]image.Rectangle, len(sprites))
+	for i, block := range sprites {
+		spr := block.(*sprite)
+		offsetX, offsetY := spr.offset()
+		w, h := spr.Size()
+		rects[i] = image.Rect(spr.x-offsetX, spr.y-offsetY, spr.x-offsetX+w, spr.y-offsetY+h)
+	}
+	if i, j, ok := packing.FindOverlap(rects); ok {
+		return fmt.Errorf("Sprites '%s' and '%s' overlap on atlas %d, as placed %v and %v including padding",
+			sprites[i].(*sprite).path, sprites[j].(*sprite).path, index, rects[i], rects[j])
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package packer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/psucodervn/lovepac/packing"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// Options configures an in-memory Pack. Zero values fall back
+// to the same defaults as the equivalent Params.
+type Options struct {
+	// Name is used for the image filename referenced by the descriptor
+	Name    string
+	Format  target.Format
+	Width   int
+	Height  int
+	Padding int
+	Scale   float64
+	// Fit shrinks the atlas to fit its contents, see Params.Fit
+	Fit bool
+}
+
+// Pack packs the images into a single atlas in memory, returning the
+// png encoded atlas image and its descriptor. Images are named by their
+// map keys in the descriptor. An error is returned if the images do not
+// fit into a single atlas.
+func Pack(images map[string]image.Image, opts Options) (atlasPNG []byte, descriptor []byte, err error) {
+	assets := make([]Asset, 0, len(images))
+	for name, img := range images {
+		assets = append(assets, &imageAsset{name: name, img: img})
+	}
+
+	output := newMemoryOutputter()
+	params := &Params{
+		Name:       opts.Name,
+		Format:     opts.Format,
+		Input:      newAssetSliceStream(assets),
+		Output:     output,
+		Width:      opts.Width,
+		Height:     opts.Height,
+		Padding:    opts.Padding,
+		Scale:      opts.Scale,
+		Fit:        opts.Fit,
+		MaxAtlases: 1,
+	}
+	if err := Run(context.Background(), params); err != nil {
+		if errors.Is(err, packing.ErrOutOfRoom) {
+			return nil, nil, fmt.Errorf("Images do not fit in a single %dx%d atlas", params.Width, params.Height)
+		}
+		return nil, nil, err
+	}
+
+	imageName := fmt.Sprintf("%s.%s", params.ImageNameFormatter(params.Name, 1), params.ContainerFormat.ext())
+	atlasImage, ok := output.files[imageName]
+	if !ok {
+		return nil, nil, errors.New("No images to pack")
+	}
+	descName := fmt.Sprintf("%s.%s", params.NameFormatter(params.Name, 1), params.DescExtension)
+	return atlasImage.Bytes(), output.files[descName].Bytes(), nil
+}
+
+// imageAsset is an Asset for an image that is already decoded,
+// it is encoded as a png when it is read
+type imageAsset struct {
+	name string
+	img  image.Image
+}
+
+func (a *imageAsset) Reader() (io.ReadCloser, error) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, a.img); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+func (a *imageAsset) Asset() string { return a.name }
+
+// memoryOutputter is an Outputter that keeps every file in memory
+type memoryOutputter struct {
+	mu    sync.Mutex
+	files map[string]*bytes.Buffer
+}
+
+func newMemoryOutputter() *memoryOutputter {
+	return &memoryOutputter{files: map[string]*bytes.Buffer{}}
+}
+
+// GetWriter implements the Outputter interface
+func (m *memoryOutputter) GetWriter(filename string, append bool) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	buf, ok := m.files[filename]
+	if !ok || !append {
+		buf = &bytes.Buffer{}
+		m.files[filename] = buf
+	}
+	return nopWriteCloser{buf}, nil
+}
+
+// nopWriteCloser adds a no-op Close method to a writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,56 @@
+package packer_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestPackReturnsAtlasAndDescriptor(t *testing.T) {
+	images := map[string]image.Image{
+		"red":  newSolidImage(16, 16, color.NRGBA{255, 0, 0, 255}),
+		"blue": newSolidImage(8, 8, color.NRGBA{0, 0, 255, 255}),
+	}
+
+	atlasPNG, descriptor, err := packer.Pack(images, packer.Options{
+		Format: target.Love,
+		Width:  32,
+		Height: 32,
+	})
+	if err != nil {
+		t.Fatalf("Expected pack to succeed without error but got '%s'", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(atlasPNG))
+	if err != nil {
+		t.Fatalf("Expected atlas to be a valid png but got '%s'", err)
+	}
+	if img.Bounds().Dx() != 32 || img.Bounds().Dy() != 32 {
+		t.Errorf("Expected a 32x32 atlas but got %v", img.Bounds())
+	}
+
+	desc := string(descriptor)
+	for name := range images {
+		if !strings.Contains(desc, "quads['"+name+"']") {
+			t.Errorf("Expected descriptor to contain '%s' but got\n\n%s", name, desc)
+		}
+	}
+}
+
+func TestPackReturnsErrorWhenImagesOverflowOneAtlas(t *testing.T) {
+	images := map[string]image.Image{
+		"a": newSolidImage(32, 32, color.White),
+		"b": newSolidImage(32, 32, color.White),
+	}
+
+	_, _, err := packer.Pack(images, packer.Options{Format: target.Love, Width: 32, Height: 32})
+	if err == nil || !strings.Contains(err.Error(), "single 32x32 atlas") {
+		t.Errorf("Expected a single atlas overflow error but got '%v'", err)
+	}
+}
@@ -0,0 +1,96 @@
+package packer
+
+import (
+	"image"
+	"image/png"
+	"sync"
+)
+
+// Packer runs the packer like RunWithResult while keeping the atlas
+// images and png encoder buffers allocated by each run to reuse them
+// in the next, which reduces the garbage of tools that pack many small
+// atlas sets. The zero value is ready to use and a Packer may be used
+// by several goroutines at once. Run and RunWithResult use a new
+// Packer for every call.
+type Packer struct {
+	canvases   canvasPool
+	pngBuffers pngBufferPool
+}
+
+// NewPacker returns a Packer without any buffers allocated yet
+func NewPacker() *Packer {
+	return &Packer{}
+}
+
+// canvasPool keeps the images that are no longer used to draw the next
+// atlases and sprites into
+type canvasPool struct {
+	mu   sync.Mutex
+	free []*image.NRGBA
+}
+
+// get returns a transparent w x h image, reusing the smallest free image
+// with enough room for its pixels if there is one. A nil pool always
+// allocates a new image.
+func (c *canvasPool) get(w, h int) *image.NRGBA {
+	if c == nil {
+		return image.NewNRGBA(image.Rect(0, 0, w, h))
+	}
+	size := 4 * w * h
+	c.mu.Lock()
+	best := -1
+	for i, img := range c.free {
+		if cap(img.Pix) >= size && (best < 0 || cap(img.Pix) < cap(c.free[best].Pix)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		c.mu.Unlock()
+		return image.NewNRGBA(image.Rect(0, 0, w, h))
+	}
+	img := c.free[best]
+	c.free = append(c.free[:best], c.free[best+1:]...)
+	c.mu.Unlock()
+
+	pix := img.Pix[:size]
+	for i := range pix {
+		pix[i] = 0
+	}
+	*img = image.NRGBA{Pix: pix, Stride: 4 * w, Rect: image.Rect(0, 0, w, h)}
+	return img
+}
+
+// put adds an image that is no longer used to the pool, images that
+// are not NRGBA are ignored as are all images when the pool is nil
+func (c *canvasPool) put(img image.Image) {
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.free = append(c.free, nrgba)
+}
+
+// pngBufferPool implements png.EncoderBufferPool
+type pngBufferPool struct {
+	mu   sync.Mutex
+	free []*png.EncoderBuffer
+}
+
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.free) == 0 {
+		return nil
+	}
+	buf := p.free[len(p.free)-1]
+	p.free = p.free[:len(p.free)-1]
+	return buf
+}
+
+func (p *pngBufferPool) Put(buf *png.EncoderBuffer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free = append(p.free, buf)
+}
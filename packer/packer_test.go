@@ -0,0 +1,48 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestPackerReusedForSeveralRunsMatchesRun(t *testing.T) {
+	runs := []map[string]image.Image{
+		{"wall.png": newSolidImage(8, 8, color.NRGBA{255, 0, 0, 255})},
+		// Drawn into the image of the previous run, which must be cleared
+		{"dot.png": newSolidImage(2, 2, color.NRGBA{0, 0, 255, 255})},
+		{"wide.png": newSolidImage(8, 2, color.NRGBA{0, 255, 0, 128})},
+	}
+	newParams := func(images map[string]image.Image, output packer.Outputter) *packer.Params {
+		return &packer.Params{
+			Name:                       "atlas",
+			Format:                     target.Love,
+			Input:                      NewImageStream(t, images),
+			Output:                     output,
+			Width:                      8,
+			Height:                     8,
+			Scale:                      0.75,
+			AllowFractionalCoordinates: true,
+		}
+	}
+
+	p := packer.NewPacker()
+	for i, images := range runs {
+		expected := NewOutputRecorder()
+		if err := packer.Run(context.Background(), newParams(images, expected)); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+		got := NewOutputRecorder()
+		if _, err := p.Pack(context.Background(), newParams(images, got)); err != nil {
+			t.Fatalf("Expected pack %d to succeed without error but got '%s'", i, err)
+		}
+		if !bytes.Equal(got.Got()["atlas-1.png"], expected.Got()["atlas-1.png"]) {
+			t.Errorf("Expected pack %d to output the same image as Run", i)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package packer
+
+// PaddingMode selects where the Padding pixels are reserved around
+// each sprite. In every mode a sprite of w pixels at x is drawn from x
+// to x+w-1, the modes differ in the space left before and after it.
+type PaddingMode int
+
+const (
+	// PaddingShared reserves Padding pixels before every sprite, above
+	// and to the left of it. Neighbouring sprites are exactly Padding
+	// pixels apart and the top and left edges of the atlas are padded
+	// by Padding pixels, while the right and bottom edges are not
+	// padded. A sprite of w pixels needs Padding+w pixels. This is the default.
+	PaddingShared PaddingMode = iota
+	// PaddingSymmetric reserves Padding pixels on every side of every
+	// sprite. Neighbouring sprites are 2*Padding pixels apart and every
+	// edge of the atlas is padded by Padding pixels. A sprite of w
+	// pixels needs Padding+w+Padding pixels.
+	PaddingSymmetric
+	// PaddingNoEdge reserves Padding pixels after every sprite, below
+	// and to the right of it, which may overhang the right and bottom
+	// edges of the atlas. Neighbouring sprites are exactly Padding pixels
+	// apart and no edge of the atlas is padded, so a row of n sprites of
+	// w pixels fills n*w+(n-1)*Padding pixels.
+	PaddingNoEdge
+)
+
+// edges returns the padding reserved before and after a sprite
+func (m PaddingMode) edges(padding int) (before, after int) {
+	switch m {
+	case PaddingSymmetric:
+		return padding, padding
+	case PaddingNoEdge:
+		return 0, padding
+	}
+	return padding, 0
+}
+
+// overhang returns how far the padding after the sprites
+// at the right and bottom edges may extend past the atlas
+func (m PaddingMode) overhang(padding int) int {
+	if m == PaddingNoEdge {
+		return padding
+	}
+	return 0
+}
+
+// padding returns the horizontal and vertical padding, PaddingX and
+// PaddingY when they are set and Padding otherwise
+func (p *Params) padding() (x, y int) {
+	x, y = p.Padding, p.Padding
+	if p.PaddingX > 0 {
+		x = p.PaddingX
+	}
+	if p.PaddingY > 0 {
+		y = p.PaddingY
+	}
+	return x, y
+}
+
+// overhang returns how far the padding after the sprites at the right
+// and bottom edges may extend past the atlas, horizontally and vertically
+func (p *Params) overhang() (x, y int) {
+	x, y = p.padding()
+	return p.PaddingMode.overhang(x), p.PaddingMode.overhang(y)
+}
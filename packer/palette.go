@@ -0,0 +1,59 @@
+package packer
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+)
+
+// maxPaletteSize is the most colors that EmitPalettes reads of a sprite
+const maxPaletteSize = 256
+
+// readPalette decodes the sprite and records the distinct colors of
+// its pixels, excluding fully transparent pixels. An error is returned
+// if the sprite has more than maxPaletteSize colors.
+func readPalette(spr *sprite) error {
+	img, err := spr.decodeImage()
+	if err != nil {
+		return err
+	}
+	colors := map[color.NRGBA]bool{}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A == 0 || colors[c] {
+				continue
+			}
+			if len(colors) == maxPaletteSize {
+				return fmt.Errorf("Asset '%s' has more than %d colors and can not be described by a palette",
+					spr.path, maxPaletteSize)
+			}
+			colors[c] = true
+		}
+	}
+
+	spr.palette = make([]color.NRGBA, 0, len(colors))
+	for c := range colors {
+		spr.palette = append(spr.palette, c)
+	}
+	sort.Slice(spr.palette, func(i, j int) bool {
+		return paletteKey(spr.palette[i]) < paletteKey(spr.palette[j])
+	})
+	return nil
+}
+
+// paletteKey orders colors by their red, green, blue then alpha channel
+func paletteKey(c color.NRGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// Palette is used for template rendering, it returns the colors of the
+// sprite as "#rrggbbaa" hex strings when Params.EmitPalettes is set
+func (s *sprite) Palette() []string {
+	palette := make([]string, len(s.palette))
+	for i, c := range s.palette {
+		palette[i] = fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	}
+	return palette
+}
@@ -0,0 +1,69 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+var paletteFormat = target.Format{
+	Name:     "palette",
+	Template: template.Must(template.New("palette").Parse("{{range .Sprites}}{{.Name}}:{{range .Palette}} {{.}}{{end}}\n{{end}}")),
+	Ext:      "txt",
+}
+
+func TestEmitPalettesDescribesTheColorsOfEachSprite(t *testing.T) {
+	img := newSolidImage(3, 2, color.NRGBA{0, 0, 255, 255})
+	img.Set(1, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(2, 1, color.NRGBA{255, 0, 0, 128})
+	// Fully transparent pixels are not part of the palette
+	img.Set(0, 1, color.NRGBA{})
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:         "atlas",
+		Format:       paletteFormat,
+		Input:        NewImageStream(t, map[string]image.Image{"hero.png": img}),
+		Output:       outputRecorder,
+		Width:        4,
+		Height:       4,
+		EmitPalettes: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expected := "hero: #0000ffff #ff000080 #ff0000ff\n"
+	if got := string(outputRecorder.Got()["atlas-1.txt"]); got != expected {
+		t.Errorf("Expected the descriptor\n\n%s\nbut got\n\n%s", expected, got)
+	}
+}
+
+func TestEmitPalettesRejectsSpritesWithTooManyColors(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 17, 16))
+	for i := 0; i < 17*16; i++ {
+		img.Set(i%17, i/17, color.NRGBA{uint8(i), uint8(i >> 8), 0, 255})
+	}
+
+	params := &packer.Params{
+		Name:         "atlas",
+		Format:       paletteFormat,
+		Input:        NewImageStream(t, map[string]image.Image{"gradient.png": img}),
+		Output:       NewOutputRecorder(),
+		Width:        32,
+		Height:       32,
+		EmitPalettes: true,
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "gradient.png") {
+		t.Errorf("Expected an error naming the sprite with too many colors but got '%v'", err)
+	}
+}
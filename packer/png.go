@@ -0,0 +1,80 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"math"
+)
+
+// metersPerInch converts a DPI into the pixels per meter unit used by pHYs
+const metersPerInch = 0.0254
+
+// pngSignatureLen is the length of the magic bytes that open every png
+const pngSignatureLen = 8
+
+// encodePNG encodes the image as a png at the compression level, reusing
+// the encoder buffers of the pool if it isn't nil. The chunks of the color profile and, when dpi
+// is positive, a pHYs chunk recording the pixel density are inserted
+// after the IHDR chunk, which image/png doesn't do itself.
+func encodePNG(w io.Writer, img image.Image, dpi int, profile ColorProfile, level png.CompressionLevel, pool png.EncoderBufferPool) error {
+	encoder := &png.Encoder{CompressionLevel: level, BufferPool: pool}
+	chunks := profile.chunks()
+	if dpi > 0 {
+		chunks = append(chunks, physChunk(dpi))
+	}
+	if len(chunks) == 0 {
+		return encoder.Encode(w, img)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := encoder.Encode(buf, img); err != nil {
+		return err
+	}
+	encoded := buf.Bytes()
+
+	// IHDR is always the first chunk, its data is 13 bytes long
+	// plus 4 bytes each for the length, type and crc
+	ihdrEnd := pngSignatureLen + 4 + 4 + 13 + 4
+	if len(encoded) < ihdrEnd || string(encoded[pngSignatureLen+4:pngSignatureLen+8]) != "IHDR" {
+		return errors.New("Encoded png does not start with an IHDR chunk")
+	}
+
+	if _, err := w.Write(encoded[:ihdrEnd]); err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(encoded[ihdrEnd:])
+	return err
+}
+
+// pngChunk creates a chunk of the given type, framing
+// the data with its length and crc
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 4+4+len(data)+4)
+	binary.BigEndian.PutUint32(chunk[0:4], uint32(len(data)))
+	copy(chunk[4:8], chunkType)
+	copy(chunk[8:], data)
+	binary.BigEndian.PutUint32(chunk[8+len(data):], crc32.ChecksumIEEE(chunk[4:8+len(data)]))
+	return chunk
+}
+
+// physChunk creates a pHYs chunk with equal horizontal and
+// vertical pixel densities for the given dpi
+func physChunk(dpi int) []byte {
+	ppm := uint32(math.Round(float64(dpi) / metersPerInch))
+
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint32(data[0:4], ppm)
+	binary.BigEndian.PutUint32(data[4:8], ppm)
+	data[8] = 1 // unit is the meter
+	return pngChunk("pHYs", data)
+}
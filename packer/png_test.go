@@ -0,0 +1,135 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestImageDPIWritesPhysChunk(t *testing.T) {
+	tests := map[int]bool{0: false, 72: true, 300: true}
+
+	for dpi, expectPhys := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:     "atlas",
+			Format:   target.Love,
+			Input:    NewImageStream(t, map[string]image.Image{"sprite.png": newSolidImage(8, 8, color.White)}),
+			Output:   outputRecorder,
+			Width:    16,
+			Height:   16,
+			ImageDPI: dpi,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		data := outputRecorder.Got()["atlas-1.png"]
+		if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+			t.Fatalf("Expected output image with dpi %d to be a valid png but got '%s'", dpi, err)
+		}
+
+		i := bytes.Index(data, []byte("pHYs"))
+		if !expectPhys {
+			if i >= 0 {
+				t.Errorf("Expected no pHYs chunk with dpi %d", dpi)
+			}
+			continue
+		}
+		if i < 0 {
+			t.Errorf("Expected a pHYs chunk with dpi %d", dpi)
+			continue
+		}
+
+		x := binary.BigEndian.Uint32(data[i+4 : i+8])
+		y := binary.BigEndian.Uint32(data[i+8 : i+12])
+		unit := data[i+12]
+		if gotDPI := int(float64(x)*0.0254 + 0.5); gotDPI != dpi || x != y || unit != 1 {
+			t.Errorf("Expected pHYs chunk for %d dpi but got %dx%d pixels per unit %d", dpi, x, y, unit)
+		}
+	}
+}
+
+func TestColorProfileSRGBWritesSRGBChunks(t *testing.T) {
+	for _, profile := range []packer.ColorProfile{packer.ColorProfileNone, packer.ColorProfileSRGB} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:         "atlas",
+			Format:       target.Love,
+			Input:        NewImageStream(t, map[string]image.Image{"sprite.png": newSolidImage(8, 8, color.White)}),
+			Output:       outputRecorder,
+			Width:        16,
+			Height:       16,
+			ImageDPI:     72,
+			ColorProfile: profile,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		data := outputRecorder.Got()["atlas-1.png"]
+		if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+			t.Fatalf("Expected output image with profile %d to be a valid png but got '%s'", profile, err)
+		}
+
+		idat := bytes.Index(data, []byte("IDAT"))
+		for _, chunk := range []string{"sRGB", "gAMA", "cHRM"} {
+			i := bytes.Index(data, []byte(chunk))
+			if profile == packer.ColorProfileNone {
+				if i >= 0 {
+					t.Errorf("Expected no %s chunk without a color profile", chunk)
+				}
+				continue
+			}
+			// The color space chunks must come before the image data
+			if i < 0 || i > idat {
+				t.Errorf("Expected a %s chunk before the IDAT chunk", chunk)
+			}
+		}
+		if profile == packer.ColorProfileSRGB {
+			i := bytes.Index(data, []byte("sRGB"))
+			if length := binary.BigEndian.Uint32(data[i-4 : i]); length != 1 || data[i+4] != 0 {
+				t.Errorf("Expected an sRGB chunk with the perceptual rendering intent but got length %d intent %d", length, data[i+4])
+			}
+		}
+	}
+}
+
+func TestPNGCompressionChangesTheImageSize(t *testing.T) {
+	sizes := map[png.CompressionLevel]int{}
+	for _, level := range []png.CompressionLevel{png.NoCompression, png.BestCompression} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:           "atlas",
+			Format:         target.Love,
+			Input:          NewImageStream(t, map[string]image.Image{"sprite.png": newSolidImage(64, 64, color.White)}),
+			Output:         outputRecorder,
+			Width:          64,
+			Height:         64,
+			PNGCompression: level,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+		got := outputRecorder.Got()["atlas-1.png"]
+		if _, err := png.Decode(bytes.NewReader(got)); err != nil {
+			t.Fatalf("Expected the atlas image to decode but got '%s'", err)
+		}
+		sizes[level] = len(got)
+	}
+
+	if sizes[png.BestCompression] >= sizes[png.NoCompression] {
+		t.Errorf("Expected the best compression to be smaller than none but got %d and %d bytes",
+			sizes[png.BestCompression], sizes[png.NoCompression])
+	}
+}
@@ -0,0 +1,75 @@
+package packer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	previewOutlineColor    = color.NRGBA{255, 0, 255, 255}
+	previewLabelColor      = color.White
+	previewLabelBackground = color.NRGBA{0, 0, 0, 192}
+)
+
+// labeledPreviewFilename returns the filename of the labeled
+// preview image written for the named atlas
+func labeledPreviewFilename(atlasName string) string {
+	return fmt.Sprintf("%s_labeled.png", atlasName)
+}
+
+// CreateLabeledImage renders the atlas with the rect of every sprite
+// outlined and labeled with its name, for reviewing the packed result
+func (a *atlas) CreateLabeledImage() (image.Image, error) {
+	atlasImg, err := a.CreateImage()
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(atlasImg.Bounds())
+	draw.Draw(img, img.Bounds(), atlasImg, image.ZP, draw.Src)
+	a.release(atlasImg)
+
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(previewLabelColor), Face: face}
+	for i := range a.Sprites {
+		spr := a.Sprites[i].(*sprite)
+		rect := image.Rect(spr.x, spr.y, spr.x+spr.w, spr.y+spr.h)
+		drawOutline(img, rect, previewOutlineColor)
+
+		// Draw the label over a dark box in the top left of the sprite
+		label := spr.DisplayName()
+		labelRect := image.Rect(0, 0, drawer.MeasureString(label).Ceil()+2, face.Height).Add(rect.Min)
+		draw.Draw(img, labelRect, image.NewUniform(previewLabelBackground), image.ZP, draw.Over)
+		drawer.Dot = fixed.P(labelRect.Min.X+1, labelRect.Min.Y+face.Ascent)
+		drawer.DrawString(label)
+	}
+
+	return img, nil
+}
+
+// drawOutline draws a one pixel border just inside the rect
+func drawOutline(img draw.Image, r image.Rectangle, c color.Color) {
+	src := image.NewUniform(c)
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), src, image.ZP, draw.Src)
+	draw.Draw(img, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), src, image.ZP, draw.Src)
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y), src, image.ZP, draw.Src)
+	draw.Draw(img, image.Rect(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y), src, image.ZP, draw.Src)
+}
+
+// OutputLabeledPreview writes the labeled preview image of the atlas
+func (a *atlas) OutputLabeledPreview(outputter Outputter) error {
+	return withFile(outputter, labeledPreviewFilename(a.Name), false, func(writer io.Writer) error {
+		img, err := a.CreateLabeledImage()
+		if err != nil {
+			return err
+		}
+		return encodePNG(writer, img, a.dpi, a.colorProfile, a.pngCompression, a.pngBuffers)
+	})
+}
@@ -0,0 +1,64 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestEmitLabeledPreviewWritesPreviewImage(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input: NewImageStream(t, map[string]image.Image{
+			"button.png": newSolidImage(64, 32, color.White),
+		}),
+		Output:             outputRecorder,
+		Width:              128,
+		Height:             64,
+		EmitLabeledPreview: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	preview, ok := got["atlas-1_labeled.png"]
+	if !ok {
+		t.Fatalf("Expected a labeled preview image but it was not written")
+	}
+	img, err := png.Decode(bytes.NewReader(preview))
+	if err != nil {
+		t.Fatalf("Expected labeled preview to be a valid png but got '%s'", err)
+	}
+	if img.Bounds().Dx() != 128 || img.Bounds().Dy() != 64 {
+		t.Errorf("Expected labeled preview to match the atlas size 128x64 but got %v", img.Bounds())
+	}
+
+	// The label is drawn in the top left of the sprite over a dark box,
+	// leaving the rest of the white sprite untouched
+	if r, g, b, _ := img.At(60, 28).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Errorf("Expected the unlabeled part of the sprite to stay white")
+	}
+	dark := false
+	for x := 1; x < 10 && !dark; x++ {
+		if r, _, _, _ := img.At(x, 2).RGBA(); r < 0x8000 {
+			dark = true
+		}
+	}
+	if !dark {
+		t.Errorf("Expected a dark label background in the top left of the sprite")
+	}
+
+	if _, ok := got["atlas-1.png"]; !ok {
+		t.Errorf("Expected the atlas image to still be written")
+	}
+}
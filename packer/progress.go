@@ -0,0 +1,91 @@
+package packer
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress is a snapshot of the work completed during a Run. The counts
+// are monotonic and a new snapshot is delivered each time one changes,
+// except that every retry of Params.AutoGrow starts them over from zero.
+type Progress struct {
+	// Decoded is the number of sprites whose metadata has been decoded
+	Decoded int
+	// Packed is the number of sprites that have been placed in an atlas
+	Packed int
+	// Encoded is the number of atlases that have been encoded and written
+	Encoded int
+	// TotalSprites is the number of sprites being packed. It is 0
+	// until every asset has been decoded.
+	TotalSprites int
+	// TotalAtlases is the number of atlases being written. It is 0
+	// until every sprite has been packed.
+	TotalAtlases int
+	// Start is the time the Run began
+	Start time.Time
+	// Time is the time this snapshot was taken
+	Time time.Time
+}
+
+// Percent estimates the percentage of the Run that has completed.
+// Decoding, packing and encoding are each weighted as a third of
+// the total work; a stage counts as zero until its total is known.
+func (p Progress) Percent() float64 {
+	stage := func(done, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(done) / float64(total)
+	}
+	return 100 * (stage(p.Decoded, p.TotalSprites) +
+		stage(p.Packed, p.TotalSprites) +
+		stage(p.Encoded, p.TotalAtlases)) / 3
+}
+
+// Elapsed returns the time between the start of the Run and this snapshot
+func (p Progress) Elapsed() time.Duration {
+	return p.Time.Sub(p.Start)
+}
+
+// ETA estimates the time remaining until the Run completes by
+// extrapolating the elapsed time. It returns 0 when no estimate
+// can be made yet.
+func (p Progress) ETA() time.Duration {
+	percent := p.Percent()
+	if percent <= 0 {
+		return 0
+	}
+	elapsed := p.Elapsed()
+	return time.Duration(float64(elapsed)*100/percent) - elapsed
+}
+
+// progressTracker serialises updates to a Progress so
+// that concurrent stages of a Run can report safely.
+type progressTracker struct {
+	mu       sync.Mutex
+	progress Progress
+	callback func(Progress)
+}
+
+func newProgressTracker(callback func(Progress)) *progressTracker {
+	now := time.Now()
+	return &progressTracker{
+		progress: Progress{Start: now, Time: now},
+		callback: callback,
+	}
+}
+
+// update applies the given change to the progress and delivers the
+// new snapshot to the callback. The callback is called with the lock
+// held so snapshots are delivered one at a time and in order.
+// It is safe to call update on a nil tracker.
+func (t *progressTracker) update(change func(p *Progress)) {
+	if t == nil || t.callback == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	change(&t.progress)
+	t.progress.Time = time.Now()
+	t.callback(t.progress)
+}
@@ -0,0 +1,61 @@
+package packer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestProgressIsReportedMonotonically(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	var snapshots []packer.Progress
+	params := &packer.Params{
+		Format: target.Love,
+		Input:  packer.NewFilenameStream("./fixtures", files...),
+		Output: NewOutputRecorder(),
+		Width:  400,
+		Height: 400,
+		// Appending without a lock is safe because calls are serialised
+		OnProgress: func(p packer.Progress) { snapshots = append(snapshots, p) },
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	if len(snapshots) == 0 {
+		t.Fatalf("Expected progress to be reported but got no snapshots")
+	}
+
+	for i := 1; i < len(snapshots); i++ {
+		prev, cur := snapshots[i-1], snapshots[i]
+		if cur.Decoded < prev.Decoded || cur.Packed < prev.Packed || cur.Encoded < prev.Encoded {
+			t.Errorf("Expected progress counts to be monotonic but got %+v after %+v", cur, prev)
+		}
+		if cur.Time.Before(prev.Time) {
+			t.Errorf("Expected progress timestamps to be monotonic but got %s after %s", cur.Time, prev.Time)
+		}
+	}
+
+	last := snapshots[len(snapshots)-1]
+	if last.Decoded != len(files) || last.Packed != len(files) || last.TotalSprites != len(files) {
+		t.Errorf("Expected all %d sprites to be decoded and packed but got %+v", len(files), last)
+	}
+	if last.Encoded != 2 || last.TotalAtlases != 2 {
+		t.Errorf("Expected 2 atlases to be encoded but got %+v", last)
+	}
+	if last.Percent() != 100 {
+		t.Errorf("Expected final progress to be 100%% but got %f%%", last.Percent())
+	}
+	if last.ETA() != 0 {
+		t.Errorf("Expected final ETA to be 0 but got %s", last.ETA())
+	}
+}
@@ -0,0 +1,414 @@
+package packer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PSD compression methods of channel image data
+const (
+	psdCompressionRaw = 0
+	psdCompressionRLE = 1
+)
+
+// psdColorModeRGB is the only PSD color mode that can be read
+const psdColorModeRGB = 3
+
+// psdLayerHidden is the layer flag bit set for invisible layers
+const psdLayerHidden = 0x02
+
+// psdMaxDimension is the largest width and height of a PSD canvas
+// and its layers, larger documents are saved as PSB files
+const psdMaxDimension = 30000
+
+// PSDLayer is an Asset representing a single layer of a PSD file.
+// The layer is encoded as a png when it is read.
+type PSDLayer struct {
+	// Name of the asset, the PSD filename without its extension
+	// joined with the layer name, eg. "hero/sword.png"
+	Name string
+	// Bounds is the position of the layer in the PSD canvas, its
+	// offset from the canvas origin is the trim offset of the sprite
+	Bounds image.Rectangle
+	// Canvas is the rectangle of the PSD canvas, the source size of
+	// the sprite. Sprites of layers without a canvas are not offset.
+	Canvas image.Rectangle
+
+	img image.Image
+}
+
+// Reader implements the Asset interface
+func (l *PSDLayer) Reader() (io.ReadCloser, error) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, l.img); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+// Asset implements the Asset interface
+func (l *PSDLayer) Asset() string {
+	return l.Name
+}
+
+// placeInCanvas offsets the sprite of the layer by the position of the
+// layer in the canvas and gives it the size of the canvas as its source
+// size, so that the sprites can be drawn where the layers were. The
+// sprite may have been trimmed within the layer already.
+func (l *PSDLayer) placeInCanvas(spr *sprite, scale float64, rounding ScaleRounding) {
+	if l.Canvas.Empty() {
+		return
+	}
+	if !spr.Trimmed() {
+		spr.trim = l.img.Bounds()
+	}
+	spr.trimX += rounding.scale(l.Bounds.Min.X-l.Canvas.Min.X, scale)
+	spr.trimY += rounding.scale(l.Bounds.Min.Y-l.Canvas.Min.Y, scale)
+	spr.sourceW = rounding.scale(l.Canvas.Dx(), scale)
+	spr.sourceH = rounding.scale(l.Canvas.Dy(), scale)
+}
+
+// NewPSDStream creates an asset streamer that streams every visible
+// layer of the PSD file at the given path as a separate sprite.
+// Only 8 bit RGB files with raw or RLE compressed layers are
+// supported, other files result in an error.
+func NewPSDStream(psdPath string) AssetStreamer {
+	return AssetStreamerFunc(func(ctx context.Context) (<-chan Asset, <-chan error) {
+		stream := make(chan Asset)
+		errc := make(chan error, 1)
+
+		go func() {
+			defer close(stream)
+			defer close(errc)
+
+			if ctx == nil {
+				errc <- errContextNil
+				return
+			}
+
+			layers, err := readPSDLayers(psdPath)
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			for _, layer := range layers {
+				select {
+				case stream <- layer:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+		}()
+
+		return stream, errc
+	})
+}
+
+// readPSDLayers reads the visible layers of the PSD file at the given path
+func readPSDLayers(psdPath string) ([]*PSDLayer, error) {
+	f, err := os.Open(psdPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	layers, err := decodePSDLayers(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode PSD '%s': %s", psdPath, err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(psdPath), filepath.Ext(psdPath))
+	for _, layer := range layers {
+		layer.Name = fmt.Sprintf("%s/%s.png", base, layer.Name)
+	}
+	return layers, nil
+}
+
+// psdReader reads big endian PSD data, remembering the first error
+type psdReader struct {
+	r   io.Reader
+	err error
+}
+
+func (p *psdReader) read(data interface{}) {
+	if p.err == nil {
+		p.err = binary.Read(p.r, binary.BigEndian, data)
+	}
+}
+
+func (p *psdReader) u8() (v uint8)   { p.read(&v); return v }
+func (p *psdReader) u16() (v uint16) { p.read(&v); return v }
+func (p *psdReader) i16() (v int16)  { p.read(&v); return v }
+func (p *psdReader) u32() (v uint32) { p.read(&v); return v }
+func (p *psdReader) i32() (v int32)  { p.read(&v); return v }
+
+// bytes reads n bytes, growing the buffer as they are read so that
+// a corrupt length can't allocate more than the data that follows
+func (p *psdReader) bytes(n int) []byte {
+	if p.err != nil {
+		return nil
+	}
+	if n < 0 {
+		p.err = fmt.Errorf("Invalid negative data length %d", n)
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	if _, p.err = io.CopyN(buf, p.r, int64(n)); p.err == io.EOF {
+		p.err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes()
+}
+
+func (p *psdReader) skip(n int64) {
+	if p.err == nil {
+		_, p.err = io.CopyN(ioutil.Discard, p.r, n)
+	}
+}
+
+type psdChannel struct {
+	id     int16
+	length uint32
+}
+
+type psdLayerRecord struct {
+	name     string
+	bounds   image.Rectangle
+	channels []psdChannel
+	opacity  uint8
+	flags    uint8
+}
+
+// decodePSDLayers decodes the visible layers of a PSD. Layer names
+// are returned without a PSD filename prefix or file extension.
+func decodePSDLayers(r io.Reader) ([]*PSDLayer, error) {
+	p := &psdReader{r: r}
+
+	// File header
+	if string(p.bytes(4)) != "8BPS" {
+		return nil, errors.New("Missing PSD signature")
+	}
+	if version := p.u16(); version != 1 {
+		return nil, fmt.Errorf("Unsupported PSD version %d", version)
+	}
+	p.skip(6)
+	p.u16() // channels
+	height, width := p.u32(), p.u32()
+	if p.err == nil && (width > psdMaxDimension || height > psdMaxDimension) {
+		return nil, fmt.Errorf("Unsupported PSD size %dx%d, larger than %d pixels", width, height, psdMaxDimension)
+	}
+	canvas := image.Rect(0, 0, int(width), int(height))
+	if depth := p.u16(); depth != 8 {
+		return nil, fmt.Errorf("Unsupported PSD bit depth %d", depth)
+	}
+	if mode := p.u16(); mode != psdColorModeRGB {
+		return nil, fmt.Errorf("Unsupported PSD color mode %d, only RGB is supported", mode)
+	}
+
+	// Color mode data and image resources
+	p.skip(int64(p.u32()))
+	p.skip(int64(p.u32()))
+
+	// Layer and mask information
+	if p.u32() == 0 {
+		return nil, p.err
+	}
+	if p.u32() == 0 {
+		return nil, p.err
+	}
+	count := int(p.i16())
+	if count < 0 {
+		// A negative count means the first alpha channel
+		// contains the transparency of the merged result
+		count = -count
+	}
+
+	records := make([]*psdLayerRecord, count)
+	for i := range records {
+		records[i] = readPSDLayerRecord(p)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	var layers []*PSDLayer
+	for _, record := range records {
+		// Hidden layers, group dividers and empty layers have no
+		// pixels to pack, so their image data is skipped unread
+		if record.flags&psdLayerHidden != 0 || record.bounds.Empty() {
+			for _, channel := range record.channels {
+				p.skip(int64(channel.length))
+			}
+			continue
+		}
+		img, err := readPSDLayerImage(p, record)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read layer '%s': %s", record.name, err)
+		}
+		layers = append(layers, &PSDLayer{Name: record.name, Bounds: record.bounds, Canvas: canvas, img: img})
+	}
+	return layers, p.err
+}
+
+func readPSDLayerRecord(p *psdReader) *psdLayerRecord {
+	record := &psdLayerRecord{}
+	top, left, bottom, right := p.i32(), p.i32(), p.i32(), p.i32()
+	record.bounds = image.Rect(int(left), int(top), int(right), int(bottom))
+
+	record.channels = make([]psdChannel, p.u16())
+	for i := range record.channels {
+		record.channels[i] = psdChannel{id: p.i16(), length: p.u32()}
+	}
+
+	p.skip(8) // blend mode signature and key
+	record.opacity = p.u8()
+	p.u8() // clipping
+	record.flags = p.u8()
+	p.u8() // filler
+
+	// Extra data holds the mask, blending ranges and the name
+	extra := int64(p.u32())
+	maskLen := int64(p.u32())
+	p.skip(maskLen)
+	rangesLen := int64(p.u32())
+	p.skip(rangesLen)
+
+	// The name is a pascal string padded to a multiple of 4 bytes
+	nameLen := int64(p.u8())
+	record.name = string(p.bytes(int(nameLen)))
+	padded := (nameLen + 1 + 3) / 4 * 4
+	p.skip(padded - nameLen - 1)
+
+	// Skip additional layer information
+	p.skip(extra - 8 - maskLen - rangesLen - padded)
+	return record
+}
+
+// readPSDLayerImage reads the channel image data of
+// the layer and combines the channels into an image
+func readPSDLayerImage(p *psdReader, record *psdLayerRecord) (*image.NRGBA, error) {
+	w, h := record.bounds.Dx(), record.bounds.Dy()
+	if w > psdMaxDimension || h > psdMaxDimension {
+		return nil, fmt.Errorf("Layer is %dx%d, larger than %d pixels", w, h, psdMaxDimension)
+	}
+	// The longest channel is RLE compressed data that doesn't repeat,
+	// the row byte counts followed by a header byte every 128 bytes
+	maxLength := 2 + h*2 + h*(w+(w+127)/128)
+
+	// The image is only allocated once a channel has the data to fill it
+	var img *image.NRGBA
+	newImage := func() {
+		img = image.NewNRGBA(image.Rect(0, 0, w, h))
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+	}
+
+	for _, channel := range record.channels {
+		if channel.length < 2 || int64(channel.length) > int64(maxLength) {
+			return nil, fmt.Errorf("Invalid channel length %d of a %dx%d layer", channel.length, w, h)
+		}
+		compression := p.u16()
+		if p.err != nil {
+			return nil, p.err
+		}
+
+		// Only the color and transparency channels are used
+		offset := -1
+		switch channel.id {
+		case 0, 1, 2:
+			offset = int(channel.id)
+		case -1:
+			offset = 3
+		}
+		if offset < 0 {
+			p.skip(int64(channel.length) - 2)
+			continue
+		}
+
+		var data []byte
+		var err error
+		switch compression {
+		case psdCompressionRaw:
+			data = p.bytes(int(channel.length) - 2)
+		case psdCompressionRLE:
+			data, err = unpackPSDRLE(p.bytes(int(channel.length)-2), w, h)
+		default:
+			return nil, fmt.Errorf("Unsupported layer compression %d, only raw and RLE are supported", compression)
+		}
+		if err == nil {
+			err = p.err
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < w*h {
+			return nil, errors.New("Channel image data is too short")
+		}
+
+		if img == nil {
+			newImage()
+		}
+		for i := 0; i < w*h; i++ {
+			img.Pix[i*4+offset] = data[i]
+		}
+	}
+
+	if img == nil {
+		return nil, errors.New("Layer has no color channels")
+	}
+	if record.opacity != 0xff {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = uint8(uint16(img.Pix[i]) * uint16(record.opacity) / 0xff)
+		}
+	}
+	return img, nil
+}
+
+// unpackPSDRLE decompresses PackBits encoded channel data, which
+// starts with the byte count of each of the h rows
+func unpackPSDRLE(data []byte, w, h int) ([]byte, error) {
+	if len(data) < h*2 {
+		return nil, errors.New("RLE row counts are too short")
+	}
+	src := data[h*2:]
+	// A run of two bytes repeats a byte at most 128 times
+	size := w * h
+	if expanded := len(src) * 64; expanded < size {
+		size = expanded
+	}
+	out := make([]byte, 0, size)
+	for len(src) > 0 && len(out) < w*h {
+		n := int(int8(src[0]))
+		src = src[1:]
+		switch {
+		case n >= 0:
+			if len(src) < n+1 {
+				return nil, errors.New("RLE literal run is too short")
+			}
+			out = append(out, src[:n+1]...)
+			src = src[n+1:]
+		case n > -128:
+			if len(src) < 1 {
+				return nil, errors.New("RLE repeat run is too short")
+			}
+			for i := 0; i < 1-n; i++ {
+				out = append(out, src[0])
+			}
+			src = src[1:]
+		}
+	}
+	return out, nil
+}
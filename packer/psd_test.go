@@ -0,0 +1,281 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+type psdTestLayer struct {
+	name        string
+	bounds      image.Rectangle
+	color       color.NRGBA
+	hidden      bool
+	compression uint16
+	// channelLength overrides the length recorded for every channel
+	channelLength *uint32
+}
+
+// encodeTestPSD writes a minimal 8 bit RGB PSD containing the layers,
+// each filled with a solid color and compressed as requested
+func encodeTestPSD(width, height int, layers []psdTestLayer) []byte {
+	be := binary.BigEndian
+	channelIDs := []int16{-1, 0, 1, 2}
+
+	// Encode the channel image data of every layer first,
+	// the records need to know the length of each channel
+	channelData := make([][][]byte, len(layers))
+	for i, layer := range layers {
+		w, h := layer.bounds.Dx(), layer.bounds.Dy()
+		c := layer.color
+		values := map[int16]byte{-1: c.A, 0: c.R, 1: c.G, 2: c.B}
+		for _, id := range channelIDs {
+			data := &bytes.Buffer{}
+			binary.Write(data, be, layer.compression)
+			if layer.compression == 1 {
+				// Each row is a single PackBits repeat run
+				for y := 0; y < h; y++ {
+					binary.Write(data, be, uint16(2))
+				}
+				for y := 0; y < h; y++ {
+					data.Write([]byte{byte(int8(1 - w)), values[id]})
+				}
+			} else {
+				data.Write(bytes.Repeat([]byte{values[id]}, w*h))
+			}
+			channelData[i] = append(channelData[i], data.Bytes())
+		}
+	}
+
+	layerInfo := &bytes.Buffer{}
+	binary.Write(layerInfo, be, int16(len(layers)))
+	for i, layer := range layers {
+		b := layer.bounds
+		binary.Write(layerInfo, be, []int32{int32(b.Min.Y), int32(b.Min.X), int32(b.Max.Y), int32(b.Max.X)})
+		binary.Write(layerInfo, be, uint16(len(channelIDs)))
+		for j, id := range channelIDs {
+			length := uint32(len(channelData[i][j]))
+			if layer.channelLength != nil {
+				length = *layer.channelLength
+			}
+			binary.Write(layerInfo, be, id)
+			binary.Write(layerInfo, be, length)
+		}
+		layerInfo.WriteString("8BIMnorm")
+		flags := byte(0)
+		if layer.hidden {
+			flags = 0x02
+		}
+		layerInfo.Write([]byte{255, 0, flags, 0})
+
+		name := []byte{byte(len(layer.name))}
+		name = append(name, layer.name...)
+		for len(name)%4 != 0 {
+			name = append(name, 0)
+		}
+		binary.Write(layerInfo, be, uint32(8+len(name)))
+		binary.Write(layerInfo, be, uint32(0)) // mask
+		binary.Write(layerInfo, be, uint32(0)) // blending ranges
+		layerInfo.Write(name)
+	}
+	for i := range layers {
+		for _, data := range channelData[i] {
+			layerInfo.Write(data)
+		}
+	}
+
+	psd := &bytes.Buffer{}
+	psd.WriteString("8BPS")
+	binary.Write(psd, be, uint16(1))
+	psd.Write(make([]byte, 6))
+	binary.Write(psd, be, uint16(3))
+	binary.Write(psd, be, uint32(height))
+	binary.Write(psd, be, uint32(width))
+	binary.Write(psd, be, uint16(8))
+	binary.Write(psd, be, uint16(3))
+	binary.Write(psd, be, uint32(0)) // color mode data
+	binary.Write(psd, be, uint32(0)) // image resources
+	binary.Write(psd, be, uint32(4+layerInfo.Len()))
+	binary.Write(psd, be, uint32(layerInfo.Len()))
+	psd.Write(layerInfo.Bytes())
+	return psd.Bytes()
+}
+
+func writeTestPSD(t *testing.T, name string, data []byte) string {
+	psdPath := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(psdPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write test PSD: %s", err)
+	}
+	return psdPath
+}
+
+func TestPSDStreamPacksVisibleLayers(t *testing.T) {
+	psdPath := writeTestPSD(t, "hero.psd", encodeTestPSD(64, 64, []psdTestLayer{
+		{name: "body", bounds: image.Rect(4, 8, 36, 40), color: color.NRGBA{255, 0, 0, 255}},
+		{name: "sword", bounds: image.Rect(40, 0, 48, 30), color: color.NRGBA{0, 255, 0, 128}, compression: 1},
+		{name: "sketch", bounds: image.Rect(0, 0, 64, 64), color: color.NRGBA{0, 0, 0, 255}, hidden: true},
+	}))
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  packer.NewPSDStream(psdPath),
+		Output: outputRecorder,
+		Width:  128,
+		Height: 128,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+	expected := map[string]string{
+		"body":  ",32,32,128,128)",
+		"sword": ",8,30,128,128)",
+	}
+	for name, size := range expected {
+		found := false
+		for _, line := range strings.Split(gotStr, "\n") {
+			if strings.HasPrefix(line, "quads['"+name+"']") {
+				found = true
+				if !strings.HasSuffix(line, size) {
+					t.Errorf("Expected quad for layer '%s' to end with '%s' but got '%s'", name, size, line)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Expected a quad for layer '%s' but got\n\n%s", name, gotStr)
+		}
+	}
+	if strings.Contains(gotStr, "sketch") {
+		t.Errorf("Expected hidden layer to be skipped but got\n\n%s", gotStr)
+	}
+}
+
+func TestPSDStreamLayerHasPixelsAndBounds(t *testing.T) {
+	bounds := image.Rect(10, 20, 14, 22)
+	layerColor := color.NRGBA{10, 20, 30, 255}
+	psdPath := writeTestPSD(t, "icons.psd", encodeTestPSD(32, 32, []psdTestLayer{
+		{name: "dot", bounds: bounds, color: layerColor, compression: 1},
+	}))
+
+	assets, errc := packer.NewPSDStream(psdPath).AssetStream(context.Background())
+	var layers []*packer.PSDLayer
+	for asset := range assets {
+		layers = append(layers, asset.(*packer.PSDLayer))
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Expected no error, got '%s'", err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("Expected 1 layer but got %d", len(layers))
+	}
+
+	layer := layers[0]
+	if layer.Asset() != "icons/dot.png" {
+		t.Errorf("Expected layer asset name 'icons/dot.png' but got '%s'", layer.Asset())
+	}
+	if layer.Bounds != bounds {
+		t.Errorf("Expected layer bounds %v but got %v", bounds, layer.Bounds)
+	}
+
+	reader, err := layer.Reader()
+	if err != nil {
+		t.Fatalf("Failed to read layer: %s", err)
+	}
+	defer reader.Close()
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		t.Fatalf("Expected layer to decode but got '%s'", err)
+	}
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 2 {
+		t.Errorf("Expected a 4x2 layer image but got %v", img.Bounds())
+	}
+	if got := color.NRGBAModel.Convert(img.At(3, 1)); got != layerColor {
+		t.Errorf("Expected layer pixel %v but got %v", layerColor, got)
+	}
+}
+
+func TestPSDStreamReportsUnsupportedCompression(t *testing.T) {
+	psdPath := writeTestPSD(t, "zipped.psd", encodeTestPSD(16, 16, []psdTestLayer{
+		{name: "layer", bounds: image.Rect(0, 0, 4, 4), color: color.NRGBA{A: 255}, compression: 2},
+	}))
+
+	assets, errc := packer.NewPSDStream(psdPath).AssetStream(context.Background())
+	for range assets {
+		t.Errorf("Expected no assets to be streamed")
+	}
+	err := <-errc
+	if err == nil || !strings.Contains(err.Error(), "Unsupported layer compression 2") {
+		t.Errorf("Expected an unsupported compression error but got '%v'", err)
+	}
+}
+
+func TestPSDStreamRejectsMalformedChannels(t *testing.T) {
+	length := func(n uint32) *uint32 { return &n }
+	layer := psdTestLayer{name: "layer", bounds: image.Rect(0, 0, 4, 4), color: color.NRGBA{A: 255}}
+	valid := encodeTestPSD(16, 16, []psdTestLayer{layer})
+	tests := map[string][]byte{
+		"truncated": valid[:len(valid)-5],
+	}
+	for name, n := range map[string]uint32{"empty": 0, "short": 1, "huge": 0xffffffff} {
+		layer.channelLength = length(n)
+		tests[name] = encodeTestPSD(16, 16, []psdTestLayer{layer})
+	}
+	tests["huge layer"] = encodeTestPSD(16, 16, []psdTestLayer{{name: "layer", bounds: image.Rect(0, 0, 40000, 1)}})
+
+	for name, data := range tests {
+		psdPath := writeTestPSD(t, "broken.psd", data)
+		assets, errc := packer.NewPSDStream(psdPath).AssetStream(context.Background())
+		for range assets {
+			t.Errorf("Expected no assets of the %s PSD to be streamed", name)
+		}
+		if err := <-errc; err == nil {
+			t.Errorf("Expected the %s PSD to fail to decode", name)
+		}
+	}
+}
+
+func TestPSDStreamSpritesKeepTheirCanvasPosition(t *testing.T) {
+	psdPath := writeTestPSD(t, "hero.psd", encodeTestPSD(64, 48, []psdTestLayer{
+		{name: "body", bounds: image.Rect(4, 8, 36, 40), color: color.NRGBA{255, 0, 0, 255}},
+		{name: "sword", bounds: image.Rect(40, 0, 48, 30), color: color.NRGBA{0, 255, 0, 255}, compression: 1},
+	}))
+	offsets := target.Format{
+		Name:     "offsets",
+		Template: template.Must(template.New("offsets").Parse("{{range .Sprites}}{{.Name}} {{.TrimX}},{{.TrimY}} {{.SourceWidth}}x{{.SourceHeight}}\n{{end}}")),
+		Ext:      "txt",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:                 "atlas",
+		Format:               offsets,
+		Input:                packer.NewPSDStream(psdPath),
+		Output:               outputRecorder,
+		Width:                128,
+		Height:               128,
+		Scale:                0.5,
+		SortDescriptorByName: true,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expected := "body 2,4 32x24\nsword 20,0 32x24\n"
+	if got := string(outputRecorder.Got()["atlas-1.txt"]); got != expected {
+		t.Errorf("Expected the sprites to be offset within the scaled canvas as\n%s\nbut got\n%s", expected, got)
+	}
+}
@@ -0,0 +1,45 @@
+package packer
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"path"
+	"strings"
+)
+
+// Rasterizer renders vector assets, such as SVG files, to images.
+// Vector assets are rasterized directly at their scaled size rather
+// than being scaled after decoding, so they remain crisp at any Scale.
+//
+// The packer does not include any rasterizers, they are provided
+// through Params.Rasterizers, eg. by wrapping an SVG library.
+type Rasterizer interface {
+	// Config returns the dimensions of the asset at a scale of 1
+	Config(r io.Reader) (image.Config, error)
+	// Rasterize renders the asset to an image of the given size
+	Rasterize(r io.Reader, width, height int) (image.Image, error)
+}
+
+// rasterizerFor returns the rasterizer registered for the
+// extension of the given asset path, or nil
+func rasterizerFor(rasterizers map[string]Rasterizer, assetPath string) Rasterizer {
+	if len(rasterizers) == 0 {
+		return nil
+	}
+	return rasterizers[strings.ToLower(path.Ext(assetPath))]
+}
+
+// rasterizeAsset renders a vector asset at the given size
+func rasterizeAsset(rasterizer Rasterizer, asset Asset, assetPath string, width, height int) (image.Image, error) {
+	assetReader, err := asset.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read asset '%s': %s", assetPath, err)
+	}
+	defer assetReader.Close()
+	img, err := rasterizer.Rasterize(assetReader, width, height)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to rasterize asset '%s': %s", assetPath, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,76 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// sizeRasterizer is a Rasterizer for a fake vector format whose
+// contents are the width and height of the image, eg. "16x8"
+type sizeRasterizer struct {
+	rasterized []image.Point
+}
+
+func (r *sizeRasterizer) Config(reader io.Reader) (image.Config, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return image.Config{}, err
+	}
+	cfg := image.Config{ColorModel: color.NRGBAModel}
+	_, err = fmt.Sscanf(string(data), "%dx%d", &cfg.Width, &cfg.Height)
+	return cfg, err
+}
+
+func (r *sizeRasterizer) Rasterize(reader io.Reader, width, height int) (image.Image, error) {
+	r.rasterized = append(r.rasterized, image.Pt(width, height))
+	return newSolidImage(width, height, color.NRGBA{0, 255, 0, 255}), nil
+}
+
+func TestVectorAssetsAreRasterizedAtTheirScaledSize(t *testing.T) {
+	rasterizer := &sizeRasterizer{}
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:        "atlas",
+		Format:      target.Love,
+		Input:       NewBytesStream(map[string][]byte{"icon.vec": []byte("16x8")}),
+		Output:      outputRecorder,
+		Width:       64,
+		Height:      64,
+		Scale:       2,
+		Rasterizers: map[string]packer.Rasterizer{".vec": rasterizer},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expectedString := "quads['icon'] = love.graphics.newQuad(0,0,32,16,64,64)"
+	gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+	if !strings.Contains(gotStr, expectedString) {
+		t.Errorf("Expected descriptor to contain the following sub-string\n\n%s\n%s\n\n%s",
+			expectedString, createUnderlineString(expectedString), gotStr)
+	}
+
+	if len(rasterizer.rasterized) != 1 || rasterizer.rasterized[0] != image.Pt(32, 16) {
+		t.Errorf("Expected asset to be rasterized once at 32x16 but got %v", rasterizer.rasterized)
+	}
+
+	atlas, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+	if err != nil {
+		t.Fatalf("Expected output image to be a valid png but got '%s'", err)
+	}
+	if got := color.NRGBAModel.Convert(atlas.At(31, 15)); got != (color.NRGBA{0, 255, 0, 255}) {
+		t.Errorf("Expected rasterized pixels to be drawn into the atlas but got %v", got)
+	}
+}
@@ -0,0 +1,78 @@
+package packer
+
+import (
+	"html/template"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// reportTemplate renders the HTML pack report, a page per run with every
+// atlas image overlaid by a box per sprite that is labeled on hover
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; background: #333; color: #eee; }
+.atlas { position: relative; display: inline-block; background: repeating-conic-gradient(#777 0 25%, #999 0 50%) 0 0 / 16px 16px; }
+.atlas img { display: block; }
+.sprite { position: absolute; box-sizing: border-box; }
+.sprite:hover { outline: 1px solid #f0f; z-index: 1; }
+.sprite:hover::after { content: attr(data-name); position: absolute; left: 0; top: 100%; padding: 2px 4px; background: rgba(0, 0, 0, 0.75); white-space: nowrap; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{- range .Atlases}}
+<h2>{{.ImageFilename}} ({{.Width}}x{{.Height}}, {{len .Sprites}} sprites)</h2>
+<div class="atlas" style="width: {{.Width}}px; height: {{.Height}}px">
+<img src="{{$.ImagePrefix}}{{.ImageFilename}}" width="{{.Width}}" height="{{.Height}}" alt="{{.ImageFilename}}">
+{{- range .Sprites}}
+<div class="sprite" style="left: {{.Left}}px; top: {{.Top}}px; width: {{.Width}}px; height: {{.Height}}px" data-name="{{.Name}}" title="{{.Name}} {{.Left}},{{.Top}} {{.Width}}x{{.Height}}"></div>
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+// reportSprite is the placement of a sprite in the atlas image, which is
+// always top down unlike the Top given to descriptor templates
+type reportSprite struct {
+	Name                     string
+	Left, Top, Width, Height int
+}
+
+type reportAtlas struct {
+	ImageFilename string
+	Width, Height int
+	Sprites       []reportSprite
+}
+
+// outputReport writes the HTML pack report of the atlases. The atlas images
+// are referenced relative to the report rather than embedded in it, so
+// that the report is written without encoding the images again.
+func outputReport(outputter Outputter, filename, name string, atlases []*atlas) error {
+	depth := strings.Count(path.Clean(filepath.ToSlash(filename)), "/")
+	data := struct {
+		Name        string
+		ImagePrefix string
+		Atlases     []reportAtlas
+	}{Name: name, ImagePrefix: strings.Repeat("../", depth)}
+
+	for _, a := range atlases {
+		ra := reportAtlas{ImageFilename: a.ImageFilename, Width: a.Width, Height: a.Height}
+		for _, block := range a.Sprites {
+			spr := block.(*sprite)
+			ra.Sprites = append(ra.Sprites, reportSprite{spr.DisplayName(), spr.x, spr.y, spr.w, spr.h})
+		}
+		data.Atlases = append(data.Atlases, ra)
+	}
+
+	return withFile(outputter, filename, false, func(writer io.Writer) error {
+		return reportTemplate.Execute(writer, data)
+	})
+}
@@ -0,0 +1,65 @@
+package packer_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestReportPathOutlinesEverySprite(t *testing.T) {
+	images := map[string]image.Image{
+		"ui/button.png": newSolidImage(4, 2, color.White),
+		"hero.png":      newSolidImage(2, 2, color.White),
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:       "atlas",
+		Format:     target.Love,
+		Input:      NewImageStream(t, images),
+		Output:     outputRecorder,
+		Width:      8,
+		Height:     8,
+		ReportPath: "review/report.html",
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	report, ok := outputRecorder.Got()["review/report.html"]
+	if !ok {
+		t.Fatalf("Expected the report to be outputted but got %v", outputRecorder.Order())
+	}
+	got := string(report)
+	expected := []string{
+		// The image is referenced relative to the report
+		`<img src="../atlas-1.png" width="8" height="8"`,
+		`style="left: 0px; top: 0px; width: 4px; height: 2px" data-name="ui/button"`,
+		`style="left: 4px; top: 0px; width: 2px; height: 2px" data-name="hero"`,
+	}
+	for _, expect := range expected {
+		if !strings.Contains(got, expect) {
+			t.Errorf("Expected the report to contain\n\n%s\n\nbut got\n\n%s", expect, got)
+		}
+	}
+}
+
+func TestReportPathDoesNotSupportGroups(t *testing.T) {
+	params := &packer.Params{
+		Format:             target.Love,
+		Input:              NewImageStream(t, map[string]image.Image{}),
+		Output:             NewOutputRecorder(),
+		ReportPath:         "report.html",
+		GroupByPrefixDepth: 1,
+	}
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "ReportPath") {
+		t.Errorf("Expected ReportPath with GroupByPrefixDepth to be rejected but got '%v'", err)
+	}
+}
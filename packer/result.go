@@ -0,0 +1,106 @@
+package packer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"sync"
+)
+
+// RunResult contains information about the output of a Run
+type RunResult struct {
+	// Hashes maps the name of each outputted file to the hex
+	// encoded SHA-256 hash of the contents that were written
+	Hashes map[string]string
+	// Strategy is the sort order and placement heuristic that
+	// the atlases were packed with, see Params.Optimize. It is
+	// not set for GroupByPrefixDepth runs, as each group may differ
+	Strategy Strategy
+	// Occupancy is the fraction of each atlas, in index order, that is
+	// covered by sprites. It is not set for GroupByPrefixDepth or
+	// FolderAsAtlas runs
+	Occupancy []float64
+	// Width and Height are the size the atlases were packed with, that
+	// of Params unless it was grown by Params.AutoGrow. They are not set
+	// for GroupByPrefixDepth, FolderAsAtlas or SeparateByExtension runs,
+	// nor with AtlasSizes
+	Width, Height int
+}
+
+// hashingOutputter wraps an Outputter and computes a SHA-256
+// hash of every file as it is written
+type hashingOutputter struct {
+	Outputter
+	mu     sync.Mutex
+	hashes map[string]hash.Hash
+	sums   map[string]string
+}
+
+func newHashingOutputter(outputter Outputter) *hashingOutputter {
+	return &hashingOutputter{
+		Outputter: outputter,
+		hashes:    map[string]hash.Hash{},
+		sums:      map[string]string{},
+	}
+}
+
+// GetWriter implements the Outputter interface. Appending to a file
+// continues the hash of the contents that were previously written.
+func (h *hashingOutputter) GetWriter(filename string, append bool) (io.WriteCloser, error) {
+	writer, err := h.Outputter.GetWriter(filename, append)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hasher, ok := h.hashes[filename]
+	if !ok || !append {
+		hasher = sha256.New()
+		h.hashes[filename] = hasher
+	}
+	return &hashingWriter{WriteCloser: writer, filename: filename, hasher: hasher, outputter: h}, nil
+}
+
+// Hashes returns the hashes of every file that was closed
+func (h *hashingOutputter) Hashes() map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sums := make(map[string]string, len(h.sums))
+	for filename, sum := range h.sums {
+		sums[filename] = sum
+	}
+	return sums
+}
+
+// Filenames returns the names of every file that was closed
+func (h *hashingOutputter) Filenames() []string {
+	sums := h.Hashes()
+	filenames := make([]string, 0, len(sums))
+	for filename := range sums {
+		filenames = append(filenames, filename)
+	}
+	return filenames
+}
+
+type hashingWriter struct {
+	io.WriteCloser
+	filename  string
+	hasher    hash.Hash
+	outputter *hashingOutputter
+}
+
+func (w *hashingWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.outputter.mu.Lock()
+	w.hasher.Write(p[:n])
+	w.outputter.mu.Unlock()
+	return n, err
+}
+
+func (w *hashingWriter) Close() error {
+	w.outputter.mu.Lock()
+	w.outputter.sums[w.filename] = hex.EncodeToString(w.hasher.Sum(nil))
+	w.outputter.mu.Unlock()
+	return w.WriteCloser.Close()
+}
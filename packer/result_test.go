@@ -0,0 +1,49 @@
+package packer_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestRunResultContainsHashOfEachOutputtedFile(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:           target.Love,
+		Input:            packer.NewFilenameStream("./fixtures", files...),
+		Output:           outputRecorder,
+		Width:            400,
+		Height:           400,
+		EmitPerAtlasDesc: true,
+		EmitCombinedDesc: true,
+	}
+
+	result, err := packer.RunWithResult(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	if len(result.Hashes) != len(got) {
+		t.Errorf("Expected a hash for each of the %d outputted files but got %d", len(got), len(result.Hashes))
+	}
+	for filename, contents := range got {
+		sum := sha256.Sum256(contents)
+		expected := hex.EncodeToString(sum[:])
+		if result.Hashes[filename] != expected {
+			t.Errorf("Expected hash of '%s' to be '%s' but got '%s'", filename, expected, result.Hashes[filename])
+		}
+	}
+}
@@ -5,13 +5,28 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/rand"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/psucodervn/lovepac/packing"
 	"github.com/psucodervn/lovepac/target"
 )
 
+// maxEncodingAtlases limits the number of atlases that are drawn and
+// encoded concurrently, packing waits for a slot before continuing
+const maxEncodingAtlases = 2
+
+// NameFormatter returns the base filename of the atlas with the given
+// 1 based index. It must return a different name for every index, Run
+// fails rather than overwriting the files of an earlier atlas.
 type NameFormatter func(name string, index int) string
 
 var (
@@ -22,6 +37,10 @@ var (
 	DefaultAtlasWidth = 2048
 	// DefaultAtlasHeight is the height used if no height is specified
 	DefaultAtlasHeight = 2048
+	// DefaultMaxDecodeDimension is the MaxDecodeDimension used if none is specified
+	DefaultMaxDecodeDimension = 16384
+	// DefaultAutoGrowLimit is the AutoGrowLimit used if none is specified
+	DefaultAutoGrowLimit = 8192
 	// DefaultNameFormatter
 	DefaultNameFormatter = func(name string, index int) string {
 		return fmt.Sprintf("%s-%d", name, index)
@@ -32,16 +51,424 @@ var (
 // Input, Output and Format are required, all other options will use
 // sensible defaults if not explicitly provided.
 type Params struct {
-	Name             string
-	Input            AssetStreamer
-	Output           Outputter
-	Format           target.Format
-	Width, Height    int
-	Padding          int
-	MaxAtlases       int
-	Scale            float64
-	CombineDescFiles bool
-	NameFormatter    NameFormatter
+	// Name is the base name of the outputted files. It may contain the
+	// tokens {index}, {width}, {height} and {hash}, which are replaced
+	// by the 1 based index and size of each atlas and a hash of its
+	// layout and content, eg. "atlas_{index}_{width}x{height}". Atlases
+	// are then named by the expanded Name alone unless a NameFormatter
+	// is set, which is passed the expanded Name. Files describing every
+	// atlas, eg. combined descriptors, are named by the text before the
+	// first token.
+	Name          string
+	Input         AssetStreamer
+	Output        Outputter
+	Format        target.Format
+	Width, Height int
+	Padding       int
+	// PaddingX and PaddingY override Padding horizontally and vertically
+	// when they are set, eg. to space sprites used as vertical strips
+	// further apart side by side than above one another. Padding sets
+	// the padding of an axis that is left 0.
+	PaddingX, PaddingY int
+	MaxAtlases         int
+	Scale              float64
+	CombineDescFiles   bool
+	NameFormatter      NameFormatter
+	// AutoGrow retries a run that fails because the sprites do not fit,
+	// as a sprite is larger than the atlases or more than MaxAtlases are
+	// needed, with Width and Height doubled until it succeeds or both
+	// reach AutoGrowLimit. RunResult reports the size that was packed
+	// with. Each try reads the Input again and has its own Timeout, and
+	// is Transactional so that the tries that fail write nothing. The
+	// Progress of every try starts over from zero counts and a new
+	// Start. AtlasSizes and LayoutHint are not supported.
+	AutoGrow bool
+	// AutoGrowLimit is the largest Width and Height that AutoGrow grows
+	// the atlases to. Defaults to DefaultAutoGrowLimit.
+	AutoGrowLimit int
+	// EmitPerAtlasDesc and EmitCombinedDesc independently enable writing
+	// a descriptor file for each atlas and a single descriptor file that
+	// describes every atlas. When neither is set CombineDescFiles selects
+	// between the two, so that setting CombineDescFiles alone is the same
+	// as setting only EmitCombinedDesc.
+	EmitPerAtlasDesc bool
+	EmitCombinedDesc bool
+	// ColorModel is the pixel format of the outputted atlas images
+	ColorModel ColorModel
+	// Background is the color that transparent pixels are composited
+	// onto when an opaque ColorModel such as ColorModelRGB is used
+	Background color.Color
+	// OnProgress is called with a snapshot of the Run's progress each
+	// time a sprite is decoded or packed and each time an atlas is
+	// written. Calls are serialised, so the callback does not need to
+	// be safe for concurrent use, but it should return quickly.
+	OnProgress func(Progress)
+	// Fit shrinks each atlas to the size of its contents, Width and
+	// Height are then treated as the maximum size of an atlas
+	Fit bool
+	// MaxAspectRatio limits how long and thin atlases may become in Fit
+	// mode. The packer prefers growing in the shorter dimension and the
+	// atlas is extended with transparent space if the ratio is exceeded.
+	// A value of 0 indicates no constraint.
+	MaxAspectRatio float64
+	// ChromaKey is a color that is treated as transparent in the input
+	// images, eg. magenta in legacy sprites. Pixels whose red, green and
+	// blue channels are each within ChromaKeyTolerance of the key are
+	// made transparent before packing.
+	ChromaKey          color.Color
+	ChromaKeyTolerance uint8
+	// CollectAllErrors continues past decode and output errors so that
+	// every problem is reported at once, the returned error joins them
+	// all with errors.Join. Atlases are only packed and outputted when
+	// every asset decodes successfully. By default Run fails fast.
+	CollectAllErrors bool
+	// ScaleFunc overrides Scale for individual sprites. It is called with
+	// the name of each asset, returning 0 falls back to Scale.
+	ScaleFunc func(name string) float64
+	// MaxUpscale caps the scale of every raster sprite, including those
+	// of ScaleFunc and spec files, so that applying a uniform Scale to
+	// sprites of mixed resolutions only shrinks those that are already
+	// small rather than blurring them. Vector assets are rasterized at
+	// their scaled size and never capped. Defaults to 1.0, which never
+	// upscales, a negative value allows any scale. Raster sprites were
+	// enlarged by a Scale above 1 before MaxUpscale was added, runs that
+	// rely on it must now set MaxUpscale to at least Scale or below 0.
+	MaxUpscale float64
+	// Rasterizers maps lower case file extensions, eg. ".svg", to the
+	// Rasterizer used to render vector assets with that extension.
+	Rasterizers map[string]Rasterizer
+	// ExplodeAnimations packs each frame of an animated GIF as a separate
+	// sprite named with a frame number suffix, eg. walk_0, walk_1 etc.
+	// Otherwise only the first frame of an animated GIF is packed.
+	ExplodeAnimations bool
+	// EmitSourceMap writes a "<Name>.sourcemap.json" file, mapping the
+	// path of every packed sprite to the atlas and rect it was placed in.
+	EmitSourceMap bool
+	// ImageDPI writes a pHYs chunk recording the physical pixel density
+	// into every atlas image, for tools that size images by DPI.
+	// Zero leaves the density unspecified.
+	ImageDPI int
+	// ColorProfile tags every png atlas image with a color space, see
+	// ColorProfile. Defaults to ColorProfileNone.
+	ColorProfile ColorProfile
+	// PNGCompression is the compression level that png atlas images are
+	// encoded with, eg. png.BestCompression for the smallest files of a
+	// release build or png.BestSpeed for fast iteration. Defaults to
+	// png.DefaultCompression.
+	PNGCompression png.CompressionLevel
+	// SpriteAlign rounds the space reserved for each sprite, and the
+	// padding before it, up to a multiple of SpriteAlign pixels so every
+	// sprite is placed at an aligned position, eg. 4 for GPU uploads.
+	// The sprite is drawn at the top left of its aligned rect and the
+	// descriptor reports the real sprite rect. Zero disables alignment.
+	SpriteAlign int
+	// PlacementHeuristic chooses which free space of an atlas each
+	// sprite is placed into. Sprites are sorted largest first and then
+	// placed in order, so the sort decides which sprite is placed next
+	// and the heuristic decides where. Defaults to packing.FirstFit and
+	// is ignored when Fit is set.
+	PlacementHeuristic packing.Heuristic
+	// SplitRule chooses how the free space left around each placed sprite
+	// is divided, see packing.SplitRule. Cutting to keep the larger space
+	// whole, eg. packing.SplitShorterLeftoverAxis, leaves fewer slivers
+	// between sprites of mixed aspect ratios. Defaults to
+	// packing.SplitHorizontal and is ignored when Fit is set.
+	SplitRule packing.SplitRule
+	// FillDirection fills each atlas in rows or columns of sprites with
+	// a packing.ShelfPacker, for layouts that are predictable and easy to
+	// scan, instead of placing sprites wherever PlacementHeuristic and
+	// SplitRule choose. Sprites are then sorted tallest first for rows
+	// and widest first for columns. Defaults to packing.FillFree, Fit and
+	// LayoutHint are not supported.
+	FillDirection packing.FillDirection
+	// StablePaging gives every sprite a home atlas picked by the hash of
+	// its name, among as many atlases as the sprites need without it, and
+	// packs each atlas with the sprites whose home it is and those that
+	// did not fit the atlas before. Adding or removing a sprite then only
+	// moves the few sprites that overflow differently onto other atlases,
+	// rather than every sprite packed after it, which keeps patches of
+	// content small. It may need more atlases than packing by size alone and
+	// sprites move whenever the number of atlases without it changes.
+	StablePaging bool
+	// BalanceAcrossAtlases packs the sprites into this many
+	// atlases, each sprite going onto the atlas with the least sprite area
+	// on it so far, so that the atlases end up roughly equally full rather
+	// than filling each one before starting the next. Run fails if the
+	// sprites don't fit this many atlases. Zero fills atlases one after
+	// another as usual.
+	BalanceAcrossAtlases int
+	// MinLastAtlasOccupancy packs the last atlas again with its longer
+	// side halved, for as long as its sprites still fit, while less than
+	// this fraction of it is covered by sprites. This saves writing a
+	// full size atlas for the few sprites that spill over from the one
+	// before. The occupancy of every atlas is reported in
+	// RunResult.Occupancy. Zero keeps the last atlas at full size.
+	MinLastAtlasOccupancy float64
+	// TextureArray packs every atlas at the full Width and Height so that
+	// the atlas images can be loaded as the layers of one 2D texture
+	// array, the template data of each sprite gives its Layer, and
+	// writes a single combined descriptor unless EmitPerAtlasDesc or
+	// EmitCombinedDesc are set, eg. with target.TextureArray. It can
+	// not be used with options that size atlases differently, Fit,
+	// AtlasSizes, MaxFileBytes and MinLastAtlasOccupancy.
+	TextureArray bool
+	// VerifyNoOverlap checks that no two sprites of each atlas overlap,
+	// including their padding, once it is packed and fails the run if
+	// any do. It is a self-check against placement bugs, eg. while
+	// developing a new packing.Heuristic, and is off by default.
+	VerifyNoOverlap bool
+	// MaxDecodeDimension rejects assets wider or taller than this many
+	// pixels when their metadata is read, before they are decoded, so that
+	// a small file declaring a huge image can't exhaust the memory of a
+	// packer running on untrusted input. The size checked is that of the
+	// image as stored, before Scale. Defaults to DefaultMaxDecodeDimension,
+	// a negative value disables the check.
+	MaxDecodeDimension int
+	// EmitLabeledPreview writes a "<atlas>_labeled.png" image alongside
+	// every atlas, with each sprite outlined and labeled with its name
+	// so that artists can check everything was packed and named.
+	EmitLabeledPreview bool
+	// GroupByPrefixDepth partitions the sprites by the first N directories
+	// of their names and packs each group independently, eg. a depth of 1
+	// packs "ui/*" and "tiles/*" into separate atlas sets named
+	// "<Name>-ui" and "<Name>-tiles". Sprites in the input root keep Name.
+	// Two groups are packed at a time, so that the next group is decoded
+	// and packed while the atlases of the previous group are encoded.
+	// OnProgress reports the progress of each group separately, the
+	// snapshots of concurrent groups are interleaved.
+	GroupByPrefixDepth int
+	// FolderAsAtlas packs the sprites of each directory in the input root
+	// into its own atlas set named after the directory, eg. "ui/button.png"
+	// is packed into the "ui" atlases as the sprite "button". Sprites in
+	// the input root are packed into the atlases named Name. The sets are
+	// packed as the groups of GroupByPrefixDepth are, which can not be set
+	// as well. Include and Exclude match the full asset paths, eg.
+	// "ui/*.png", while KeepTogether and ScaleFunc are given the paths
+	// without the folder, eg. "button.png", as Aliases are given the
+	// DisplayName, eg. "button".
+	FolderAsAtlas bool
+	// SeparateByExtension packs the sprites of each file extension into
+	// their own atlas set named after it, eg. "<Name>-png" and
+	// "<Name>-jpg", so that icons and photos are not packed together.
+	// Extensions are compared ignoring case. The sets are packed as the
+	// groups of GroupByPrefixDepth are, and are further split by them or
+	// by FolderAsAtlas, eg. "<Name>-ui-png".
+	SeparateByExtension bool
+	// ExtensionContainers overrides ContainerFormat for the atlas sets of
+	// SeparateByExtension, mapping lower case file extensions, eg. ".jpg",
+	// to the container format that the atlases of their sprites are
+	// written in. It is only supported with SeparateByExtension.
+	ExtensionContainers map[string]ContainerFormat
+	// Timeout cancels the run if it takes longer than the given
+	// duration. Zero means no timeout other than the context's.
+	Timeout time.Duration
+	// Transactional stages every file in memory and only writes them to
+	// the Output once the whole run has succeeded, so that a run that
+	// fails, is cancelled or exceeds its Timeout writes nothing. Otherwise
+	// each file is written to the Output as it is encoded.
+	Transactional bool
+	// PrettyPrint indents the descriptors of JSON formats for reading and
+	// diffing, and Minify compacts them to a single line. Otherwise, and
+	// for the descriptors of other formats, descriptors are written
+	// exactly as their templates render them. They can't both be set.
+	PrettyPrint bool
+	Minify      bool
+	// Optimize tries several combinations of sort order and placement
+	// heuristic before packing and uses the one needing the fewest
+	// atlases, or the least total atlas area when atlases tie. The
+	// strategy used is reported in RunResult.Strategy.
+	Optimize bool
+	// OptimizeBudget limits the time Optimize spends trying strategies,
+	// defaults to DefaultOptimizeBudget.
+	OptimizeBudget time.Duration
+	// Seed seeds the random number generator of every randomized step of
+	// the packing, so that the same Seed and inputs give identical output.
+	// A non zero Seed makes Optimize try the strategies in a random order
+	// after the default one, which can find a better strategy within a
+	// short budget. Zero tries them in a fixed order. As Optimize stops
+	// when its budget runs out, the budget must be long enough to try
+	// every strategy for identical output on machines of any speed.
+	Seed int64
+	// DescExtension overrides the file extension of the descriptors,
+	// which defaults to the extension of the Format, eg. "txt" writes
+	// Love descriptors as "atlas-1.txt". A leading dot is ignored.
+	DescExtension string
+	// Formats writes the descriptors of the packed atlases in several
+	// formats at once, eg. Love for the game and PhaserMulti for tools,
+	// instead of the single Format which may then be left empty. Each
+	// format is written with its own extension, which must be unique,
+	// and DescExtension is ignored.
+	Formats []target.Format
+	// SplitAlpha writes the alpha channel of each atlas to a separate
+	// grayscale "<atlas>_a.png" image and the color channels to the
+	// opaque atlas image, for platforms whose texture compression has
+	// no alpha channel, eg. ETC1. Descriptors can reference the alpha
+	// image with the AlphaFilename template value, Run fails if a
+	// descriptor format can not, see target.Format.SplitAlpha.
+	SplitAlpha bool
+	// KeepTogether lists groups of asset path patterns, in path.Match
+	// syntax, eg. {"ui/panel/*"}. The sprites matching a group are always
+	// packed onto the same atlas so that drawing them never crosses a
+	// texture boundary. Run fails if a group does not fit on one atlas.
+	// Sprites matching several groups belong to the first.
+	KeepTogether [][]string
+	// OriginMode selects the corner that the Top values, and the V
+	// texture coordinates, given to descriptor templates are measured
+	// from. The Unity format converts to its bottom left origin itself
+	// so should be used with the default OriginTopLeft.
+	OriginMode OriginMode
+	// RespectEXIF rotates and mirrors JPEG assets upright according to
+	// their EXIF orientation, as photos from cameras and phones are often
+	// stored sideways. The sprite is packed with its upright size. Other
+	// image formats are unaffected. The lovepac command enables it by default.
+	RespectEXIF bool
+	// MinWidth and MinHeight are the smallest size that an atlas shrinks
+	// to in Fit mode, any extra space is left transparent. They are
+	// applied after MaxAspectRatio and are capped at Width and Height.
+	MinWidth  int
+	MinHeight int
+	// AtlasSizes gives each atlas its own maximum size, the first atlas
+	// uses the first size, the second atlas the second and so on, with
+	// the last size used for every remaining atlas. eg. a large primary
+	// atlas followed by small overflow atlases. When set, Width and
+	// Height become the largest width and height in the list.
+	AtlasSizes []image.Point
+	// PostProcess is called with every rendered atlas and its 1 based
+	// index before the atlas is converted to the ColorModel, split and
+	// encoded, eg. to color grade, watermark or premultiply the image.
+	// It may modify and return img or return a replacement image of the
+	// same size. It is called concurrently for different atlases, and
+	// again for the image of each atlas's EmitLabeledPreview.
+	PostProcess func(img *image.NRGBA, atlasIndex int) image.Image
+	// ImagePathPrefix is prepended to the ImageFilename and AlphaFilename
+	// given to descriptor templates, for runtimes that load images from a
+	// different directory than the descriptors, eg. "textures/" describes
+	// "textures/atlas-1.png". The images are still outputted as
+	// "atlas-1.png". The prefix is prepended as is, so directories should
+	// end with a slash.
+	ImagePathPrefix string
+	// NameSeparator replaces the directory separators in the DisplayName
+	// of sprites, eg. "." describes "ui/buttons/ok.png" as "ui.buttons.ok",
+	// to match the asset keys of an engine. Detected animations are named
+	// from the replaced names. Defaults to leaving the names unchanged.
+	NameSeparator string
+	// BuildTag is recorded with the lovepac Version in the meta of the
+	// descriptors of formats with a meta block, eg. a build number or
+	// timestamp to trace which build produced a shipped atlas. It is
+	// given to templates as .Meta.BuildTag, with .Meta.App and
+	// .Meta.Version. Empty tags are left out.
+	BuildTag string
+	// Aliases describes sprites under more names, keyed by the DisplayName
+	// of the sprite, eg. {"icons/coin": {"icons/currency"}}. The sprite is
+	// drawn once and every alias is described at the same rect, right
+	// after the sprite. An alias must not be the name of another sprite or
+	// alias, and the aliases of names that no sprite has are ignored.
+	Aliases map[string][]string
+	// PaddingMode selects where the Padding is reserved around each
+	// sprite, see PaddingMode for the spacing each mode results in.
+	// Defaults to PaddingShared.
+	PaddingMode PaddingMode
+	// DepFile is the name of a Makefile dependency file to write to Output,
+	// a rule making every outputted file depend on every input asset, so
+	// that make or ninja re-run the packer when any asset changes. Assets
+	// read from files are listed by their path, others by their name, and
+	// the outputted files are named as they were written to Output.
+	DepFile string
+	// LayoutHint is the source map of an earlier Run with EmitSourceMap,
+	// whose layout is reproduced to keep texture coordinates stable, eg.
+	// for patch based content delivery. Every sprite with the same path,
+	// size and content hash as before is placed at the same position of
+	// the same atlas, the other sprites are packed around them. Sprites
+	// that no longer fit where they were are packed as if they were new.
+	// Fit, MaxFileBytes, MinLastAtlasOccupancy and GroupByPrefixDepth are
+	// not supported as the atlases must keep their size.
+	LayoutHint io.Reader
+	// SortDescriptorByName describes the sprites of each atlas sorted by
+	// name, then by path, instead of in the order they were packed, so
+	// that descriptors are easy to read and diff. The layout is unchanged
+	// and Sprite.Index still gives the packing order.
+	SortDescriptorByName bool
+	// MaxFileBytes is the largest size in bytes of any outputted atlas
+	// image, eg. for the file size limit of a CDN. Every atlas is encoded
+	// once more to measure it and an atlas that is too large is repacked
+	// with its longer side halved, until its images fit and the sprites
+	// that no longer fit move on to the next atlas. An error is returned
+	// if an atlas of a single sprite is still too large. PostProcess may
+	// be called for every attempt. Zero means no limit, it is not
+	// supported with LayoutHint.
+	MaxFileBytes int
+	// ContainerFormat is the file format that atlas images, and their
+	// split alpha images, are written in. The ImageFilename given to
+	// descriptor templates has the extension of the format. Labeled
+	// previews are always PNG images. Defaults to ContainerPNG.
+	ContainerFormat ContainerFormat
+	// PreserveColorModel keeps the atlases of a set of 8-bit grayscale
+	// assets, eg. masks, in 8-bit grayscale instead of drawing them in
+	// RGBA and converting them, which uses a quarter of the memory. The
+	// space between sprites is black as there is no alpha channel. It
+	// only applies when every asset is grayscale and no ColorModel other
+	// than ColorModelGray, PostProcess, SplitAlpha or chroma key is used.
+	PreserveColorModel bool
+	// TrimMode trims the fully transparent edges of the sprites before
+	// they are packed, see TrimMode. Every asset that may be trimmed is
+	// decoded once more to find its edges. Defaults to TrimNone.
+	TrimMode TrimMode
+	// EmitPalettes reads the distinct colors of every sprite, ignoring
+	// fully transparent pixels, and gives them to templates as Palette,
+	// sorted "#rrggbbaa" strings, eg. for recoloring sprites by palette
+	// swaps. The colors are those of the assets before they are scaled.
+	// An error is returned if a sprite has more than 256 colors.
+	EmitPalettes bool
+	// ReportPath is the name of an HTML pack report to write to Output,
+	// a page showing every atlas image with the rect and name of each
+	// sprite outlined on hover, for artists to review the packed result.
+	// The images are referenced relative to the report, so browsers only
+	// show ContainerPNG atlases. It is not supported with GroupByPrefixDepth.
+	ReportPath string
+	// ScaleRounding selects how the sizes of scaled sprites are rounded
+	// to whole pixels with AllowFractionalCoordinates, see ScaleRounding.
+	// Defaults to ScaleFloor.
+	ScaleRounding ScaleRounding
+	// AllowFractionalCoordinates rounds the size of a sprite whose scale
+	// does not give it a whole number of pixels, eg. a 31 pixel sprite at
+	// a Scale of 0.5, by ScaleRounding. By default the run fails instead,
+	// so that pixel perfect engines are never given a rounded sprite. The
+	// positions and sizes of sprites given to templates are always whole
+	// pixels, so only the scaling of sizes is checked.
+	AllowFractionalCoordinates bool
+	// Include and Exclude filter the assets of Input by their names with
+	// path.Match patterns, eg. "ui/*.png". When Include is set only assets
+	// matching one of its patterns are packed, and assets matching one of
+	// the Exclude patterns are never packed, even if they are included.
+	Include, Exclude []string
+	// ImageNameFormatter names the images of each atlas independently of
+	// their descriptors, which are still named by NameFormatter, eg. to
+	// version the images for cache busting while the descriptors keep
+	// stable names. The ImageFilename and AlphaFilename given to templates
+	// and source maps are the formatted names. Defaults to NameFormatter.
+	ImageNameFormatter NameFormatter
+	// EmbedImage writes no atlas images, instead each image is encoded
+	// first and embedded in the descriptors as the ImageFilename, a
+	// base64 data URI, eg. "data:image/png;base64,...", so that a run
+	// writes a single file. It is only supported by JSON formats and not
+	// with SplitAlpha or ReportPath. ImagePathPrefix is not applied.
+	EmbedImage bool
+	// DetectAnimations groups sprites named like numbered frames, eg.
+	// "hero/walk_0" to "hero/walk_7", into animations that are given to
+	// templates as Animations, each with its Name and Frames sorted by
+	// frame number. Only sequences of at least two frames are animations.
+	DetectAnimations bool
+	// AnimationPattern matches the DisplayName of animation frames, its
+	// first subexpression captures the animation name and its second the
+	// frame number. Defaults to DefaultAnimationPattern.
+	AnimationPattern *regexp.Regexp
+	// AllowedTypes restricts the assets to the listed image types, other
+	// assets are rejected when their metadata is read, before any image is
+	// decoded. Types are the names of the decoders, eg. "png", "jpeg" or
+	// "webp", or the extensions of Rasterizers, eg. "svg". The png, jpeg,
+	// gif, bmp and webp decoders are always registered. By default every
+	// type with a registered decoder or Rasterizer is allowed.
+	AllowedTypes []string
 }
 
 // applySensibleDefaults will fill in nil values with values
@@ -50,6 +477,17 @@ func (p *Params) applySensibleDefaults() {
 	if p.Name == "" {
 		p.Name = DefaultAtlasName
 	}
+	if len(p.AtlasSizes) > 0 {
+		p.Width, p.Height = 0, 0
+		for _, size := range p.AtlasSizes {
+			if size.X > p.Width {
+				p.Width = size.X
+			}
+			if size.Y > p.Height {
+				p.Height = size.Y
+			}
+		}
+	}
 	if p.Width == 0 {
 		p.Width = DefaultAtlasWidth
 	}
@@ -59,9 +497,79 @@ func (p *Params) applySensibleDefaults() {
 	if p.Scale == 0 {
 		p.Scale = 1.0
 	}
+	if p.MaxUpscale == 0 {
+		p.MaxUpscale = 1.0
+	}
+	if p.MaxDecodeDimension == 0 {
+		p.MaxDecodeDimension = DefaultMaxDecodeDimension
+	}
+	if p.NameFormatter == nil && hasNameTokens(p.Name) {
+		p.NameFormatter = func(name string, index int) string { return name }
+	}
 	if p.NameFormatter == nil {
 		p.NameFormatter = DefaultNameFormatter
 	}
+	if p.ImageNameFormatter == nil {
+		p.ImageNameFormatter = p.NameFormatter
+	}
+	if p.Background == nil {
+		p.Background = DefaultBackground
+	}
+	if p.DescExtension == "" {
+		p.DescExtension = p.Format.Ext
+	}
+	p.DescExtension = strings.TrimPrefix(p.DescExtension, ".")
+	if !p.EmitPerAtlasDesc && !p.EmitCombinedDesc {
+		p.EmitCombinedDesc = p.CombineDescFiles || p.TextureArray
+		p.EmitPerAtlasDesc = !p.EmitCombinedDesc
+	}
+}
+
+// validateFormats checks that Format, or every one of Formats when
+// set, is valid and that no two formats share a file extension
+func (p *Params) validateFormats() error {
+	if len(p.Formats) == 0 {
+		if !p.Format.IsValid() {
+			return errors.New("Invalid 'Format' parameter")
+		}
+		if err := p.Format.Validate(); err != nil {
+			return fmt.Errorf("Invalid 'Format' template: %s", err)
+		}
+		return nil
+	}
+	extensions := map[string]string{}
+	for _, format := range p.Formats {
+		if !format.IsValid() {
+			return fmt.Errorf("Invalid format '%s' in 'Formats' parameter", format.Name)
+		}
+		if err := format.Validate(); err != nil {
+			return fmt.Errorf("Invalid template of format '%s': %s", format.Name, err)
+		}
+		if other, ok := extensions[format.Ext]; ok {
+			return fmt.Errorf("Formats '%s' and '%s' both use the extension '%s'", other, format.Name, format.Ext)
+		}
+		extensions[format.Ext] = format.Name
+	}
+	return nil
+}
+
+// descFormat is a descriptor format and the file
+// extension that its descriptors are written with
+type descFormat struct {
+	target.Format
+	ext string
+}
+
+// descFormats returns every format that descriptors are written in
+func (p *Params) descFormats() []descFormat {
+	if len(p.Formats) == 0 {
+		return []descFormat{{p.Format, p.DescExtension}}
+	}
+	formats := make([]descFormat, len(p.Formats))
+	for i, format := range p.Formats {
+		formats[i] = descFormat{format, format.Ext}
+	}
+	return formats
 }
 
 // validateRequiredParameters tests the parameters for
@@ -76,6 +584,91 @@ func (p *Params) validateRequiredParameters() error {
 	return nil
 }
 
+// validateAtlasSize tests that the atlas is large enough to fit
+// at least a single pixel sprite with the configured padding
+func (p *Params) validateAtlasSize() error {
+	if p.Padding < 0 {
+		return fmt.Errorf("Padding must not be negative, got %d", p.Padding)
+	}
+	if p.PaddingX < 0 || p.PaddingY < 0 {
+		return fmt.Errorf("PaddingX and PaddingY must not be negative, got %dx%d", p.PaddingX, p.PaddingY)
+	}
+	if p.PaddingMode < PaddingShared || p.PaddingMode > PaddingNoEdge {
+		return fmt.Errorf("Invalid PaddingMode %d", p.PaddingMode)
+	}
+	if p.TrimMode < TrimNone || p.TrimMode > TrimSymmetric {
+		return fmt.Errorf("Invalid TrimMode %d", p.TrimMode)
+	}
+	if p.ScaleRounding < ScaleFloor || p.ScaleRounding > ScaleCeil {
+		return fmt.Errorf("Invalid ScaleRounding %d", p.ScaleRounding)
+	}
+	if p.MaxUpscale > 0 && p.MaxUpscale < 1 {
+		return fmt.Errorf("MaxUpscale must be at least 1, got %g", p.MaxUpscale)
+	}
+	if p.ColorProfile < ColorProfileNone || p.ColorProfile > ColorProfileSRGB {
+		return fmt.Errorf("Invalid ColorProfile %d", p.ColorProfile)
+	}
+	if p.PNGCompression < png.BestCompression || p.PNGCompression > png.DefaultCompression {
+		return fmt.Errorf("Invalid PNGCompression %d", p.PNGCompression)
+	}
+	if len(p.AtlasSizes) == 0 {
+		return p.validateSize("Width and Height", p.Width, p.Height)
+	}
+	for i, size := range p.AtlasSizes {
+		if err := p.validateSize(fmt.Sprintf("AtlasSizes[%d]", i), size.X, size.Y); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSize checks that a sprite with padding fits in a w x h atlas
+func (p *Params) validateSize(name string, w, h int) error {
+	if w < 0 || h < 0 {
+		return fmt.Errorf("%s must not be negative, got %dx%d", name, w, h)
+	}
+	paddingX, paddingY := p.padding()
+	pixel := &sprite{w: 1, h: 1, paddingX: paddingX, paddingY: paddingY, align: p.SpriteAlign, paddingMode: p.PaddingMode}
+	minW, minH := pixel.Size()
+	overhangX, overhangY := p.overhang()
+	minW, minH = minW-overhangX, minH-overhangY
+	if w < minW || h < minH {
+		if paddingX == paddingY {
+			return fmt.Errorf("%s (%dx%d) must be at least %d to fit a sprite with %d padding",
+				name, w, h, minW, paddingX)
+		}
+		return fmt.Errorf("%s (%dx%d) must be at least %dx%d to fit a sprite with %dx%d padding",
+			name, w, h, minW, minH, paddingX, paddingY)
+	}
+	return nil
+}
+
+// pageSize returns the maximum size of the atlas with the 0 based index
+// once its longer side has been halved shrink times, to fit MaxFileBytes
+func (p *Params) pageSize(index, shrink int) (int, int) {
+	w, h := p.maxAtlasSize(index)
+	for i := 0; i < shrink; i++ {
+		if w >= h {
+			w /= 2
+		} else {
+			h /= 2
+		}
+	}
+	return w, h
+}
+
+// maxAtlasSize returns the maximum size of the atlas with the 0 based index
+func (p *Params) maxAtlasSize(index int) (int, int) {
+	if len(p.AtlasSizes) == 0 {
+		return p.Width, p.Height
+	}
+	if index >= len(p.AtlasSizes) {
+		index = len(p.AtlasSizes) - 1
+	}
+	size := p.AtlasSizes[index]
+	return size.X, size.Y
+}
+
 // Run performs the texture packing. It reads files from the given
 // AssetStreamer and outputs the results to the given Outputter
 // returning an error if any critical failures are encountered.
@@ -111,126 +704,444 @@ func (p *Params) validateRequiredParameters() error {
 //
 // MaxAtlases can be used to limit the number of atlases outputted. A value
 // of 0 is interpreted as no limit.
+//
+// Runs with Transactional set stage every file in memory and only write
+// them to the Output once the whole run has succeeded. A transactional run
+// that fails, is cancelled or exceeds its Timeout writes nothing.
+//
+// Atlases are encoded as they are packed, at most maxEncodingAtlases at a
+// time, so the decoded pixels of only a few atlases are held in memory at
+// once however many atlases are packed. Transactional runs also hold the
+// encoded files until they are committed.
 func Run(ctx context.Context, params *Params) error {
+	_, err := RunWithResult(ctx, params)
+	return err
+}
+
+// RunWithResult performs the texture packing in the same way as Run
+// and returns information about the files that were outputted.
+func RunWithResult(ctx context.Context, params *Params) (*RunResult, error) {
+	return NewPacker().Pack(ctx, params)
+}
+
+// Pack performs the texture packing in the same way as RunWithResult,
+// drawing and encoding the atlases with the buffers of earlier runs.
+func (p *Packer) Pack(ctx context.Context, params *Params) (*RunResult, error) {
+	if params != nil && params.AutoGrow {
+		return p.packGrowing(ctx, params)
+	}
+	result, err := p.pack(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !params.groupsAssets() && len(params.AtlasSizes) == 0 {
+		result.Width, result.Height = params.Width, params.Height
+	}
+	return result, nil
+}
+
+// pack performs a single texture packing run with the parameters
+func (p *Packer) pack(ctx context.Context, params *Params) (*RunResult, error) {
 	if ctx == nil {
-		return errors.New("Context must not be nil")
+		return nil, errors.New("Context must not be nil")
 	}
 	if params == nil {
-		return errors.New("Params must not be nil")
+		return nil, errors.New("Params must not be nil")
 	}
-	if !params.Format.IsValid() {
-		return errors.New("Invalid 'Format' parameter")
+	if err := params.validateFormats(); err != nil {
+		return nil, err
 	}
 
 	ctx, cancelCtx := context.WithCancel(ctx)
 	defer cancelCtx()
+	if params.Timeout > 0 {
+		ctx, cancelCtx = context.WithTimeout(ctx, params.Timeout)
+		defer cancelCtx()
+	}
 
 	// Validate the parameters
 	if err := params.validateRequiredParameters(); err != nil {
-		return err
+		return nil, err
 	}
 	params.applySensibleDefaults()
+	if err := params.validateAtlasSize(); err != nil {
+		return nil, err
+	}
+	if err := params.validateLayoutHint(); err != nil {
+		return nil, err
+	}
+	if err := params.validateFilters(); err != nil {
+		return nil, err
+	}
+	if err := params.validateAnimationPattern(); err != nil {
+		return nil, err
+	}
+	if err := params.validateEmbedImage(); err != nil {
+		return nil, err
+	}
+	if err := params.validateSplitAlpha(); err != nil {
+		return nil, err
+	}
+	if params.PrettyPrint && params.Minify {
+		return nil, errors.New("PrettyPrint and Minify can not be used together")
+	}
+	if len(params.ExtensionContainers) > 0 && !params.SeparateByExtension {
+		return nil, errors.New("ExtensionContainers is only supported with SeparateByExtension")
+	}
+	if params.ReportPath != "" && params.groupsAssets() {
+		return nil, errors.New("ReportPath does not support GroupByPrefixDepth, FolderAsAtlas or SeparateByExtension")
+	}
+	if err := params.validateBalance(); err != nil {
+		return nil, err
+	}
+	if err := params.validateMinLastAtlasOccupancy(); err != nil {
+		return nil, err
+	}
+	if err := params.validateTextureArray(); err != nil {
+		return nil, err
+	}
+	if err := params.validateFillDirection(); err != nil {
+		return nil, err
+	}
+	if params.LayoutHint != nil && hasNameTokens(params.Name) {
+		return nil, errors.New("LayoutHint does not support tokens in Name, the atlases of the hint must be named by their index")
+	}
+	if params.StablePaging && params.LayoutHint != nil {
+		return nil, errors.New("StablePaging can not be used with LayoutHint, which already keeps sprites on their atlases")
+	}
+	if params.GroupByPrefixDepth > 0 && params.FolderAsAtlas {
+		return nil, errors.New("GroupByPrefixDepth and FolderAsAtlas can not be used together")
+	}
+	output, commit := params.runOutput()
+	if params.groupsAssets() {
+		return p.runGroups(ctx, params, output, commit)
+	}
+	progress := newProgressTracker(params.OnProgress)
+	outputter := newHashingOutputter(output)
 
 	// Read the images from the input directory
-	sprites, err := readAssetStream(ctx, params.Input, params.Padding, params.Scale)
+	sprites, err := readAssetStream(ctx, params, progress)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	progress.update(func(p *Progress) { p.TotalSprites = len(sprites) })
+	if err := validateAliases(params.Aliases, sprites); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(params.Seed))
+	strategy := defaultStrategy(params)
+	if params.Optimize {
+		strategy = optimizeStrategy(ctx, sprites, params, rng)
+	}
+	sortBlocks(sprites, strategy.Sort)
+	for i, block := range sprites {
+		block.(*sprite).index = i
 	}
-	// TODO allow sorting algorithm to be specified
-	sort.Sort(packing.ByArea(sprites))
 
-	totalNumberOfSprites := len(sprites)
+	gray := params.preservesGray(sprites)
+	formats := params.descFormats()
+	units := groupUnits(sprites)
 	totalNumberOfAtlases := 0
-	completedSprites := make([]packing.Block, 0, totalNumberOfSprites)
-	incompleteSprites := make([]packing.Block, 0, totalNumberOfSprites)
+	// shrink is the number of times the current atlas was too large for MaxFileBytes
+	shrink := 0
+	newPacker := func() packing.Packer { return newAtlasPacker(params, strategy, totalNumberOfAtlases, shrink) }
+	var pins map[int][]layoutPin
+	lastPinnedAtlas := -1
+	if params.LayoutHint != nil {
+		var unpinned []packing.Block
+		if pins, unpinned, err = pinLayout(params, strategy, sprites); err != nil {
+			return nil, err
+		}
+		for index := range pins {
+			if index > lastPinnedAtlas {
+				lastPinnedAtlas = index
+			}
+		}
+		units = groupUnits(unpinned)
+		newPacker = func() packing.Packer {
+			return newPinnedPacker(params, strategy, totalNumberOfAtlases, pins[totalNumberOfAtlases])
+		}
+	}
+	var pages *pager
+	if params.StablePaging {
+		pages = newStablePager(units, stablePageCount(sprites, params, strategy))
+	}
+	if params.BalanceAcrossAtlases > 0 {
+		if pages, err = newBalancedPager(units, params, strategy); err != nil {
+			return nil, err
+		}
+	}
+	if pages != nil {
+		units = pages.next(0, nil)
+	}
 	wg := &sync.WaitGroup{}
+	// embedded counts the atlases whose images are still being embedded
+	embedded := &sync.WaitGroup{}
 	errc := make(chan error)
+	encoding := make(chan struct{}, maxEncodingAtlases)
+	// descAtlases are appended as they are packed, so combined descriptors
+	// list them in index order however their images finish encoding
 	var descAtlases []*atlas
+	var atlases []*atlas
+	var errs []error
+	atlasNames := map[string]int{}
+	imageNames := map[string]int{}
 	for {
 		// Return error if maxAtlases param exceeded
 		if params.MaxAtlases > 0 && totalNumberOfAtlases == params.MaxAtlases {
-			return fmt.Errorf("Maximum number of atlases (%d) exceeded", params.MaxAtlases)
+			return nil, fmt.Errorf("Maximum number of atlases (%d) exceeded: %w", params.MaxAtlases, packing.ErrOutOfRoom)
 		}
 
 		// Arrange the images into the atlas space
-		completedSprites = completedSprites[:0]
-		incompleteSprites = incompleteSprites[:0]
-		packer := packing.NewBinPacker(params.Width, params.Height)
-		for _, sprite := range sprites {
-			switch packer.Pack(sprite) {
-			case packing.ErrInputTooLarge:
-				return packing.ErrInputTooLarge
-			case packing.ErrOutOfRoom:
-				incompleteSprites = append(incompleteSprites, sprite)
-			default:
-				completedSprites = append(completedSprites, sprite)
+		packer, completedSprites, incompleteUnits, err := packAtlas(newPacker, units)
+		if err != nil {
+			return nil, err
+		}
+		if atlasPins := pins[totalNumberOfAtlases]; len(atlasPins) > 0 {
+			for _, pin := range atlasPins {
+				completedSprites = append(completedSprites, pin.spr)
 			}
+			sort.Slice(completedSprites, func(i, j int) bool {
+				return completedSprites[i].(*sprite).index < completedSprites[j].(*sprite).index
+			})
+		}
+
+		atlasWidth, atlasHeight := atlasSize(packer, params, totalNumberOfAtlases, shrink)
+		last := len(incompleteUnits) == 0 && totalNumberOfAtlases > lastPinnedAtlas && (pages == nil || !pages.remaining())
+		for last && occupancy(completedSprites, atlasWidth, atlasHeight) < params.MinLastAtlasOccupancy {
+			// Try packing the nearly empty last atlas into a smaller one
+			shrink++
+			smaller, smallerSprites, rest, err := packAtlas(newPacker, units)
+			if err != nil || len(rest) > 0 {
+				// Place the sprites again as they were at the previous size
+				shrink--
+				var rest []*packUnit
+				if packer, completedSprites, rest, err = packAtlas(newPacker, units); err != nil {
+					return nil, err
+				}
+				if len(rest) > 0 {
+					return nil, fmt.Errorf("Failed to pack the last atlas again at its previous size, %d sprites no longer fit", len(rest))
+				}
+				break
+			}
+			packer, completedSprites = smaller, smallerSprites
+			atlasWidth, atlasHeight = atlasSize(packer, params, totalNumberOfAtlases, shrink)
+		}
+		if params.VerifyNoOverlap {
+			if err := verifyNoOverlap(completedSprites, totalNumberOfAtlases+1); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, block := range completedSprites {
+			spr := block.(*sprite)
+			spr.origin = params.OriginMode
+			spr.atlasW, spr.atlasH = atlasWidth, atlasHeight
+			spr.layer = totalNumberOfAtlases
 		}
 
 		totalNumberOfAtlases++
-		atlasName := params.NameFormatter(params.Name, totalNumberOfAtlases)
-		descName := params.NameFormatter(params.Name, totalNumberOfAtlases)
-		if params.CombineDescFiles {
-			descName = params.Name
+		name, err := expandName(params.Name, totalNumberOfAtlases, atlasWidth, atlasHeight, completedSprites)
+		if err != nil {
+			return nil, err
+		}
+		atlasName := params.NameFormatter(name, totalNumberOfAtlases)
+		imageName := params.ImageNameFormatter(name, totalNumberOfAtlases)
+		// A formatter that ignores the index would overwrite the same files
+		if index, ok := atlasNames[atlasName]; ok {
+			return nil, fmt.Errorf("NameFormatter returned '%s' for both atlas %d and %d, names must be unique for each index",
+				atlasName, index, totalNumberOfAtlases)
+		}
+		if index, ok := imageNames[imageName]; ok {
+			return nil, fmt.Errorf("ImageNameFormatter returned '%s' for both atlas %d and %d, names must be unique for each index",
+				imageName, index, totalNumberOfAtlases)
 		}
+		atlasNames[atlasName] = totalNumberOfAtlases
+		imageNames[imageName] = totalNumberOfAtlases
 		atlas := &atlas{
-			Name:         atlasName,
-			Sprites:      make([]packing.Block, len(completedSprites)),
-			DescFilename: fmt.Sprintf("%s.%s", descName, params.Format.Ext),
-			// TODO add image type parameter
-			ImageFilename: fmt.Sprintf("%s.%s", atlasName, "png"),
-			Width:         params.Width,
-			Height:        params.Height,
-			Scale:         params.Scale,
+			Name:            atlasName,
+			Sprites:         make([]packing.Block, len(completedSprites)),
+			DescFilename:    fmt.Sprintf("%s.%s", atlasName, formats[0].ext),
+			ImageFilename:   fmt.Sprintf("%s.%s", imageName, params.ContainerFormat.ext()),
+			Width:           atlasWidth,
+			Height:          atlasHeight,
+			Occupancy:       occupancy(completedSprites, atlasWidth, atlasHeight),
+			Scale:           params.Scale,
+			Meta:            params.meta(),
+			colorModel:      params.ColorModel,
+			background:      params.Background,
+			dpi:             params.ImageDPI,
+			colorProfile:    params.ColorProfile,
+			pngCompression:  params.PNGCompression,
+			container:       params.ContainerFormat,
+			gray:            gray,
+			jsonStyle:       params.jsonStyle(),
+			index:           totalNumberOfAtlases,
+			postProcess:     params.PostProcess,
+			imagePathPrefix: params.ImagePathPrefix,
+			embedImage:      params.EmbedImage,
+			canvases:        &p.canvases,
+			pngBuffers:      &p.pngBuffers,
+		}
+		if params.SplitAlpha {
+			atlas.AlphaFilename = alphaFilename(imageName, params.ContainerFormat)
 		}
 		copy(atlas.Sprites, completedSprites)
+		if params.SortDescriptorByName {
+			sortSpritesByName(atlas.Sprites)
+		}
+		if len(params.Aliases) > 0 {
+			atlas.descSprites = withAliases(atlas.Sprites, params.Aliases)
+		}
+		if params.DetectAnimations {
+			atlas.Animations = detectAnimations(atlas.Sprites, params.AnimationPattern)
+		}
+		if params.MaxFileBytes > 0 {
+			fits, err := atlas.imagesFit(params.MaxFileBytes)
+			if err != nil {
+				return nil, err
+			}
+			if !fits {
+				if len(completedSprites) == 1 {
+					return nil, fmt.Errorf("Atlas %d exceeds MaxFileBytes (%d) with only the sprite '%s' on it",
+						totalNumberOfAtlases, params.MaxFileBytes, completedSprites[0].(*sprite).path)
+				}
+				// Pack the same sprites again into a smaller atlas
+				delete(atlasNames, atlasName)
+				delete(imageNames, imageName)
+				totalNumberOfAtlases--
+				shrink++
+				continue
+			}
+			shrink = 0
+		}
+		atlases = append(atlases, atlas)
+		progress.update(func(p *Progress) { p.Packed += len(completedSprites) })
 
-		if params.CombineDescFiles {
+		output := func(outputter Outputter) error { return atlas.Output(outputter, formats) }
+		if !params.EmitPerAtlasDesc {
+			output = atlas.OutputImage
+			if params.EmbedImage {
+				output = func(Outputter) error { return atlas.embed() }
+			}
+		}
+		if params.EmitCombinedDesc {
 			descAtlases = append(descAtlases, atlas)
-			wg.Add(1)
-			go func(ctx context.Context, errc chan<- error, wg *sync.WaitGroup) {
-				select {
-				case errc <- atlas.OutputImage(params.Output, params.Format.Template):
-				case <-ctx.Done():
-				}
-				wg.Done()
-			}(ctx, errc, wg)
-		} else {
-			wg.Add(1)
-			go func(ctx context.Context, errc chan<- error, wg *sync.WaitGroup) {
-				select {
-				case errc <- atlas.Output(params.Output, params.Format.Template):
-				case <-ctx.Done():
+		}
+		encode := func() error {
+			err := output(outputter)
+			if err == nil && params.EmitLabeledPreview {
+				err = atlas.OutputLabeledPreview(outputter)
+			}
+			if err == nil {
+				progress.update(func(p *Progress) { p.Encoded++ })
+			}
+			atlas.releaseSprites()
+			return err
+		}
+		// Runs whose sprites all fit the first atlas, eg. small icon sets,
+		// encode it right away instead of in a goroutine waiting for a slot
+		if last && totalNumberOfAtlases == 1 {
+			if err := encode(); err != nil {
+				if !params.CollectAllErrors {
+					return nil, err
 				}
-				wg.Done()
-			}(ctx, errc, wg)
+				errs = append(errs, err)
+			}
+			break
+		}
+		select {
+		case encoding <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		if params.EmbedImage {
+			embedded.Add(1)
 		}
+		go func(ctx context.Context, errc chan<- error, wg *sync.WaitGroup) {
+			err := encode()
+			if params.EmbedImage {
+				embedded.Done()
+			}
+			// Free the slot before reporting, errors are only drained once packing is done
+			<-encoding
+			select {
+			case errc <- err:
+			case <-ctx.Done():
+			}
+			wg.Done()
+		}(ctx, errc, wg)
 
-		totalNumberOfIncompletedSprites := len(incompleteSprites)
-		// If there are no more sprites that are incomplete, we are done!
-		if totalNumberOfIncompletedSprites == 0 {
+		// If there are no more sprites that are incomplete, or pinned, we are done!
+		if len(incompleteUnits) == 0 && totalNumberOfAtlases > lastPinnedAtlas && (pages == nil || !pages.remaining()) {
 			break
 		}
 		// If we don't make any progress, then we've failed
-		if totalNumberOfIncompletedSprites == totalNumberOfSprites {
-			return packing.ErrOutOfRoom
+		if len(completedSprites) == 0 && len(incompleteUnits) > 0 {
+			if shrink > 0 {
+				return nil, fmt.Errorf("Atlas %d can not be shrunk to fit MaxFileBytes (%d): %w",
+					totalNumberOfAtlases, params.MaxFileBytes, packing.ErrOutOfRoom)
+			}
+			return nil, packing.ErrOutOfRoom
 		}
 		// Otherwise continue
-		sprites = incompleteSprites
+		units = incompleteUnits
+		if pages != nil {
+			units = pages.next(totalNumberOfAtlases, incompleteUnits)
+		}
 	}
+	progress.update(func(p *Progress) { p.TotalAtlases = totalNumberOfAtlases })
 
-	if len(descAtlases) > 0 {
+	for _, format := range formats {
+		if len(descAtlases) == 0 {
+			break
+		}
+		filename := fmt.Sprintf("%s.%s", params.runName(), format.ext)
 		wg.Add(1)
-		go func(ctx context.Context, errc chan<- error, wg *sync.WaitGroup) {
+		go func(ctx context.Context, errc chan<- error, wg *sync.WaitGroup, format descFormat) {
 			defer wg.Done()
-			for i := range descAtlases {
-				atlas := descAtlases[i]
+			// Embedded images are only known once every atlas is encoded
+			embedded.Wait()
+			combinedAtlases := make([]*atlas, len(descAtlases))
+			for i, atlas := range descAtlases {
+				combinedAtlases[i] = atlas.withDescFilename(filename)
+			}
+			// Multi formats describe every atlas at once, others are appended one after another
+			if format.Multi {
+				set := &atlasSet{Name: params.runName(), Atlases: combinedAtlases, Meta: params.meta(), jsonStyle: params.jsonStyle()}
+				if params.DetectAnimations {
+					var sprites []packing.Block
+					for _, atlas := range combinedAtlases {
+						sprites = append(sprites, atlas.Sprites...)
+					}
+					set.Animations = detectAnimations(sprites, params.AnimationPattern)
+				}
 				select {
-				case errc <- atlas.OutputDesc(params.Output, i > 0, params.Format.Template):
+				case errc <- outputAtlasSet(outputter, filename, set, format.Format):
+				case <-ctx.Done():
+				}
+				return
+			}
+			for i := range combinedAtlases {
+				atlas := combinedAtlases[i]
+				select {
+				case errc <- atlas.OutputDesc(outputter, i > 0, format.Format):
 				case <-ctx.Done():
 					return
 				}
 			}
+		}(ctx, errc, wg, format)
+	}
+
+	if params.EmitSourceMap {
+		wg.Add(1)
+		go func(ctx context.Context, errc chan<- error, wg *sync.WaitGroup) {
+			defer wg.Done()
+			select {
+			case errc <- outputSourceMap(outputter, sourceMapFilename(params.runName()), atlases):
+			case <-ctx.Done():
+			}
 		}(ctx, errc, wg)
 	}
 
@@ -241,11 +1152,98 @@ func Run(ctx context.Context, params *Params) error {
 
 	for err := range errc {
 		if err != nil {
-			return err
+			if !params.CollectAllErrors {
+				return nil, err
+			}
+			errs = append(errs, err)
 		}
 	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 
-	return nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params.ReportPath != "" {
+		if err := outputReport(outputter, params.ReportPath, params.runName(), atlases); err != nil {
+			return nil, err
+		}
+	}
+	if params.DepFile != "" {
+		assets := make([]Asset, len(sprites))
+		for i, block := range sprites {
+			assets[i] = block.(*sprite).Asset
+		}
+		if err := outputDepFile(outputter, params.DepFile, outputter.Filenames(), assets); err != nil {
+			return nil, err
+		}
+	}
+	if err := commit(); err != nil {
+		return nil, err
+	}
+	occupancies := make([]float64, len(atlases))
+	for i, atlas := range atlases {
+		occupancies[i] = atlas.Occupancy
+	}
+	return &RunResult{Hashes: outputter.Hashes(), Strategy: strategy, Occupancy: occupancies}, nil
+}
+
+// atlasSize returns the size of the atlas filled by the packer. Atlases
+// in Fit mode shrink to their contents, within the aspect ratio and
+// minimum size constraints, others are always the maximum size.
+func atlasSize(packer packing.Packer, params *Params, index, shrink int) (int, int) {
+	maxW, maxH := params.pageSize(index, shrink)
+	growingPacker, ok := packer.(*packing.GrowingPacker)
+	if !ok {
+		return maxW, maxH
+	}
+	w, h := growingPacker.Size()
+	// Padding overhanging the right and bottom edges is not part of the atlas
+	overhangX, overhangY := params.overhang()
+	w, h = w-overhangX, h-overhangY
+	w, h = constrainAspectRatio(w, h, params.MaxAspectRatio, maxW, maxH)
+	return constrainMinSize(w, h, params.MinWidth, params.MinHeight, maxW, maxH)
+}
+
+// constrainMinSize extends a w x h atlas to at least minW x minH,
+// without exceeding the maximum width and height
+func constrainMinSize(w, h, minW, minH, maxW, maxH int) (int, int) {
+	if w < minW {
+		w = minW
+		if w > maxW {
+			w = maxW
+		}
+	}
+	if h < minH {
+		h = minH
+		if h > maxH {
+			h = maxH
+		}
+	}
+	return w, h
+}
+
+// constrainAspectRatio extends the shorter side of a w x h atlas so that the
+// longer side is at most maxAspectRatio times the shorter side, without
+// exceeding the maximum width and height. A ratio of 0 means no constraint.
+func constrainAspectRatio(w, h int, maxAspectRatio float64, maxW, maxH int) (int, int) {
+	if maxAspectRatio <= 0 || w == 0 || h == 0 {
+		return w, h
+	}
+	if minH := int(math.Ceil(float64(w) / maxAspectRatio)); h < minH {
+		h = minH
+		if h > maxH {
+			h = maxH
+		}
+	}
+	if minW := int(math.Ceil(float64(h) / maxAspectRatio)); w < minW {
+		w = minW
+		if w > maxW {
+			w = maxW
+		}
+	}
+	return w, h
 }
 
 type assetDecodeResult struct {
@@ -253,11 +1251,11 @@ type assetDecodeResult struct {
 	Err    error
 }
 
-func readAssetStream(ctx context.Context, assetStream AssetStreamer, padding int, scale float64) ([]packing.Block, error) {
+func readAssetStream(ctx context.Context, params *Params, progress *progressTracker) ([]packing.Block, error) {
 	ctx, cancelCtx := context.WithCancel(ctx)
 	defer cancelCtx()
 	// Stream the input
-	assets, errc := assetStream.AssetStream(ctx)
+	assets, errc := params.Input.AssetStream(ctx)
 	// Create decoder pool
 	out := make(chan *assetDecodeResult)
 	const numDecoders = 5
@@ -265,7 +1263,7 @@ func readAssetStream(ctx context.Context, assetStream AssetStreamer, padding int
 	wg.Add(numDecoders)
 	for i := 0; i < numDecoders; i++ {
 		go func() {
-			decode(ctx, padding, scale, assets, out)
+			decode(ctx, params, assets, out)
 			wg.Done()
 		}()
 	}
@@ -276,24 +1274,53 @@ func readAssetStream(ctx context.Context, assetStream AssetStreamer, padding int
 	}()
 	// Copy results from the out channel to the sprites slice
 	var sprites []packing.Block
+	var errs []error
 	for res := range out {
 		if res.Err != nil {
-			return nil, res.Err
+			if !params.CollectAllErrors {
+				return nil, res.Err
+			}
+			errs = append(errs, res.Err)
+			continue
 		}
 		sprites = append(sprites, res.Sprite)
+		progress.update(func(p *Progress) { p.Decoded++ })
 	}
 	// Check if the asset stream failed
 	if err := <-errc; err != nil {
-		return nil, err
+		if !params.CollectAllErrors {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
+	// Assets are decoded concurrently, order the sprites by path so that
+	// sprites of equal size are always packed in the same order
+	sort.Slice(sprites, func(i, j int) bool {
+		return sprites[i].(*sprite).path < sprites[j].(*sprite).path
+	})
 	return sprites, nil
 }
 
+// readAssetOrientation reads the EXIF orientation of a JPEG asset
+func readAssetOrientation(asset Asset) (int, error) {
+	assetReader, err := asset.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer assetReader.Close()
+	return readJPEGOrientation(assetReader), nil
+}
+
 // Decodes assets from the in channel and publishes the results to
 // the out channel. Will continue even after errors have been discovered
 // cancel the context to interrupt early.
-func decode(ctx context.Context, padding int, scale float64, in <-chan Asset, out chan<- *assetDecodeResult) {
+func decode(ctx context.Context, params *Params, in <-chan Asset, out chan<- *assetDecodeResult) {
+	chromaKey := newChromaKey(params.ChromaKey, params.ChromaKeyTolerance)
+
 	publishResult := func(spr *sprite, err error) {
 		select {
 		case out <- &assetDecodeResult{spr, err}:
@@ -302,28 +1329,158 @@ func decode(ctx context.Context, padding int, scale float64, in <-chan Asset, ou
 	}
 
 	for asset := range in {
-		assetPath := asset.Asset()
-		assetReader, err := asset.Reader()
+		if !params.includes(asset.Asset()) {
+			continue
+		}
+		sprites, err := decodeSprites(params, asset, chromaKey)
 		if err != nil {
-			publishResult(nil, fmt.Errorf("Failed to read asset '%s': %s", assetPath, err))
+			publishResult(nil, err)
 			continue
 		}
-		defer assetReader.Close()
+		for _, spr := range sprites {
+			publishResult(spr, nil)
+		}
+	}
+}
+
+// decodeSprites reads the metadata of an asset and returns the
+// sprites that it contains, most assets contain a single sprite.
+func decodeSprites(params *Params, asset Asset, chromaKey *chromaKey) ([]*sprite, error) {
+	assetPath := asset.Asset()
+	assetReader, err := asset.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read asset '%s': %s", assetPath, err)
+	}
+	defer assetReader.Close()
+
+	rasterizer := rasterizerFor(params.Rasterizers, assetPath)
+	var cfg image.Config
+	var format string
+	if rasterizer != nil {
+		cfg, err = rasterizer.Config(assetReader)
+	} else {
+		cfg, format, err = image.DecodeConfig(assetReader)
+	}
+	if errors.Is(err, image.ErrFormat) {
+		return nil, fmt.Errorf("Asset '%s' is not an image of a supported type, png, jpeg, gif, bmp and webp are supported and other types need their decoder registered", assetPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read asset metadata '%s': %s", assetPath, err)
+	}
+	if err := params.checkAllowedType(assetPath, format, rasterizer != nil); err != nil {
+		return nil, err
+	}
+	if limit := params.MaxDecodeDimension; limit > 0 && (cfg.Width > limit || cfg.Height > limit) {
+		return nil, fmt.Errorf("Asset '%s' is %dx%d, larger than the MaxDecodeDimension of %d pixels",
+			assetPath, cfg.Width, cfg.Height, limit)
+	}
+
+	orientation := 1
+	if params.RespectEXIF && format == "jpeg" {
+		orientation, err = readAssetOrientation(asset)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read asset '%s': %s", assetPath, err)
+		}
+		if orientationSwapsSides(orientation) {
+			cfg.Width, cfg.Height = cfg.Height, cfg.Width
+		}
+	}
 
-		cfg, _, err := image.DecodeConfig(assetReader)
+	scale := params.Scale
+	if params.ScaleFunc != nil {
+		if s := params.ScaleFunc(assetPath); s != 0 {
+			scale = s
+		}
+	}
+	var spec SpriteSpec
+	if specAsset, ok := asset.(SpecAsset); ok {
+		spec = specAsset.Spec()
+	}
+	if spec.Scale != nil {
+		scale = *spec.Scale
+	}
+	if rasterizer == nil && params.MaxUpscale > 0 && scale > params.MaxUpscale {
+		scale = params.MaxUpscale
+	}
+	if !params.AllowFractionalCoordinates {
+		w, h := cfg.Width, cfg.Height
+		if isNinePatch(assetPath) {
+			w, h = w-2, h-2
+		}
+		if !exactlyScaled(w, scale) || !exactlyScaled(h, scale) {
+			return nil, fmt.Errorf("Asset '%s' of %dx%d scaled by %g is %gx%g, not a whole number of pixels",
+				assetPath, w, h, scale, float64(w)*scale, float64(h)*scale)
+		}
+	}
+	paddingX, paddingY := params.padding()
+	if spec.Padding != nil {
+		paddingX, paddingY = *spec.Padding, *spec.Padding
+	}
+
+	spr := &sprite{
+		Asset:         asset,
+		path:          assetPath,
+		w:             params.ScaleRounding.scale(cfg.Width, scale),
+		h:             params.ScaleRounding.scale(cfg.Height, scale),
+		paddingX:      paddingX,
+		paddingY:      paddingY,
+		align:         params.SpriteAlign,
+		paddingMode:   params.PaddingMode,
+		keepTogether:  keepTogetherGroup(params.KeepTogether, assetPath),
+		chromaKey:     chromaKey,
+		rasterizer:    rasterizer,
+		orientation:   orientation,
+		gray:          rasterizer == nil && cfg.ColorModel == color.GrayModel,
+		pivot:         specPivot(spec),
+		nameSeparator: params.NameSeparator,
+	}
+
+	// Nine-patch images must be fully decoded to read the
+	// 9-slice guides, which are stripped from the packed sprite
+	if isNinePatch(assetPath) {
+		if cfg.Width < 3 || cfg.Height < 3 {
+			return nil, fmt.Errorf("Nine-patch asset '%s' is too small to contain guides", assetPath)
+		}
+		img, err := decodeAsset(asset, assetPath)
 		if err != nil {
-			publishResult(nil, fmt.Errorf("Failed to read asset metadata '%s': %s", assetPath, err))
-			continue
+			return nil, err
+		}
+		spr.ninePatch = true
+		spr.border = readNinePatchBorder(img).scale(scale, params.ScaleRounding)
+		spr.w = params.ScaleRounding.scale(cfg.Width-2, scale)
+		spr.h = params.ScaleRounding.scale(cfg.Height-2, scale)
+	}
+
+	if !params.ExplodeAnimations || format != "gif" {
+		if err := trimSprite(spr, params.TrimMode, scale, params.ScaleRounding); err != nil {
+			return nil, err
 		}
+	}
+	if layer, ok := asset.(*PSDLayer); ok {
+		layer.placeInCanvas(spr, scale, params.ScaleRounding)
+	}
+
+	// Fail early if the sprite can never fit, rather than when it is packed
+	w, h := spr.Size()
+	overhangX, overhangY := params.overhang()
+	if w, h = w-overhangX, h-overhangY; w > params.Width || h > params.Height {
+		return nil, fmt.Errorf("Asset '%s' needs %dx%d including padding and can never fit in a %dx%d atlas: %w",
+			assetPath, w, h, params.Width, params.Height, packing.ErrInputTooLarge)
+	}
 
-		spr := &sprite{
-			Asset:   asset,
-			path:    assetPath,
-			w:       int(float64(cfg.Width) * scale),
-			h:       int(float64(cfg.Height) * scale),
-			padding: padding,
+	sprites := []*sprite{spr}
+	if params.ExplodeAnimations && format == "gif" {
+		if sprites, err = explodeGIF(spr); err != nil {
+			return nil, err
 		}
+	}
 
-		publishResult(spr, nil)
+	if params.EmitPalettes {
+		for _, spr := range sprites {
+			if err := readPalette(spr); err != nil {
+				return nil, err
+			}
+		}
 	}
+	return sprites, nil
 }
@@ -1,13 +1,31 @@
 package packer_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"strings"
+	"text/template"
 
 	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/packing"
 	"github.com/psucodervn/lovepac/target"
 )
 
@@ -168,6 +186,428 @@ func TestRunWithTooManyFilesForOneAtlasResultsInMultipleAtlases(t *testing.T) {
 	}
 }
 
+func TestRunWithNameFormatterIgnoringIndexResultsInError(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:        target.Love,
+		Input:         packer.NewFilenameStream("./fixtures", files...),
+		Output:        outputRecorder,
+		Width:         400,
+		Height:        400,
+		NameFormatter: func(name string, index int) string { return name },
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "for both atlas 1 and 2") {
+		t.Errorf("Expected a name collision error but got '%v'", err)
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected nothing to be outputted but got %d files", len(got))
+	}
+}
+
+func TestImageNameFormatterNamesImagesApartFromDescriptors(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Starling,
+		Input: NewImageStream(t, map[string]image.Image{
+			"a.png": newSolidImage(4, 4, color.White),
+			"b.png": newSolidImage(4, 4, color.White),
+		}),
+		Output:     outputRecorder,
+		Width:      4,
+		Height:     4,
+		SplitAlpha: true,
+		ImageNameFormatter: func(name string, index int) string {
+			return fmt.Sprintf("%s-%d.v2", name, index)
+		},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	for i := 1; i <= 2; i++ {
+		for _, filename := range []string{
+			fmt.Sprintf("atlas-%d.xml", i),
+			fmt.Sprintf("atlas-%d.v2.png", i),
+			fmt.Sprintf("atlas-%d.v2_a.png", i),
+		} {
+			if _, ok := got[filename]; !ok {
+				t.Errorf("Expected '%s' to be outputted but got %v", filename, got)
+			}
+		}
+		desc := string(got[fmt.Sprintf("atlas-%d.xml", i)])
+		expected := fmt.Sprintf(`imagePath="atlas-%d.v2.png" alphaPath="atlas-%d.v2_a.png"`, i, i)
+		if !strings.Contains(desc, expected) {
+			t.Errorf("Expected the descriptor to reference its images with\n\n%s\n\nbut got\n\n%s", expected, desc)
+		}
+	}
+}
+
+func TestRunCanEmitPerAtlasAndCombinedDescriptors(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	tests := []struct {
+		perAtlas, combined bool
+		expected           []string
+	}{
+		{true, false, []string{"atlas-1.png", "atlas-2.png", "atlas-1.lua", "atlas-2.lua"}},
+		{false, true, []string{"atlas-1.png", "atlas-2.png", "atlas.lua"}},
+		{true, true, []string{"atlas-1.png", "atlas-2.png", "atlas-1.lua", "atlas-2.lua", "atlas.lua"}},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:             "atlas",
+			Format:           target.Love,
+			Input:            packer.NewFilenameStream("./fixtures", files...),
+			Output:           outputRecorder,
+			Width:            400,
+			Height:           400,
+			EmitPerAtlasDesc: test.perAtlas,
+			EmitCombinedDesc: test.combined,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Errorf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		got := outputRecorder.Got()
+		if len(got) != len(test.expected) {
+			t.Errorf("Expected %d files to be outputted but got %d", len(test.expected), len(got))
+		}
+		for _, expect := range test.expected {
+			if _, ok := got[expect]; !ok {
+				t.Errorf("Expected file '%s' to be outputted", expect)
+			}
+		}
+
+		if test.combined {
+			combined := string(got["atlas.lua"])
+			if n := strings.Count(combined, "return quads"); n != 2 {
+				t.Errorf("Expected combined descriptor to describe 2 atlases but got %d", n)
+			}
+		}
+	}
+}
+
+func TestRunWritesDescriptorsInEveryFormat(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	tests := []struct {
+		perAtlas, combined bool
+		expected           []string
+	}{
+		{true, false, []string{"atlas-1.png", "atlas-2.png", "atlas-1.lua", "atlas-2.lua", "atlas-1.json", "atlas-2.json"}},
+		{false, true, []string{"atlas-1.png", "atlas-2.png", "atlas.lua", "atlas.json"}},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:             "atlas",
+			Formats:          []target.Format{target.Love, target.PhaserMulti},
+			Input:            packer.NewFilenameStream("./fixtures", files...),
+			Output:           outputRecorder,
+			Width:            400,
+			Height:           400,
+			EmitPerAtlasDesc: test.perAtlas,
+			EmitCombinedDesc: test.combined,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		got := outputRecorder.Got()
+		if len(got) != len(test.expected) {
+			t.Errorf("Expected %d files to be outputted but got %d", len(test.expected), len(got))
+		}
+		for _, expect := range test.expected {
+			if _, ok := got[expect]; !ok {
+				t.Errorf("Expected file '%s' to be outputted", expect)
+			}
+		}
+
+		if test.combined {
+			if n := strings.Count(string(got["atlas.lua"]), "return quads"); n != 2 {
+				t.Errorf("Expected combined love descriptor to describe 2 atlases but got %d", n)
+			}
+			if n := strings.Count(string(got["atlas.json"]), `"image":`); n != 2 {
+				t.Errorf("Expected combined phaser descriptor to describe 2 atlases but got %d", n)
+			}
+		}
+	}
+}
+
+func TestRunWithFormatsSharingAnExtensionResultsInError(t *testing.T) {
+	params := &packer.Params{
+		Name:    "atlas",
+		Formats: []target.Format{target.Love, {Name: "other", Template: target.Love.Template, Ext: "lua"}},
+		Input:   packer.NewFilenameStream("./fixtures", "button.png"),
+		Output:  NewOutputRecorder(),
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "both use the extension 'lua'") {
+		t.Errorf("Expected an extension collision error but got '%v'", err)
+	}
+}
+
+func TestCombinedDescriptorListsAtlasesInIndexOrder(t *testing.T) {
+	const numAtlases = 12
+	images := map[string]image.Image{}
+	for i := 0; i < numAtlases; i++ {
+		images[fmt.Sprintf("sprite_%02d.png", i)] = newSolidImage(4, 4, color.White)
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Formats:          []target.Format{target.Starling, target.PhaserMulti},
+		Input:            NewImageStream(t, images),
+		Output:           outputRecorder,
+		Width:            4,
+		Height:           4,
+		EmitCombinedDesc: true,
+		// Later atlases finish encoding before earlier ones
+		PostProcess: func(img *image.NRGBA, atlasIndex int) image.Image {
+			time.Sleep(time.Duration(numAtlases-atlasIndex) * time.Millisecond)
+			return img
+		},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	var desc struct {
+		Textures []struct{ Image string }
+	}
+	if err := json.Unmarshal(outputRecorder.Got()["atlas.json"], &desc); err != nil {
+		t.Fatalf("Expected descriptor to be valid JSON but got '%s'", err)
+	}
+	xml := string(outputRecorder.Got()["atlas.xml"])
+	if len(desc.Textures) != numAtlases {
+		t.Fatalf("Expected descriptor to contain %d textures but got %d", numAtlases, len(desc.Textures))
+	}
+	previous := -1
+	for i, texture := range desc.Textures {
+		expected := fmt.Sprintf("atlas-%d.png", i+1)
+		if texture.Image != expected {
+			t.Errorf("Expected texture %d to reference '%s' but got '%s'", i, expected, texture.Image)
+		}
+		// Appended descriptors must describe the atlases in the same order
+		at := strings.Index(xml, fmt.Sprintf(`imagePath="%s"`, expected))
+		if at <= previous {
+			t.Errorf("Expected '%s' to be described after the previous atlas in\n\n%s", expected, xml)
+		}
+		previous = at
+	}
+}
+
+func TestMultiFormatDescribesEveryAtlasInOneDescriptor(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.PhaserMulti,
+		Input:            packer.NewFilenameStream("./fixtures", files...),
+		Output:           outputRecorder,
+		Width:            400,
+		Height:           400,
+		EmitCombinedDesc: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	var desc struct {
+		Textures []struct {
+			Image  string
+			Frames []struct {
+				Filename string
+			}
+		}
+	}
+	gotStr := string(outputRecorder.Got()["atlas.json"])
+	if err := json.Unmarshal([]byte(gotStr), &desc); err != nil {
+		t.Fatalf("Expected descriptor to be valid JSON but got '%s'\n\n%s", err, gotStr)
+	}
+
+	if len(desc.Textures) != 2 {
+		t.Fatalf("Expected descriptor to contain 2 textures but got %d", len(desc.Textures))
+	}
+	frames := 0
+	for i, texture := range desc.Textures {
+		if expected := fmt.Sprintf("atlas-%d.png", i+1); texture.Image != expected {
+			t.Errorf("Expected texture %d to reference '%s' but got '%s'", i, expected, texture.Image)
+		}
+		frames += len(texture.Frames)
+	}
+	if frames != len(files) {
+		t.Errorf("Expected descriptor to contain %d frames but got %d", len(files), frames)
+	}
+}
+
+func TestTSDefFormatDeclaresEverySpriteName(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.TSDef,
+		Input:            packer.NewFilenameStream("./fixtures", "button.png", "button_hover.png", "character_hero.png"),
+		Output:           outputRecorder,
+		Width:            210,
+		Height:           360,
+		EmitCombinedDesc: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	desc, ok := outputRecorder.Got()["atlas.d.ts"]
+	if !ok {
+		t.Fatalf("Expected the declarations to be written to 'atlas.d.ts'")
+	}
+	gotStr := string(desc)
+	if n := strings.Count(gotStr, "\t| "); n != 3 {
+		t.Errorf("Expected a union of 3 names over several atlases but got\n\n%s", gotStr)
+	}
+	for _, name := range []string{`"button"`, `"button_hover"`, `"character_hero"`} {
+		if !strings.Contains(gotStr, name) {
+			t.Errorf("Expected the declarations to contain %s but got\n\n%s", name, gotStr)
+		}
+	}
+}
+
+func TestBinaryFormatDescribesEveryAtlas(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.Binary,
+		Input:            packer.NewFilenameStream("./fixtures", files...),
+		Output:           outputRecorder,
+		Width:            400,
+		Height:           400,
+		EmitCombinedDesc: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	data := outputRecorder.Got()["atlas.bin"]
+	if len(data) < 16 || string(data[:4]) != "LPAB" {
+		t.Fatalf("Expected descriptor to start with the LPAB magic but got %q", data)
+	}
+	u32 := func(offset int) int { return int(binary.LittleEndian.Uint32(data[offset:])) }
+	str := func(tableOffset, offset int) string {
+		s := data[tableOffset+offset:]
+		return string(s[:bytes.IndexByte(s, 0)])
+	}
+
+	atlases, table := u32(8), u32(12)
+	if atlases != 2 {
+		t.Fatalf("Expected descriptor to contain 2 atlases but got %d", atlases)
+	}
+	record := 16 + atlases*16
+	names := map[string]bool{}
+	for i := 0; i < atlases; i++ {
+		atlas := 16 + i*16
+		if expected, got := fmt.Sprintf("atlas-%d.png", i+1), str(table, u32(atlas)); got != expected {
+			t.Errorf("Expected atlas %d to reference '%s' but got '%s'", i, expected, got)
+		}
+		if w, h := u32(atlas+4), u32(atlas+8); w != 400 || h != 400 {
+			t.Errorf("Expected atlas %d to be 400x400 but got %dx%d", i, w, h)
+		}
+		for j := 0; j < u32(atlas+12); j++ {
+			names[str(table, u32(record))] = true
+			if w, h := u32(record+12), u32(record+16); w == 0 || h == 0 {
+				t.Errorf("Expected sprite record %d of atlas %d to have a size but got %dx%d", j, i, w, h)
+			}
+			record += 24
+		}
+	}
+	if record != table {
+		t.Errorf("Expected the string table to follow the sprite records at %d but it is at %d", record, table)
+	}
+	for _, file := range files {
+		if name := file[:len(file)-len(".png")]; !names[name] {
+			t.Errorf("Expected a sprite record named '%s' but got %v", name, names)
+		}
+	}
+}
+
+func TestImagePathPrefixIsPrependedToDescribedImages(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:            "atlas",
+		Format:          target.Starling,
+		Input:           packer.NewFilenameStream("./fixtures", "button.png"),
+		Output:          outputRecorder,
+		Width:           256,
+		Height:          256,
+		SplitAlpha:      true,
+		ImagePathPrefix: "textures/",
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	expected := `imagePath="textures/atlas-1.png" alphaPath="textures/atlas-1_a.png"`
+	if gotStr := string(got["atlas-1.xml"]); !strings.Contains(gotStr, expected) {
+		t.Errorf("Expected descriptor to contain '%s' but got\n\n%s", expected, gotStr)
+	}
+	for _, filename := range []string{"atlas-1.png", "atlas-1_a.png"} {
+		if _, ok := got[filename]; !ok {
+			t.Errorf("Expected image to be outputted as '%s' without the prefix", filename)
+		}
+	}
+}
+
 func TestRunWithTooManyFilesAndMaxAtlasesResultsInError(t *testing.T) {
 	files := []string{
 		"button_active.png",
@@ -221,7 +661,7 @@ func TestPaddingIsAppliedCorrectly(t *testing.T) {
 	expectedString := fmt.Sprintf("quads['button'] = love.graphics.newQuad(%d,%d,%d,%d,%d,%d)",
 		padding, padding, buttonWidth, buttonHeight, packer.DefaultAtlasWidth, packer.DefaultAtlasHeight)
 	seperator := createUnderlineString(expectedString)
-	gotStr := got["atlas-1.lua"].String()
+	gotStr := string(got["atlas-1.lua"])
 	if !strings.Contains(gotStr, expectedString) {
 		t.Errorf("Expected descriptor to contain the following sub-string\n\n%s\n%s\n\n%s", expectedString, seperator, gotStr)
 	}
@@ -229,31 +669,1207 @@ func TestPaddingIsAppliedCorrectly(t *testing.T) {
 	// TODO do we want to ensure the image was placed correctly too?
 }
 
-func TestAssetsDoNotFitIfPaddingCannotBeApplied(t *testing.T) {
+func TestPaddingModeSpacesSprites(t *testing.T) {
+	// Two 10x10 sprites side by side in an atlas exactly large
+	// enough for them, with 2 pixels of padding in each mode
+	tests := []struct {
+		mode          packer.PaddingMode
+		fit           bool
+		width, height int
+		quads         []string
+	}{
+		{packer.PaddingShared, false, 24, 12, []string{
+			"quads['a'] = love.graphics.newQuad(2,2,10,10,24,12)",
+			"quads['b'] = love.graphics.newQuad(14,2,10,10,24,12)",
+		}},
+		{packer.PaddingSymmetric, false, 28, 14, []string{
+			"quads['a'] = love.graphics.newQuad(2,2,10,10,28,14)",
+			"quads['b'] = love.graphics.newQuad(16,2,10,10,28,14)",
+		}},
+		{packer.PaddingNoEdge, false, 22, 10, []string{
+			"quads['a'] = love.graphics.newQuad(0,0,10,10,22,10)",
+			"quads['b'] = love.graphics.newQuad(12,0,10,10,22,10)",
+		}},
+		{packer.PaddingNoEdge, true, 64, 64, []string{
+			"quads['a'] = love.graphics.newQuad(0,0,10,10,22,10)",
+			"quads['b'] = love.graphics.newQuad(12,0,10,10,22,10)",
+		}},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: target.Love,
+			Input: NewImageStream(t, map[string]image.Image{
+				"a.png": newSolidImage(10, 10, color.White),
+				"b.png": newSolidImage(10, 10, color.White),
+			}),
+			Output:      outputRecorder,
+			Width:       test.width,
+			Height:      test.height,
+			Padding:     2,
+			PaddingMode: test.mode,
+			Fit:         test.fit,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Errorf("Expected padding mode %d to pack without error but got '%s'", test.mode, err)
+			continue
+		}
+
+		got := outputRecorder.Got()
+		if len(got) != 2 {
+			t.Errorf("Expected padding mode %d to fit both sprites on one atlas but got %d files", test.mode, len(got))
+			continue
+		}
+		gotStr := string(got["atlas-1.lua"])
+		for _, quad := range test.quads {
+			if !strings.Contains(gotStr, quad) {
+				t.Errorf("Expected descriptor with padding mode %d to contain '%s' but got\n\n%s", test.mode, quad, gotStr)
+			}
+		}
+	}
+}
+
+func TestPaddingXAndPaddingYSpaceSpritesPerAxis(t *testing.T) {
+	// Four 10x10 sprites in a 2x2 grid, exactly large enough with 4
+	// pixels of padding horizontally and 1 vertically
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input: NewImageStream(t, map[string]image.Image{
+			"a.png": newSolidImage(10, 10, color.White),
+			"b.png": newSolidImage(10, 10, color.White),
+			"c.png": newSolidImage(10, 10, color.White),
+			"d.png": newSolidImage(10, 10, color.White),
+		}),
+		Output:   outputRecorder,
+		Width:    28,
+		Height:   22,
+		Padding:  2,
+		PaddingX: 4,
+		PaddingY: 1,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+	for _, quad := range []string{
+		"love.graphics.newQuad(4,1,10,10,28,22)",
+		"love.graphics.newQuad(18,1,10,10,28,22)",
+		"love.graphics.newQuad(4,12,10,10,28,22)",
+		"love.graphics.newQuad(18,12,10,10,28,22)",
+	} {
+		if !strings.Contains(gotStr, quad) {
+			t.Errorf("Expected descriptor to contain '%s' but got\n\n%s", quad, gotStr)
+		}
+	}
+}
+
+func TestSpriteThatExactlyFillsTheAtlasFits(t *testing.T) {
+	tests := []struct {
+		padding int
+		fit     bool
+	}{
+		{0, false},
+		{2, false},
+		{0, true},
+		{2, true},
+	}
+
+	for _, test := range tests {
+		// The padded size of the sprite is exactly the atlas size
+		width, height := 64, 32
+		images := map[string]image.Image{
+			"exact.png": newSolidImage(width-test.padding, height-test.padding, color.White),
+		}
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:    "atlas",
+			Format:  target.Love,
+			Input:   NewImageStream(t, images),
+			Output:  outputRecorder,
+			Width:   width,
+			Height:  height,
+			Padding: test.padding,
+			Fit:     test.fit,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Errorf("Expected sprite with padding %d and fit %t to fit but got '%s'", test.padding, test.fit, err)
+			continue
+		}
+
+		expected := fmt.Sprintf("quads['exact'] = love.graphics.newQuad(%d,%d,%d,%d,%d,%d)",
+			test.padding, test.padding, width-test.padding, height-test.padding, width, height)
+		if got := string(outputRecorder.Got()["atlas-1.lua"]); !strings.Contains(got, expected) {
+			t.Errorf("Expected descriptor to contain '%s' but got\n\n%s", expected, got)
+		}
+	}
+}
+
+func TestFitShrinksAtlasToContents(t *testing.T) {
 	button := "button.png"
 	buttonWidth, buttonHeight := 124, 50
 
+	tests := []struct {
+		maxAspectRatio          float64
+		minWidth, minHeight     int
+		atlasWidth, atlasHeight int
+	}{
+		{0, 0, 0, buttonWidth, buttonHeight},
+		{2, 0, 0, buttonWidth, buttonWidth / 2},
+		{0, 256, 256, 256, 256},
+		{0, 64, 64, buttonWidth, 64},
+		{4, 0, 100, buttonWidth, 100},
+		{0, 0, packer.DefaultAtlasHeight * 2, buttonWidth, packer.DefaultAtlasHeight},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Input:          packer.NewFilenameStream("./fixtures", button),
+			Output:         outputRecorder,
+			Name:           "atlas",
+			Format:         target.Love,
+			Fit:            true,
+			MaxAspectRatio: test.maxAspectRatio,
+			MinWidth:       test.minWidth,
+			MinHeight:      test.minHeight,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Errorf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		expectedString := fmt.Sprintf("quads['button'] = love.graphics.newQuad(%d,%d,%d,%d,%d,%d)",
+			0, 0, buttonWidth, buttonHeight, test.atlasWidth, test.atlasHeight)
+		gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+		if !strings.Contains(gotStr, expectedString) {
+			t.Errorf("Expected descriptor to contain the following sub-string\n\n%s\n%s\n\n%s",
+				expectedString, createUnderlineString(expectedString), gotStr)
+		}
+	}
+}
+
+func TestAtlasSizesGivesEachAtlasItsOwnSize(t *testing.T) {
+	images := map[string]image.Image{}
+	for i := 0; i < 6; i++ {
+		images[fmt.Sprintf("sprite_%d.png", i)] = newSolidImage(64, 64, color.White)
+	}
+
 	outputRecorder := NewOutputRecorder()
 	params := &packer.Params{
-		Format:  target.Love,
-		Input:   packer.NewFilenameStream("./fixtures", button),
-		Output:  outputRecorder,
-		Padding: 2,
-		Width:   buttonWidth,
-		Height:  buttonHeight,
+		Name:       "atlas",
+		Format:     target.Love,
+		Input:      NewImageStream(t, images),
+		Output:     outputRecorder,
+		AtlasSizes: []image.Point{{128, 128}, {64, 64}},
 	}
 
-	err := packer.Run(context.Background(), params)
-	if err == nil {
-		t.Errorf("Expected run to fail but unstead got nil error")
+	result, err := packer.RunWithResult(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	if result.Width != 0 || result.Height != 0 {
+		t.Errorf("Expected no single size to be reported for atlases of several sizes but got %dx%d", result.Width, result.Height)
+	}
+
+	// The first atlas fits four sprites and the last size repeats
+	expected := map[string]int{"atlas-1.lua": 128, "atlas-2.lua": 64, "atlas-3.lua": 64}
+	got := outputRecorder.Got()
+	if len(got) != len(expected)*2 {
+		t.Errorf("Expected %d atlases but got %d files", len(expected), len(got))
+	}
+	for filename, size := range expected {
+		desc, ok := got[filename]
+		if !ok {
+			t.Errorf("Expected file '%s' to be outputted", filename)
+			continue
+		}
+		if suffix := fmt.Sprintf(",%d,%d)", size, size); !strings.Contains(string(desc), suffix) {
+			t.Errorf("Expected '%s' to describe a %dx%d atlas but got\n\n%s", filename, size, size, desc)
+		}
 	}
 }
 
-func createUnderlineString(input string) string {
-	inputLength := len(input)
-	chars := make([]rune, inputLength)
-	for i := 0; i < inputLength; i++ {
-		chars[i] = '~'
+func TestSpriteIndexFollowsPackingOrder(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	images := map[string]image.Image{
+		"c.png":     newSolidImage(8, 8, white),
+		"a.png":     newSolidImage(8, 8, white),
+		"large.png": newSolidImage(16, 16, white),
+		"b.png":     newSolidImage(8, 8, white),
+	}
+
+	// Sprites are indexed largest first with ties broken by path
+	expected := map[string]string{"large": "0", "a": "1", "b": "2", "c": "3"}
+	for i := 0; i < 3; i++ {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: target.Spine,
+			Input:  NewImageStream(t, images),
+			Output: outputRecorder,
+			Width:  64,
+			Height: 64,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		lines := strings.Split(string(outputRecorder.Got()["atlas-1.atlas"]), "\n")
+		found := 0
+		for j := 0; j+2 < len(lines); j++ {
+			index, ok := expected[lines[j]]
+			if !ok {
+				continue
+			}
+			found++
+			if got := lines[j+2]; got != "index:"+index {
+				t.Errorf("Expected sprite '%s' to have 'index:%s' but got '%s'", lines[j], index, got)
+			}
+		}
+		if found != len(expected) {
+			t.Errorf("Expected %d sprites in the descriptor but found %d", len(expected), found)
+		}
+	}
+}
+
+func TestUnityFormatUsesBottomLeftOrigin(t *testing.T) {
+	button := "button.png"
+	buttonWidth, buttonHeight := 124, 50
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Input:  packer.NewFilenameStream("./fixtures", button),
+		Output: outputRecorder,
+		Name:   "atlas",
+		Format: target.Unity,
+	}
+
+	err := packer.Run(context.Background(), params)
+	got := outputRecorder.Got()
+
+	if err != nil {
+		t.Errorf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expectedString := fmt.Sprintf("button;%d;%d;%d;%d;",
+		0, packer.DefaultAtlasHeight-buttonHeight, buttonWidth, buttonHeight)
+	seperator := createUnderlineString(expectedString)
+	gotStr := string(got["atlas-1.tpsheet"])
+	if !strings.Contains(gotStr, expectedString) {
+		t.Errorf("Expected descriptor to contain the following sub-string\n\n%s\n%s\n\n%s", expectedString, seperator, gotStr)
+	}
+}
+
+func TestAssetsDoNotFitIfPaddingCannotBeApplied(t *testing.T) {
+	button := "button.png"
+	buttonWidth, buttonHeight := 124, 50
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:  target.Love,
+		Input:   packer.NewFilenameStream("./fixtures", button),
+		Output:  outputRecorder,
+		Padding: 2,
+		Width:   buttonWidth,
+		Height:  buttonHeight,
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil {
+		t.Errorf("Expected run to fail but unstead got nil error")
+	} else if !errors.Is(err, packing.ErrInputTooLarge) || !strings.Contains(err.Error(), "'button.png' needs 126x52") {
+		t.Errorf("Expected an error naming the asset that can never fit but got '%s'", err)
+	}
+}
+
+func TestRunWithInvalidFormatTemplateResultsInError(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format: target.Format{
+			Name:     "broken",
+			Template: template.Must(template.New("broken").Parse("{{.DoesNotExist}}")),
+			Ext:      "txt",
+		},
+		Input:  packer.NewFilenameStream("./fixtures", "button.png"),
+		Output: outputRecorder,
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil {
+		t.Errorf("Expected run to fail but error was nil")
+	}
+
+	if got := outputRecorder.Got(); len(got) > 0 {
+		t.Errorf("Expected no files to be outputted but got %d", len(got))
+	}
+}
+
+func createUnderlineString(input string) string {
+	inputLength := len(input)
+	chars := make([]rune, inputLength)
+	for i := 0; i < inputLength; i++ {
+		chars[i] = '~'
+	}
+	return string(chars)
+}
+
+func TestCollectAllErrorsReportsEveryFailedAsset(t *testing.T) {
+	files := []string{"button.png", "missing_one.png", "missing_two.png"}
+
+	for _, collect := range []bool{false, true} {
+		params := &packer.Params{
+			Format:           target.Love,
+			Input:            packer.NewFilenameStream("./fixtures", files...),
+			Output:           NewOutputRecorder(),
+			CollectAllErrors: collect,
+		}
+
+		err := packer.Run(context.Background(), params)
+		if err == nil {
+			t.Fatalf("Expected run to fail but error was nil")
+		}
+
+		expectedMissing := 1
+		if collect {
+			expectedMissing = 2
+		}
+		gotMissing := strings.Count(err.Error(), "Failed to read asset")
+		if gotMissing != expectedMissing {
+			t.Errorf("Expected error to report %d missing assets with CollectAllErrors=%t but got '%s'",
+				expectedMissing, collect, err)
+		}
+	}
+}
+
+func TestScaleFuncOverridesScalePerSprite(t *testing.T) {
+	files := []string{"button.png", "button_hover.png"}
+	buttonWidth, buttonHeight := 124, 50
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:       "atlas",
+		Format:     target.Love,
+		Input:      packer.NewFilenameStream("./fixtures", files...),
+		Output:     outputRecorder,
+		Scale:      2,
+		MaxUpscale: 2,
+		ScaleFunc: func(name string) float64 {
+			if name == "button_hover.png" {
+				return 0.5
+			}
+			return 0
+		},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+	expectedSizes := map[string][2]int{
+		"button":       {buttonWidth * 2, buttonHeight * 2},
+		"button_hover": {buttonWidth / 2, buttonHeight / 2},
+	}
+	for _, line := range strings.Split(gotStr, "\n") {
+		for name, size := range expectedSizes {
+			if !strings.HasPrefix(line, fmt.Sprintf("quads['%s'] =", name)) {
+				continue
+			}
+			expectedString := fmt.Sprintf(",%d,%d,%d,%d)", size[0], size[1], packer.DefaultAtlasWidth, packer.DefaultAtlasHeight)
+			if !strings.HasSuffix(line, expectedString) {
+				t.Errorf("Expected quad for '%s' to end with '%s' but got '%s'", name, expectedString, line)
+			}
+			delete(expectedSizes, name)
+		}
+	}
+	for name := range expectedSizes {
+		t.Errorf("Expected descriptor to contain a quad for '%s' but got\n\n%s", name, gotStr)
+	}
+}
+
+func TestSpriteAlignPlacesSpritesAtAlignedPositions(t *testing.T) {
+	align := 4
+	sizes := map[string][2]int{
+		"a.png": {5, 5},
+		"b.png": {7, 3},
+		"c.png": {3, 9},
+		"d.png": {6, 6},
+	}
+	images := map[string]image.Image{}
+	for name, size := range sizes {
+		images[name] = newSolidImage(size[0], size[1], color.White)
+	}
+
+	format := target.Format{
+		Name:     "rects",
+		Template: template.Must(template.New("rects").Parse("{{range .Sprites}}{{.Name}} {{.Left}} {{.Top}} {{.Width}} {{.Height}}\n{{end}}")),
+		Ext:      "txt",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:        "atlas",
+		Format:      format,
+		Input:       NewImageStream(t, images),
+		Output:      outputRecorder,
+		Width:       32,
+		Height:      32,
+		Padding:     1,
+		SpriteAlign: align,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	gotStr := string(outputRecorder.Got()["atlas-1.txt"])
+	lines := strings.Split(strings.TrimSpace(gotStr), "\n")
+	if len(lines) != len(sizes) {
+		t.Fatalf("Expected %d sprites in the descriptor but got\n\n%s", len(sizes), gotStr)
+	}
+	for _, line := range lines {
+		var name string
+		var x, y, w, h int
+		if _, err := fmt.Sscanf(line, "%s %d %d %d %d", &name, &x, &y, &w, &h); err != nil {
+			t.Fatalf("Failed to parse descriptor line '%s': %s", line, err)
+		}
+		if x%align != 0 || y%align != 0 {
+			t.Errorf("Expected '%s' to be placed at a multiple of %d but got %d,%d", name, align, x, y)
+		}
+		if size := sizes[name+".png"]; size != [2]int{w, h} {
+			t.Errorf("Expected '%s' to report its real size %dx%d but got %dx%d", name, size[0], size[1], w, h)
+		}
+	}
+}
+
+func TestPrettyPrintAndMinifyFormatJSONDescriptors(t *testing.T) {
+	outputs := map[string]string{}
+	for _, style := range []string{"rendered", "pretty", "minify"} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:        "atlas",
+			Format:      target.PhaserMulti,
+			Input:       packer.NewFilenameStream("./fixtures", "button.png"),
+			Output:      outputRecorder,
+			PrettyPrint: style == "pretty",
+			Minify:      style == "minify",
+		}
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		gotStr := string(outputRecorder.Got()["atlas-1.json"])
+		if !json.Valid([]byte(gotStr)) {
+			t.Fatalf("Expected descriptor to be valid JSON but got\n\n%s", gotStr)
+		}
+		outputs[style] = gotStr
+	}
+
+	// By default the descriptor is written as the template renders it
+	if !strings.Contains(outputs["rendered"], "\n\t\"textures\": [") {
+		t.Errorf("Expected the descriptor to be written as rendered but got\n\n%s", outputs["rendered"])
+	}
+	if !strings.Contains(outputs["pretty"], "\n  \"textures\": [") {
+		t.Errorf("Expected pretty printed descriptor to be indented but got\n\n%s", outputs["pretty"])
+	}
+	if lines := strings.Count(strings.TrimSpace(outputs["minify"]), "\n"); lines != 0 {
+		t.Errorf("Expected minified descriptor to be a single line but got\n\n%s", outputs["minify"])
+	}
+
+	// Other formats are unaffected
+	outputs = map[string]string{}
+	for _, style := range []string{"rendered", "pretty", "minify"} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:        "atlas",
+			Format:      target.Love,
+			Input:       packer.NewFilenameStream("./fixtures", "button.png"),
+			Output:      outputRecorder,
+			PrettyPrint: style == "pretty",
+			Minify:      style == "minify",
+		}
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+		outputs[style] = string(outputRecorder.Got()["atlas-1.lua"])
+	}
+	if outputs["rendered"] != outputs["pretty"] || outputs["rendered"] != outputs["minify"] {
+		t.Errorf("Expected PrettyPrint and Minify to have no effect on non-JSON formats")
+	}
+
+	params := &packer.Params{
+		Format:      target.PhaserMulti,
+		Input:       packer.NewFilenameStream("./fixtures", "button.png"),
+		Output:      NewOutputRecorder(),
+		PrettyPrint: true,
+		Minify:      true,
+	}
+	if err := packer.Run(context.Background(), params); err == nil {
+		t.Errorf("Expected PrettyPrint with Minify to result in an error")
+	}
+}
+
+func TestDescExtensionOverridesFormatExtension(t *testing.T) {
+	for _, ext := range []string{"txt", ".txt"} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:             "atlas",
+			Format:           target.Love,
+			Input:            packer.NewFilenameStream("./fixtures", "button.png"),
+			Output:           outputRecorder,
+			DescExtension:    ext,
+			EmitPerAtlasDesc: true,
+			EmitCombinedDesc: true,
+		}
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		got := outputRecorder.Got()
+		for _, filename := range []string{"atlas-1.txt", "atlas.txt", "atlas-1.png"} {
+			if _, ok := got[filename]; !ok {
+				t.Errorf("Expected '%s' to be written with extension '%s'", filename, ext)
+			}
+		}
+		if _, ok := got["atlas-1.lua"]; ok {
+			t.Errorf("Expected the format extension not to be used with extension '%s'", ext)
+		}
+		if !strings.Contains(string(got["atlas-1.txt"]), "love.graphics.newQuad") {
+			t.Errorf("Expected the descriptor to contain the love template output")
+		}
+	}
+}
+
+func TestRunWithAtlasTooSmallForPaddingResultsInError(t *testing.T) {
+	tests := []struct {
+		width, height, padding int
+	}{
+		{4, 64, 8},
+		{64, 4, 8},
+		{8, 8, 8},
+		{-1, 64, 0},
+		{64, 64, -1},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Format:  target.Love,
+			Input:   packer.NewFilenameStream("./fixtures", "button.png"),
+			Output:  outputRecorder,
+			Width:   test.width,
+			Height:  test.height,
+			Padding: test.padding,
+		}
+
+		err := packer.Run(context.Background(), params)
+		if err == nil {
+			t.Errorf("Expected a %dx%d atlas with %d padding to result in an error",
+				test.width, test.height, test.padding)
+		} else if err == packing.ErrOutOfRoom || err == packing.ErrInputTooLarge {
+			t.Errorf("Expected a configuration error but got '%s'", err)
+		}
+		if got := outputRecorder.Got(); len(got) != 0 {
+			t.Errorf("Expected nothing to be written but got %d files", len(got))
+		}
+	}
+}
+
+func TestOriginModeTransformsTemplateCoordinates(t *testing.T) {
+	format := target.Format{
+		Name:     "coords",
+		Template: template.Must(template.New("coords").Parse("{{range .Sprites}}{{.Left}} {{.Top}} {{.U0}} {{.V0}} {{.U1}} {{.V1}}{{end}}")),
+		Ext:      "txt",
+	}
+
+	tests := map[packer.OriginMode]string{
+		packer.OriginTopLeft:    "0 0 0 0 0.25 0.125",
+		packer.OriginBottomLeft: "0 56 0 0.875 0.25 1",
+	}
+
+	for mode, expected := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:       "atlas",
+			Format:     format,
+			Input:      NewImageStream(t, map[string]image.Image{"sprite.png": newSolidImage(16, 8, color.White)}),
+			Output:     outputRecorder,
+			Width:      64,
+			Height:     64,
+			OriginMode: mode,
+		}
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		if got := string(outputRecorder.Got()["atlas-1.txt"]); got != expected {
+			t.Errorf("Expected origin mode %d to give coordinates '%s' but got '%s'", mode, expected, got)
+		}
+	}
+}
+
+// heapSampler discards everything written to it and records the
+// peak heap size seen whenever it is written to
+type heapSampler struct {
+	mu    sync.Mutex
+	peak  uint64
+	stats runtime.MemStats
+}
+
+func (h *heapSampler) sample() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	runtime.ReadMemStats(&h.stats)
+	if h.stats.HeapAlloc > h.peak {
+		h.peak = h.stats.HeapAlloc
+	}
+}
+
+func (h *heapSampler) Write(p []byte) (int, error) {
+	h.sample()
+	return len(p), nil
+}
+
+func (h *heapSampler) Close() error {
+	h.sample()
+	return nil
+}
+
+func TestRunEncodesAtlasesWithBoundedMemory(t *testing.T) {
+	const atlases = 64
+	const size = 1024
+
+	// Every sprite fills an atlas, so drawing them all at once holds
+	// 256MB of atlas pixels alongside the sprite pixels. Encoding only
+	// a few at a time stays well below that, the limit leaves room for
+	// the garbage that the collector has not yet reclaimed.
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, newSolidImage(size, size, color.NRGBA{255, 0, 0, 255})); err != nil {
+		t.Fatalf("Failed to encode test image: %s", err)
+	}
+	files := make(map[string][]byte, atlases)
+	for i := 0; i < atlases; i++ {
+		files[fmt.Sprintf("sprite_%d.png", i)] = buf.Bytes()
+	}
+
+	sampler := &heapSampler{}
+	runtime.GC()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  NewBytesStream(files),
+		Output: packer.OutputterFunc(func(string, bool) (io.WriteCloser, error) {
+			return sampler, nil
+		}),
+		Width:  size,
+		Height: size,
+		OnProgress: func(packer.Progress) {
+			sampler.sample()
+		},
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	if limit := uint64(96 << 20); sampler.peak > limit {
+		t.Errorf("Expected peak heap to stay below %d bytes but got %d", limit, sampler.peak)
+	}
+}
+
+func TestPostProcessReplacesAtlasImage(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	images := map[string]image.Image{
+		"a.png": newSolidImage(16, 16, white),
+		"b.png": newSolidImage(16, 16, white),
+	}
+
+	// The atlases are output concurrently
+	var mu sync.Mutex
+	var indices []int
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  NewImageStream(t, images),
+		Output: outputRecorder,
+		Width:  16,
+		Height: 16,
+		PostProcess: func(img *image.NRGBA, atlasIndex int) image.Image {
+			mu.Lock()
+			indices = append(indices, atlasIndex)
+			mu.Unlock()
+			// Replace the atlas with a tinted copy of a different type
+			out := image.NewRGBA(img.Bounds())
+			draw.Draw(out, out.Bounds(), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.ZP, draw.Src)
+			return out
+		},
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	sort.Ints(indices)
+	if !reflect.DeepEqual(indices, []int{1, 2}) {
+		t.Errorf("Expected PostProcess to be called for atlases 1 and 2 but got %v", indices)
+	}
+	for _, filename := range []string{"atlas-1.png", "atlas-2.png"} {
+		atlas, err := png.Decode(bytes.NewReader(outputRecorder.Got()[filename]))
+		if err != nil {
+			t.Fatalf("Expected '%s' to be a valid png but got '%s'", filename, err)
+		}
+		if got := color.NRGBAModel.Convert(atlas.At(8, 8)); got != (color.NRGBA{0, 0, 255, 255}) {
+			t.Errorf("Expected '%s' to contain the post processed image but got %v", filename, got)
+		}
+	}
+}
+
+func TestPostProcessMustKeepAtlasSize(t *testing.T) {
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  NewImageStream(t, map[string]image.Image{"a.png": newSolidImage(16, 16, color.White)}),
+		Output: NewOutputRecorder(),
+		Width:  16,
+		Height: 16,
+		PostProcess: func(img *image.NRGBA, atlasIndex int) image.Image {
+			return image.NewNRGBA(image.Rect(0, 0, 8, 8))
+		},
+	}
+
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "changed the size") {
+		t.Errorf("Expected a size change error but got '%v'", err)
+	}
+}
+
+func TestDepFileListsEveryInputOfTheOutputs(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:    "atlas",
+		Format:  target.Love,
+		Input:   packer.NewFilenameStream("./fixtures", "button_hover.png", "button.png"),
+		Output:  outputRecorder,
+		DepFile: "atlas.d",
+	}
+
+	result, err := packer.RunWithResult(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expected := "atlas-1.lua atlas-1.png: \\\n  fixtures/button.png \\\n  fixtures/button_hover.png\n"
+	got := outputRecorder.Got()
+	if depFile, ok := got["atlas.d"]; !ok {
+		t.Fatalf("Expected the dependency file to be written")
+	} else if string(depFile) != expected {
+		t.Errorf("Expected the dependency file\n\n%s\nbut got\n\n%s", expected, depFile)
+	}
+	if _, ok := result.Hashes["atlas.d"]; !ok {
+		t.Errorf("Expected the result to hash the dependency file")
+	}
+}
+
+func TestSortDescriptorByNameListsSpritesAlphabetically(t *testing.T) {
+	tests := []struct {
+		sortByName bool
+		expected   []string
+	}{
+		// Packed largest first
+		{false, []string{"'c'", "'a'", "'b'"}},
+		{true, []string{"'a'", "'b'", "'c'"}},
+	}
+
+	for _, test := range tests {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: target.Love,
+			Input: NewImageStream(t, map[string]image.Image{
+				"a.png": newSolidImage(20, 20, color.White),
+				"b.png": newSolidImage(10, 10, color.White),
+				"c.png": newSolidImage(30, 30, color.White),
+			}),
+			Output:               outputRecorder,
+			Width:                64,
+			Height:               64,
+			SortDescriptorByName: test.sortByName,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+		last := -1
+		for _, name := range test.expected {
+			i := strings.Index(gotStr, name)
+			if i < last {
+				t.Errorf("Expected the descriptor with SortDescriptorByName %v to list %v in order but got\n\n%s",
+					test.sortByName, test.expected, gotStr)
+				break
+			}
+			last = i
+		}
+		// The layout is the same in both orders
+		if !strings.Contains(gotStr, "quads['c'] = love.graphics.newQuad(0,0,30,30,64,64)") {
+			t.Errorf("Expected the largest sprite to be packed first but got\n\n%s", gotStr)
+		}
+	}
+}
+
+func TestRunWritesEachFileOnceWithMatchingImageAndDescriptor(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input: NewImageStream(t, map[string]image.Image{
+			"red.png":  newSolidImage(8, 4, red),
+			"blue.png": newSolidImage(4, 4, blue),
+		}),
+		Output: outputRecorder,
+		Width:  16,
+		Height: 16,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	// Files are staged until the run succeeds, so each is requested once
+	order := outputRecorder.Order()
+	sort.Strings(order)
+	if expected := []string{"atlas-1.lua", "atlas-1.png"}; !reflect.DeepEqual(order, expected) {
+		t.Fatalf("Expected the files %v to be written once each but got %v", expected, order)
+	}
+
+	got := outputRecorder.Got()
+	atlas, err := png.Decode(bytes.NewReader(got["atlas-1.png"]))
+	if err != nil {
+		t.Fatalf("Expected the atlas to be a valid png but got '%s'", err)
+	}
+	if b := atlas.Bounds(); b.Dx() != 16 || b.Dy() != 16 {
+		t.Errorf("Expected a 16x16 atlas but got %dx%d", b.Dx(), b.Dy())
+	}
+
+	// Every quad of the descriptor must frame its sprite in the image
+	quad := regexp.MustCompile(`quads\['(\w+)'\] = love\.graphics\.newQuad\((\d+),(\d+),(\d+),(\d+),16,16\)`)
+	matches := quad.FindAllStringSubmatch(string(got["atlas-1.lua"]), -1)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 quads in the descriptor but got\n\n%s", got["atlas-1.lua"])
+	}
+	for _, match := range matches {
+		expect := map[string]color.NRGBA{"red": red, "blue": blue}[match[1]]
+		var rect [4]int
+		for i := range rect {
+			rect[i], _ = strconv.Atoi(match[i+2])
+		}
+		x, y, w, h := rect[0], rect[1], rect[2], rect[3]
+		for _, p := range []image.Point{{x, y}, {x + w - 1, y + h - 1}} {
+			if c := color.NRGBAModel.Convert(atlas.At(p.X, p.Y)); c != expect {
+				t.Errorf("Expected the quad of '%s' to frame %v pixels but got %v at %v", match[1], expect, c, p)
+			}
+		}
+	}
+}
+
+func TestRunDrawsEverySpriteAtItsDescribedRect(t *testing.T) {
+	colors := map[string]color.NRGBA{
+		"red":     {255, 0, 0, 255},
+		"green":   {0, 255, 0, 255},
+		"blue":    {0, 0, 255, 255},
+		"yellow":  {255, 255, 0, 255},
+		"cyan":    {0, 255, 255, 255},
+		"magenta": {255, 0, 255, 255},
+		"white":   {255, 255, 255, 255},
+	}
+	sizes := map[string]image.Point{
+		"red": {20, 12}, "green": {12, 20}, "blue": {16, 16}, "yellow": {7, 3},
+		"cyan": {3, 9}, "magenta": {30, 5}, "white": {1, 1},
+	}
+	images := map[string]image.Image{}
+	for name, c := range colors {
+		images[name+".png"] = newSolidImage(sizes[name].X, sizes[name].Y, c)
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.PhaserMulti,
+		Input:            NewImageStream(t, images),
+		Output:           outputRecorder,
+		Width:            32,
+		Height:           32,
+		Padding:          1,
+		CombineDescFiles: true,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	var desc struct {
+		Textures []struct {
+			Image  string `json:"image"`
+			Frames []struct {
+				Filename string `json:"filename"`
+				Frame    struct{ X, Y, W, H int }
+			} `json:"frames"`
+		} `json:"textures"`
+	}
+	if err := json.Unmarshal(got["atlas.json"], &desc); err != nil {
+		t.Fatalf("Expected a JSON descriptor but got '%s'", err)
+	}
+	if len(desc.Textures) < 2 {
+		t.Fatalf("Expected the sprites to need several atlases but got %d", len(desc.Textures))
+	}
+
+	described := 0
+	for _, texture := range desc.Textures {
+		atlas, err := png.Decode(bytes.NewReader(got[texture.Image]))
+		if err != nil {
+			t.Fatalf("Expected '%s' to be a valid png but got '%s'", texture.Image, err)
+		}
+		drawn := 0
+		for _, frame := range texture.Frames {
+			described++
+			expected, rect := colors[frame.Filename], frame.Frame
+			if size := sizes[frame.Filename]; rect.W != size.X || rect.H != size.Y {
+				t.Errorf("Expected '%s' to be %dx%d but got %dx%d", frame.Filename, size.X, size.Y, rect.W, rect.H)
+			}
+			for y := rect.Y; y < rect.Y+rect.H; y++ {
+				for x := rect.X; x < rect.X+rect.W; x++ {
+					if c := color.NRGBAModel.Convert(atlas.At(x, y)).(color.NRGBA); c != expected {
+						t.Fatalf("Expected pixel %d,%d of '%s' in %s to be %v but got %v", x, y, frame.Filename, texture.Image, expected, c)
+					}
+				}
+			}
+			drawn += rect.W * rect.H
+		}
+
+		// Nothing is drawn outside of the described rects
+		covered := 0
+		bounds := atlas.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if _, _, _, a := atlas.At(x, y).RGBA(); a != 0 {
+					covered++
+				}
+			}
+		}
+		if covered != drawn {
+			t.Errorf("Expected %d pixels of %s to be drawn but got %d", drawn, texture.Image, covered)
+		}
+	}
+	if described != len(colors) {
+		t.Errorf("Expected all %d sprites to be described but got %d", len(colors), described)
+	}
+}
+
+func TestVerifyNoOverlapPassesForEveryHeuristic(t *testing.T) {
+	images := map[string]image.Image{}
+	for i := 0; i < 40; i++ {
+		images[fmt.Sprintf("sprite%02d.png", i)] = newSolidImage(3+i%7*3, 2+i%5*4, color.White)
+	}
+
+	for _, heuristic := range []packing.Heuristic{packing.FirstFit, packing.BestAreaFit, packing.BestShortSideFit, packing.BottomLeft} {
+		params := &packer.Params{
+			Name:               "atlas",
+			Format:             target.Love,
+			Input:              NewImageStream(t, images),
+			Output:             NewOutputRecorder(),
+			Width:              64,
+			Height:             64,
+			PaddingX:           2,
+			PaddingY:           1,
+			PlacementHeuristic: heuristic,
+			VerifyNoOverlap:    true,
+		}
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Errorf("Expected run with heuristic %d to succeed without error but got '%s'", heuristic, err)
+		}
+	}
+}
+
+func TestNameSeparatorReplacesDirectorySeparators(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        target.Spine,
+		Input:         NewImageStream(t, map[string]image.Image{"ui/buttons/ok.png": newSolidImage(4, 4, color.White)}),
+		Output:        outputRecorder,
+		Width:         8,
+		Height:        8,
+		NameSeparator: ".",
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	gotStr := string(outputRecorder.Got()["atlas-1.atlas"])
+	if !strings.Contains(gotStr, "\nui.buttons.ok\n") || strings.Contains(gotStr, "ui/buttons") {
+		t.Errorf("Expected the sprite to be named 'ui.buttons.ok' but got\n\n%s", gotStr)
+	}
+}
+
+func TestFillDirectionFillsAtlasesInRowsOrColumns(t *testing.T) {
+	for direction, quads := range map[packing.FillDirection][]string{
+		packing.FillRows:    {"newQuad(0,0,16,10,32,32)", "newQuad(16,0,16,10,32,32)", "newQuad(0,10,16,10,32,32)"},
+		packing.FillColumns: {"newQuad(0,0,16,10,32,32)", "newQuad(0,10,16,10,32,32)", "newQuad(0,20,16,10,32,32)"},
+	} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: target.Love,
+			Input: NewImageStream(t, map[string]image.Image{
+				"a.png": newSolidImage(16, 10, color.White),
+				"b.png": newSolidImage(16, 10, color.White),
+				"c.png": newSolidImage(16, 10, color.White),
+			}),
+			Output:        outputRecorder,
+			Width:         32,
+			Height:        32,
+			FillDirection: direction,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		gotStr := string(outputRecorder.Got()["atlas-1.lua"])
+		for _, quad := range quads {
+			if !strings.Contains(gotStr, quad) {
+				t.Errorf("Expected descriptor filling %s to contain '%s' but got\n\n%s", direction, quad, gotStr)
+			}
+		}
+	}
+}
+
+func TestBuildTagIsRecordedInTheDescriptorMeta(t *testing.T) {
+	for _, buildTag := range []string{"", "build 42"} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:             "atlas",
+			Format:           target.PhaserMulti,
+			Input:            NewImageStream(t, map[string]image.Image{"icon.png": newSolidImage(4, 4, color.White)}),
+			Output:           outputRecorder,
+			Width:            8,
+			Height:           8,
+			EmitCombinedDesc: true,
+			BuildTag:         buildTag,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected run to succeed without error but got '%s'", err)
+		}
+
+		var desc struct {
+			Meta map[string]string
+		}
+		if err := json.Unmarshal(outputRecorder.Got()["atlas.json"], &desc); err != nil {
+			t.Fatalf("Expected descriptor to be valid JSON but got '%s'", err)
+		}
+		expected := map[string]string{"app": "https://github.com/psucodervn/lovepac", "version": packer.Version}
+		if buildTag != "" {
+			expected["buildTag"] = buildTag
+		}
+		if !reflect.DeepEqual(desc.Meta, expected) {
+			t.Errorf("Expected the meta %v but got %v", expected, desc.Meta)
+		}
+	}
+}
+
+func TestNDJSONStreamsALinePerFrame(t *testing.T) {
+	images := map[string]image.Image{}
+	for i := 0; i < 400; i++ {
+		images[fmt.Sprintf("sprite%03d.png", i)] = newSolidImage(2, 2, color.White)
+	}
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:             "atlas",
+		Format:           target.NDJSON,
+		Input:            NewImageStream(t, images),
+		Output:           outputRecorder,
+		Width:            64,
+		Height:           64,
+		EmitCombinedDesc: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()["atlas.ndjson"]
+	lines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+	if len(lines) != len(images) {
+		t.Fatalf("Expected a line for each of the %d frames but got %d", len(images), len(lines))
+	}
+	for _, line := range lines {
+		var frame struct {
+			Filename string
+			Image    string
+		}
+		if err := json.Unmarshal([]byte(line), &frame); err != nil || frame.Filename == "" || frame.Image == "" {
+			t.Fatalf("Expected each line to be a frame but got '%s': %v", line, err)
+		}
+	}
+}
+
+// writeRecorder discards what is written to it and records the size of every write
+type writeRecorder struct {
+	sizes []int
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.sizes = append(w.sizes, len(p))
+	return len(p), nil
+}
+
+func (w *writeRecorder) Close() error { return nil }
+
+func TestNDJSONIsWrittenAsItIsRendered(t *testing.T) {
+	images := map[string]image.Image{}
+	for i := 0; i < 400; i++ {
+		images[fmt.Sprintf("sprite%03d.png", i)] = newSolidImage(2, 2, color.White)
+	}
+	desc := &writeRecorder{}
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.NDJSON,
+		Input:  NewImageStream(t, images),
+		Output: packer.OutputterFunc(func(filename string, append bool) (io.WriteCloser, error) {
+			if filename == "atlas.ndjson" {
+				return desc, nil
+			}
+			return &writeRecorder{}, nil
+		}),
+		Width:            64,
+		Height:           64,
+		EmitCombinedDesc: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	// A descriptor rendered into memory first arrives in a single write
+	total := 0
+	for _, size := range desc.sizes {
+		total += size
+	}
+	if len(desc.sizes) < 2 || desc.sizes[0] >= total {
+		t.Errorf("Expected the %d byte descriptor to reach the writer in parts as it is rendered but got writes of %v", total, desc.sizes)
 	}
-	return string(chars)
 }
@@ -0,0 +1,37 @@
+package packer
+
+import "math"
+
+// ScaleRounding selects how the scaled size of a sprite is rounded to
+// whole pixels. The sprite is resampled to exactly the rounded size, so
+// the rounding only decides whether, eg., a 31 pixel sprite at a Scale
+// of 0.5 is packed and drawn as 15 or 16 pixels.
+type ScaleRounding int
+
+const (
+	// ScaleFloor rounds scaled sizes down. This is the default.
+	ScaleFloor ScaleRounding = iota
+	// ScaleRound rounds scaled sizes to the nearest pixel, halves up
+	ScaleRound
+	// ScaleCeil rounds scaled sizes up
+	ScaleCeil
+)
+
+// scale returns n pixels scaled by scale and rounded to whole pixels
+func (r ScaleRounding) scale(n int, scale float64) int {
+	scaled := float64(n) * scale
+	switch r {
+	case ScaleRound:
+		return int(math.Floor(scaled + 0.5))
+	case ScaleCeil:
+		return int(math.Ceil(scaled))
+	}
+	return int(scaled)
+}
+
+// exactlyScaled returns true if n pixels scaled by scale are a whole
+// number of pixels, allowing for the error of the floating point scale
+func exactlyScaled(n int, scale float64) bool {
+	scaled := float64(n) * scale
+	return math.Abs(scaled-math.Round(scaled)) < 1e-6
+}
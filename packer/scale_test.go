@@ -0,0 +1,148 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestScaleRoundingSizesTheDrawnSprite(t *testing.T) {
+	var tests = []struct {
+		name     string
+		rounding packer.ScaleRounding
+		width    int
+	}{
+		{"floor", packer.ScaleFloor, 15},
+		{"round", packer.ScaleRound, 16},
+		{"ceil", packer.ScaleCeil, 16},
+	}
+
+	red := color.NRGBA{255, 0, 0, 255}
+	format := target.Format{
+		Name:     "size",
+		Template: template.Must(template.New("size").Parse("{{range .Sprites}}{{.Width}}x{{.Height}}{{end}}")),
+		Ext:      "txt",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputRecorder := NewOutputRecorder()
+			params := &packer.Params{
+				Name:                       "atlas",
+				Format:                     format,
+				Input:                      NewImageStream(t, map[string]image.Image{"tile.png": newSolidImage(31, 2, red)}),
+				Output:                     outputRecorder,
+				Width:                      32,
+				Height:                     4,
+				Scale:                      0.5,
+				ScaleRounding:              tt.rounding,
+				AllowFractionalCoordinates: true,
+			}
+
+			if err := packer.Run(context.Background(), params); err != nil {
+				t.Fatalf("Expected run to succeed without error but got '%s'", err)
+			}
+
+			expected := fmt.Sprintf("%dx1", tt.width)
+			if got := string(outputRecorder.Got()["atlas-1.txt"]); got != expected {
+				t.Errorf("Expected the sprite to be described as %s but got %s", expected, got)
+			}
+
+			// The resampled sprite fills exactly the rect it was packed in
+			atlas, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+			if err != nil {
+				t.Fatalf("Expected output image to be a valid png but got '%s'", err)
+			}
+			if got := color.NRGBAModel.Convert(atlas.At(tt.width-1, 0)).(color.NRGBA); got.A == 0 {
+				t.Errorf("Expected the last column of the sprite to be drawn but got %v", got)
+			}
+			if got := color.NRGBAModel.Convert(atlas.At(tt.width, 0)).(color.NRGBA); got.A != 0 {
+				t.Errorf("Expected nothing to be drawn past the sprite but got %v", got)
+			}
+		})
+	}
+}
+
+func TestMaxUpscaleCapsTheScaleOfSprites(t *testing.T) {
+	var tests = []struct {
+		name       string
+		maxUpscale float64
+		size       string
+	}{
+		{"default", 0, "8x4"},
+		{"capped", 1.5, "12x6"},
+		{"unlimited", -1, "32x16"},
+	}
+
+	format := target.Format{
+		Name:     "size",
+		Template: template.Must(template.New("size").Parse("{{range .Sprites}}{{.Width}}x{{.Height}}{{end}}")),
+		Ext:      "txt",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputRecorder := NewOutputRecorder()
+			params := &packer.Params{
+				Name:       "atlas",
+				Format:     format,
+				Input:      NewImageStream(t, map[string]image.Image{"icon.png": newSolidImage(8, 4, color.White)}),
+				Output:     outputRecorder,
+				Width:      64,
+				Height:     64,
+				Scale:      4,
+				MaxUpscale: tt.maxUpscale,
+			}
+
+			if err := packer.Run(context.Background(), params); err != nil {
+				t.Fatalf("Expected run to succeed without error but got '%s'", err)
+			}
+
+			if got := string(outputRecorder.Got()["atlas-1.txt"]); got != tt.size {
+				t.Errorf("Expected the sprite to be described as %s but got %s", tt.size, got)
+			}
+		})
+	}
+}
+
+func TestFractionalSizesFailUnlessAllowed(t *testing.T) {
+	for _, test := range []struct {
+		width  int
+		scale  float64
+		allow  bool
+		failed bool
+	}{
+		{32, 0.5, false, false},
+		{30, 0.1, false, false},
+		{31, 0.5, false, true},
+		{31, 0.5, true, false},
+	} {
+		params := &packer.Params{
+			Name:                       "atlas",
+			Format:                     target.Love,
+			Input:                      NewImageStream(t, map[string]image.Image{"tile.png": newSolidImage(test.width, 10, color.White)}),
+			Output:                     NewOutputRecorder(),
+			Width:                      32,
+			Height:                     8,
+			Scale:                      test.scale,
+			AllowFractionalCoordinates: test.allow,
+		}
+
+		err := packer.Run(context.Background(), params)
+		if test.failed && (err == nil || !strings.Contains(err.Error(), "not a whole number of pixels")) {
+			t.Errorf("Expected %d pixels scaled by %g to fail as fractional but got '%v'", test.width, test.scale, err)
+		}
+		if !test.failed && err != nil {
+			t.Errorf("Expected %d pixels scaled by %g to succeed without error but got '%s'", test.width, test.scale, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package packer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// sourceMapEntry records where an input sprite was placed
+type sourceMapEntry struct {
+	Source  string `json:"source"`
+	Atlas   string `json:"atlas"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	W       int    `json:"w"`
+	H       int    `json:"h"`
+	Rotated bool   `json:"rotated"`
+	Trimmed bool   `json:"trimmed"`
+	// Hash is the hex encoded SHA-256 hash of the source asset, it is
+	// empty in source maps written before assets were hashed
+	Hash string `json:"hash"`
+}
+
+// matches returns true if the sprite recorded by the entry is placed as
+// the other entry records. The content hashes are only compared if the
+// entry has one, so earlier source maps still match unchanged sprites.
+func (e sourceMapEntry) matches(other sourceMapEntry) bool {
+	if e.Hash == "" {
+		other.Hash = ""
+	}
+	return e == other
+}
+
+// sourceMapFilename returns the name of the source map for the given atlas name
+func sourceMapFilename(name string) string {
+	return fmt.Sprintf("%s.sourcemap.json", name)
+}
+
+// outputSourceMap writes a JSON object mapping the path of every packed
+// sprite to its placement, independent of the descriptor format.
+func outputSourceMap(outputter Outputter, filename string, atlases []*atlas) error {
+	entries := map[string]sourceMapEntry{}
+	for _, a := range atlases {
+		for _, block := range a.Sprites {
+			spr := block.(*sprite)
+			hash, err := hashAsset(spr.Asset)
+			if err != nil {
+				return fmt.Errorf("Failed to read asset '%s': %s", spr.path, err)
+			}
+			entries[spr.path] = sourceMapEntry{
+				Source:  spr.Asset.Asset(),
+				Atlas:   a.ImageFilename,
+				X:       spr.x,
+				Y:       spr.y,
+				W:       spr.w,
+				H:       spr.h,
+				Rotated: spr.Rotated(),
+				Trimmed: spr.Trimmed(),
+				Hash:    hash,
+			}
+		}
+	}
+	return withFile(outputter, filename, false, func(writer io.Writer) error {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	})
+}
+
+// hashAsset returns the hex encoded SHA-256 hash of the asset's contents
+func hashAsset(asset Asset) (string, error) {
+	reader, err := asset.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,62 @@
+package packer_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestSourceMapRecordsPlacementOfEverySprite(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        target.Love,
+		Input:         packer.NewFilenameStream("./fixtures", files...),
+		Output:        outputRecorder,
+		Width:         400,
+		Height:        400,
+		EmitSourceMap: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	var sourceMap map[string]struct {
+		Source     string
+		Atlas      string
+		X, Y, W, H int
+	}
+	got := outputRecorder.Got()
+	if err := json.Unmarshal(got["atlas.sourcemap.json"], &sourceMap); err != nil {
+		t.Fatalf("Expected source map to be valid JSON but got '%s'", err)
+	}
+
+	for _, file := range files {
+		entry, ok := sourceMap[file]
+		if !ok {
+			t.Errorf("Expected source map to contain '%s'", file)
+			continue
+		}
+		if entry.Source != file {
+			t.Errorf("Expected source of '%s' to be '%s' but got '%s'", file, file, entry.Source)
+		}
+		if _, ok := got[entry.Atlas]; !ok {
+			t.Errorf("Expected '%s' to be placed in an outputted atlas but got '%s'", file, entry.Atlas)
+		}
+		if entry.W == 0 || entry.H == 0 {
+			t.Errorf("Expected '%s' to have a non-empty rect but got %dx%d", file, entry.W, entry.H)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package packer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SpriteSpec configures a single sprite of a spec file, which lists the
+// source images of the sprites along with the names and properties they
+// are packed with, so that sprite configuration lives in one place
+// rather than in the file layout. Properties that are nil fall back to
+// those of Params.
+type SpriteSpec struct {
+	// Source is the path of the image, relative to the spec file
+	Source string `json:"source"`
+	// Name is the name of the sprite, without the file extension of
+	// Source which is kept. Defaults to Source.
+	Name string `json:"name,omitempty"`
+	// Padding overrides Params.Padding for the sprite
+	Padding *int `json:"padding,omitempty"`
+	// Scale overrides Params.Scale and Params.ScaleFunc for the sprite
+	Scale *float64 `json:"scale,omitempty"`
+	// PivotX and PivotY are the point of the sprite that formats with
+	// pivots rotate and position it around, as a fraction of its size
+	// from the top left corner. Both default to 0.5, the center.
+	PivotX *float64 `json:"pivotX,omitempty"`
+	PivotY *float64 `json:"pivotY,omitempty"`
+}
+
+// SpecAsset is an Asset configured by a spec file, the assets of the
+// spec streams implement it.
+type SpecAsset interface {
+	Asset
+	Spec() SpriteSpec
+}
+
+// specAsset is an asset named and configured by a spec
+type specAsset struct {
+	inner Asset
+	name  string
+	spec  SpriteSpec
+}
+
+// Reader implements the Asset interface
+func (a *specAsset) Reader() (io.ReadCloser, error) { return a.inner.Reader() }
+
+// Asset implements the Asset interface
+func (a *specAsset) Asset() string { return a.name }
+
+// Spec implements the SpecAsset interface
+func (a *specAsset) Spec() SpriteSpec { return a.spec }
+
+// SourcePath implements the SourceAsset interface, assets
+// that are not read from files are identified by their name
+func (a *specAsset) SourcePath() string {
+	if source, ok := a.inner.(SourceAsset); ok {
+		return source.SourcePath()
+	}
+	return a.inner.Asset()
+}
+
+// newSpecAsset names the asset after the spec, keeping the extension of
+// the source so that sprites are named and decoded as for a file
+func newSpecAsset(asset Asset, spec SpriteSpec) *specAsset {
+	if spec.Name == "" {
+		return &specAsset{inner: asset, name: spec.Source, spec: spec}
+	}
+	ext := path.Ext(spec.Source)
+	if isNinePatch(spec.Source) {
+		ext = spec.Source[len(spec.Source)-len(ninePatchExt):]
+	}
+	return &specAsset{inner: asset, name: spec.Name + ext, spec: spec}
+}
+
+// ReadSpecFile reads the sprite specs of a JSON or CSV spec file, chosen
+// by its extension.
+//
+// JSON spec files contain an array of SpriteSpec objects. CSV spec files
+// start with a header row naming the columns, any of source, name,
+// padding, scale, pivotx and pivoty in any order, the source column is
+// required. Empty cells are left unset.
+func ReadSpecFile(specPath string) ([]SpriteSpec, error) {
+	f, err := os.Open(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read spec '%s': %s", specPath, err)
+	}
+	defer f.Close()
+
+	var specs []SpriteSpec
+	switch ext := strings.ToLower(filepath.Ext(specPath)); ext {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&specs); err != nil {
+			return nil, fmt.Errorf("Failed to read spec '%s': %s", specPath, err)
+		}
+	case ".csv":
+		if specs, err = readCSVSpecs(f); err != nil {
+			return nil, fmt.Errorf("Failed to read spec '%s': %s", specPath, err)
+		}
+	default:
+		return nil, fmt.Errorf("Spec '%s' must be a .json or .csv file, not '%s'", specPath, ext)
+	}
+
+	for i, spec := range specs {
+		if spec.Source == "" {
+			return nil, fmt.Errorf("Spec '%s' entry %d has no source", specPath, i+1)
+		}
+		if spec.Padding != nil && *spec.Padding < 0 {
+			return nil, fmt.Errorf("Spec '%s' padding of '%s' must not be negative, got %d", specPath, spec.Source, *spec.Padding)
+		}
+		if spec.Scale != nil && *spec.Scale <= 0 {
+			return nil, fmt.Errorf("Spec '%s' scale of '%s' must be positive, got %g", specPath, spec.Source, *spec.Scale)
+		}
+	}
+	return specs, nil
+}
+
+// readCSVSpecs reads the rows of a CSV spec file below its header
+func readCSVSpecs(r io.Reader) ([]SpriteSpec, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("Missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "source", "name", "padding", "scale", "pivotx", "pivoty":
+		default:
+			return nil, fmt.Errorf("Unknown column '%s', expected source, name, padding, scale, pivotx or pivoty", column)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns["source"]; !ok {
+		return nil, errors.New("Missing source column")
+	}
+
+	var specs []SpriteSpec
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		float := func(column string) (*float64, error) {
+			if cell(column) == "" {
+				return nil, nil
+			}
+			v, err := strconv.ParseFloat(cell(column), 64)
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: invalid %s '%s'", line, column, cell(column))
+			}
+			return &v, nil
+		}
+
+		spec := SpriteSpec{Source: cell("source"), Name: cell("name")}
+		if cell("padding") != "" {
+			padding, err := strconv.Atoi(cell("padding"))
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: invalid padding '%s'", line, cell("padding"))
+			}
+			spec.Padding = &padding
+		}
+		if spec.Scale, err = float("scale"); err != nil {
+			return nil, err
+		}
+		if spec.PivotX, err = float("pivotx"); err != nil {
+			return nil, err
+		}
+		if spec.PivotY, err = float("pivoty"); err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+}
+
+// NewSpecStream creates an asset streamer that streams the images listed
+// by the spec file at specPath, see ReadSpecFile, named and configured as
+// the spec describes. The images are read relative to the directory of
+// the spec file using the standard os package.
+func NewSpecStream(specPath string) AssetStreamer {
+	return AssetStreamerFunc(func(ctx context.Context) (<-chan Asset, <-chan error) {
+		stream := make(chan Asset)
+		errc := make(chan error, 1)
+
+		go func() {
+			defer close(stream)
+			defer close(errc)
+
+			if ctx == nil {
+				errc <- errContextNil
+				return
+			}
+
+			specs, err := ReadSpecFile(specPath)
+			if err != nil {
+				errc <- err
+				return
+			}
+			dir := filepath.Dir(specPath)
+			for _, spec := range specs {
+				asset := &fileAsset{Name: spec.Source, path: filepath.Join(dir, filepath.FromSlash(spec.Source))}
+				select {
+				case stream <- newSpecAsset(asset, spec):
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+		}()
+
+		return stream, errc
+	})
+}
+
+// NewSpecOverlayStream creates an asset streamer that augments the assets
+// of the inner streamer with the spec file at specPath, see ReadSpecFile.
+// Assets whose name is the source of a spec are named and configured as
+// the spec describes, all other assets are streamed unchanged.
+func NewSpecOverlayStream(inner AssetStreamer, specPath string) AssetStreamer {
+	return AssetStreamerFunc(func(ctx context.Context) (<-chan Asset, <-chan error) {
+		stream := make(chan Asset)
+		errc := make(chan error, 1)
+
+		go func() {
+			defer close(stream)
+			defer close(errc)
+
+			if ctx == nil {
+				errc <- errContextNil
+				return
+			}
+
+			specs, err := ReadSpecFile(specPath)
+			if err != nil {
+				errc <- err
+				return
+			}
+			bySource := map[string]SpriteSpec{}
+			for _, spec := range specs {
+				bySource[spec.Source] = spec
+			}
+
+			// Stop the inner stream if this one finishes early
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			assets, innerErrc := inner.AssetStream(ctx)
+			for asset := range assets {
+				if spec, ok := bySource[filepath.ToSlash(asset.Asset())]; ok {
+					asset = newSpecAsset(asset, spec)
+				}
+				select {
+				case stream <- asset:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+
+			err = <-innerErrc
+			if err == nil {
+				err = ctx.Err()
+			}
+			if err != nil {
+				errc <- err
+			}
+		}()
+
+		return stream, errc
+	})
+}
+
+// pivot is the point of a sprite, as a fraction of its size from the
+// top left corner, that formats with pivots place it around
+type pivot struct{ x, y float64 }
+
+// specPivot returns the pivot of a sprite configured by the spec
+func specPivot(spec SpriteSpec) pivot {
+	p := pivot{0.5, 0.5}
+	if spec.PivotX != nil {
+		p.x = *spec.PivotX
+	}
+	if spec.PivotY != nil {
+		p.y = *spec.PivotY
+	}
+	return p
+}
+
+// Template data of the pivot, the center unless configured by a spec
+func (s *sprite) PivotX() float64 { return s.pivot.x }
+func (s *sprite) PivotY() float64 { return s.pivot.y }
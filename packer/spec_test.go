@@ -0,0 +1,102 @@
+package packer_test
+
+import (
+	"context"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+var spritePropertiesFormat = target.Format{
+	Name:     "properties",
+	Template: template.Must(template.New("properties").Parse("{{range .Sprites}}{{.DisplayName}} {{.Left}} {{.Width}} {{.PivotX}} {{.PivotY}}\n{{end}}")),
+	Ext:      "txt",
+}
+
+// writeSpecFiles writes 8x8 png images and the spec file into a new directory
+// and returns the path of the spec file
+func writeSpecFiles(t *testing.T, images []string, specName, spec string) string {
+	dir := t.TempDir()
+	for _, name := range images {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %s", err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("Failed to write test image: %s", err)
+		}
+		if err := png.Encode(f, newSolidImage(8, 8, color.White)); err != nil {
+			t.Fatalf("Failed to encode test image: %s", err)
+		}
+		f.Close()
+	}
+	specPath := filepath.Join(dir, specName)
+	if err := ioutil.WriteFile(specPath, []byte(spec), 0644); err != nil {
+		t.Fatalf("Failed to write test spec: %s", err)
+	}
+	return specPath
+}
+
+func packSpriteProperties(t *testing.T, input packer.AssetStreamer) string {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: spritePropertiesFormat,
+		Input:  input,
+		Output: outputRecorder,
+		Width:  32,
+		Height: 32,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	return string(outputRecorder.Got()["atlas-1.txt"])
+}
+
+func TestSpecStreamNamesAndConfiguresSprites(t *testing.T) {
+	specPath := writeSpecFiles(t, []string{"art/hero_final.png"}, "sprites.json",
+		`[{"source": "art/hero_final.png", "name": "characters/hero", "padding": 3, "scale": 0.5, "pivotX": 0.25, "pivotY": 1}]`)
+
+	got := packSpriteProperties(t, packer.NewSpecStream(specPath))
+	expected := "characters/hero 3 4 0.25 1\n"
+	if got != expected {
+		t.Errorf("Expected the sprites '%s' but got '%s'", expected, got)
+	}
+}
+
+func TestSpecOverlayStreamFallsBackToParams(t *testing.T) {
+	specPath := writeSpecFiles(t, []string{"hero.png", "plain.png"}, "sprites.csv",
+		"source, name, pivotY\nhero.png, characters/hero, 0\n")
+
+	inner := packer.NewFilenameStream(filepath.Dir(specPath), "hero.png", "plain.png")
+	got := packSpriteProperties(t, packer.NewSpecOverlayStream(inner, specPath))
+	for _, expected := range []string{"characters/hero 0 8 0.5 0\n", "plain 8 8 0.5 0.5\n"} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("Expected the sprites to contain '%s' but got '%s'", expected, got)
+		}
+	}
+}
+
+func TestReadSpecFileRejectsInvalidSpecs(t *testing.T) {
+	for spec, expected := range map[string]string{
+		"name\nhero\n":                     "Missing source column",
+		"source, size\nhero.png, 8\n":      "Unknown column 'size'",
+		"source, padding\nhero.png, two\n": "Line 2: invalid padding 'two'",
+		"source, scale\nhero.png, -1\n":    "scale of 'hero.png' must be positive",
+	} {
+		specPath := writeSpecFiles(t, nil, "sprites.csv", spec)
+		_, err := packer.ReadSpecFile(specPath)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing '%s' but got '%v'", expected, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package packer
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// alphaFilename returns the filename of the alpha image split from the
+// named atlas, in the given container format
+func alphaFilename(atlasName string, container ContainerFormat) string {
+	return fmt.Sprintf("%s_a.%s", atlasName, container.ext())
+}
+
+// validateSplitAlpha checks that every descriptor format of a
+// SplitAlpha run can reference the alpha image of its atlases
+func (p *Params) validateSplitAlpha() error {
+	if !p.SplitAlpha {
+		return nil
+	}
+	for _, format := range p.descFormats() {
+		if !format.SplitAlpha {
+			return fmt.Errorf("SplitAlpha is not supported by the format '%s' as it can not reference the alpha image", format.Name)
+		}
+	}
+	return nil
+}
+
+// splitAlpha separates the image into an opaque copy of its color
+// channels and a grayscale image of its alpha channel
+func splitAlpha(img *image.NRGBA) (*image.NRGBA, *image.Gray) {
+	opaque := image.NewNRGBA(img.Bounds())
+	alpha := image.NewGray(img.Bounds())
+	copy(opaque.Pix, img.Pix)
+	for i := 0; i < len(opaque.Pix); i += 4 {
+		alpha.Pix[i/4] = opaque.Pix[i+3]
+		opaque.Pix[i+3] = 0xff
+	}
+	return opaque, alpha
+}
+
+// outputSplitAlphaImages writes the color channels of the atlas, in its
+// color model, to ImageFilename and its alpha channel to AlphaFilename
+func (a *atlas) outputSplitAlphaImages(outputter Outputter) error {
+	img, err := a.renderImage()
+	if err != nil {
+		return err
+	}
+	opaque, alpha := splitAlpha(img)
+	a.release(img)
+
+	err = withFile(outputter, a.ImageFilename, false, func(writer io.Writer) error {
+		return a.encodeImage(writer, convertColorModel(opaque, a.colorModel, a.background))
+	})
+	if err != nil {
+		return err
+	}
+	return withFile(outputter, a.AlphaFilename, false, func(writer io.Writer) error {
+		return a.encodeImage(writer, alpha)
+	})
+}
@@ -0,0 +1,116 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestSplitAlphaWritesColorAndAlphaImages(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Starling,
+		Input: NewImageStream(t, map[string]image.Image{
+			"sprite.png": newSolidImage(8, 8, color.NRGBA{200, 100, 50, 128}),
+		}),
+		Output:     outputRecorder,
+		Width:      16,
+		Height:     16,
+		SplitAlpha: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	got := outputRecorder.Got()
+
+	colorImg, err := png.Decode(bytes.NewReader(got["atlas-1.png"]))
+	if err != nil {
+		t.Fatalf("Expected color image to be a valid png but got '%s'", err)
+	}
+	if c := color.NRGBAModel.Convert(colorImg.At(2, 2)); c != (color.NRGBA{200, 100, 50, 255}) {
+		t.Errorf("Expected the color image to keep the color channels and be opaque but got %v", c)
+	}
+
+	alphaImg, err := png.Decode(bytes.NewReader(got["atlas-1_a.png"]))
+	if err != nil {
+		t.Fatalf("Expected alpha image to be a valid png but got '%s'", err)
+	}
+	if alphaImg.ColorModel() != color.GrayModel {
+		t.Errorf("Expected the alpha image to be grayscale")
+	}
+	if c := color.GrayModel.Convert(alphaImg.At(2, 2)).(color.Gray); c.Y != 128 {
+		t.Errorf("Expected the alpha image to contain the sprite alpha 128 but got %d", c.Y)
+	}
+	if c := color.GrayModel.Convert(alphaImg.At(12, 12)).(color.Gray); c.Y != 0 {
+		t.Errorf("Expected the alpha image to be black where the atlas is empty but got %d", c.Y)
+	}
+
+	desc := string(got["atlas-1.xml"])
+	if !strings.Contains(desc, `imagePath="atlas-1.png" alphaPath="atlas-1_a.png"`) {
+		t.Errorf("Expected the descriptor to reference both images but got\n\n%s", desc)
+	}
+}
+
+func TestSplitAlphaDescriptorsReferenceTheAlphaImage(t *testing.T) {
+	for _, test := range []struct {
+		format target.Format
+		desc   string
+		want   string
+	}{
+		{target.LoveModule, "atlas-1.lua", `local alpha = love.graphics.newImage('atlas-1_a.png')`},
+		{target.NDJSON, "atlas-1.ndjson", `"image":"atlas-1.png","alphaImage":"atlas-1_a.png"`},
+		{target.TextureArray, "atlas-1.json", `"alphaLayers": [
+		"atlas-1_a.png"
+	]`},
+	} {
+		outputRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: test.format,
+			Input: NewImageStream(t, map[string]image.Image{
+				"sprite.png": newSolidImage(8, 8, color.NRGBA{200, 100, 50, 128}),
+			}),
+			Output:     outputRecorder,
+			Width:      16,
+			Height:     16,
+			SplitAlpha: true,
+		}
+
+		if err := packer.Run(context.Background(), params); err != nil {
+			t.Fatalf("Expected %s run to succeed without error but got '%s'", test.format.Name, err)
+		}
+		if desc := string(outputRecorder.Got()[test.desc]); !strings.Contains(desc, test.want) {
+			t.Errorf("Expected the %s descriptor to reference the alpha image but got\n\n%s", test.format.Name, desc)
+		}
+	}
+}
+
+func TestSplitAlphaRejectsFormatsThatCanNotReferenceTheAlphaImage(t *testing.T) {
+	for _, format := range []target.Format{target.Love, target.Spine, target.Unity, target.Binary} {
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: format,
+			Input: NewImageStream(t, map[string]image.Image{
+				"sprite.png": newSolidImage(8, 8, color.NRGBA{200, 100, 50, 128}),
+			}),
+			Output:     NewOutputRecorder(),
+			Width:      16,
+			Height:     16,
+			SplitAlpha: true,
+		}
+
+		err := packer.Run(context.Background(), params)
+		if err == nil || !strings.Contains(err.Error(), "SplitAlpha is not supported by the format '"+format.Name+"'") {
+			t.Errorf("Expected SplitAlpha to be rejected for %s but got '%v'", format.Name, err)
+		}
+	}
+}
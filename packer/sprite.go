@@ -1,8 +1,15 @@
 package packer
 
 import (
+	"fmt"
+	"image"
+	"image/color"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/psucodervn/lovepac/packing"
 )
 
 // sprite implements the Block interface for packing
@@ -10,27 +17,192 @@ import (
 // was constructed to represent
 type sprite struct {
 	Asset
-	path    string
-	x, y    int
-	w, h    int
-	padding int
-	placed  bool
+	path string
+	x, y int
+	w, h int
+	// paddingX and paddingY are the horizontal and vertical padding
+	paddingX, paddingY int
+	align              int
+	// paddingMode decides which sides of the sprite are padded
+	paddingMode PaddingMode
+	// keepTogether is the 1 based KeepTogether group of the sprite
+	keepTogether int
+	placed       bool
+	ninePatch    bool
+	border       border
+	chromaKey    *chromaKey
+	// rasterizer renders the asset when it is a vector image
+	rasterizer Rasterizer
+	// img is the decoded image content of sprites that do not
+	// map directly to an asset, eg. the frames of an animation
+	img image.Image
+	// animation composites the image of the sprites that are the frame
+	// with the index frame of an animated GIF, see ExplodeAnimations
+	animation *gifAnimation
+	frame     int
+	// delay is the duration of an animation frame in milliseconds
+	delay int
+	// orientation is the EXIF orientation the asset is stored with
+	orientation int
+	// gray is set when the asset is an 8-bit grayscale image
+	gray bool
+	// trim is the rectangle of the decoded image that is packed when
+	// the sprite is trimmed, see TrimMode. trimX, trimY and the source
+	// size place the scaled trimmed sprite within the untrimmed sprite.
+	trim             image.Rectangle
+	trimX, trimY     int
+	sourceW, sourceH int
+	// pivot is the point formats with pivots place the sprite around
+	pivot pivot
+	// palette is the set of colors of the sprite, see Params.EmitPalettes
+	palette []color.NRGBA
+	// index is the position of the sprite in the packing order
+	index int
+	// origin and the atlas size are set once the sprite
+	// is packed, to transform its coordinates for templates
+	origin         OriginMode
+	atlasW, atlasH int
+	// layer is the 0 based index of the atlas the sprite is packed on
+	layer int
+	// nameSeparator replaces the directory separators of the
+	// DisplayName, see Params.NameSeparator
+	nameSeparator string
 }
 
 // Implement block interface
 func (s *sprite) Size() (int, int) {
-	return s.w + s.padding, s.h + s.padding
+	_, afterX := s.paddingMode.edges(s.paddingX)
+	_, afterY := s.paddingMode.edges(s.paddingY)
+	offsetX, offsetY := s.offset()
+	return offsetX + alignUp(s.w+afterX, s.align), offsetY + alignUp(s.h+afterY, s.align)
 }
 func (s *sprite) Place(x int, y int) {
-	s.x = x + s.padding
-	s.y = y + s.padding
+	offsetX, offsetY := s.offset()
+	s.x = x + offsetX
+	s.y = y + offsetY
 	s.placed = true
 }
 
+// offset returns the distance from the position that the sprite's
+// block is placed at to the sprite itself, the aligned padding before it
+func (s *sprite) offset() (x, y int) {
+	beforeX, _ := s.paddingMode.edges(s.paddingX)
+	beforeY, _ := s.paddingMode.edges(s.paddingY)
+	return alignUp(beforeX, s.align), alignUp(beforeY, s.align)
+}
+
+// sortSpritesByName sorts the sprites by name, sprites with
+// the same name in different directories are sorted by path
+func sortSpritesByName(sprites []packing.Block) {
+	sort.Slice(sprites, func(i, j int) bool {
+		a, b := sprites[i].(*sprite), sprites[j].(*sprite)
+		if a.Name() != b.Name() {
+			return a.Name() < b.Name()
+		}
+		return a.path < b.path
+	})
+}
+
+// alignUp rounds n up to the nearest multiple of align,
+// an align of 1 or less leaves n unchanged
+func alignUp(n, align int) int {
+	if align <= 1 {
+		return n
+	}
+	return (n + align - 1) / align * align
+}
+
 // Used for template rendering
-func (s *sprite) Name() string        { return strings.Replace(path.Base(s.path), path.Ext(s.path), "", 1) }
-func (s *sprite) DisplayName() string { return strings.Replace(s.path, path.Ext(s.path), "", 1) }
+func (s *sprite) Name() string        { return strings.Replace(path.Base(s.path), s.ext(), "", 1) }
+func (s *sprite) DisplayName() string { return s.separateName(strings.Replace(s.path, s.ext(), "", 1)) }
 func (s *sprite) Left() int           { return s.x }
-func (s *sprite) Top() int            { return s.y }
+func (s *sprite) Top() int            { return s.origin.top(s.y, s.h, s.atlasH) }
 func (s *sprite) Width() int          { return s.w }
 func (s *sprite) Height() int         { return s.h }
+func (s *sprite) BorderLeft() int     { return s.border.left }
+func (s *sprite) BorderTop() int      { return s.border.top }
+func (s *sprite) BorderRight() int    { return s.border.right }
+func (s *sprite) BorderBottom() int   { return s.border.bottom }
+func (s *sprite) Delay() int          { return s.delay }
+func (s *sprite) Index() int          { return s.index }
+func (s *sprite) Layer() int          { return s.layer }
+
+// separateName replaces the directory separators of the name with the
+// nameSeparator of the sprite, if it has one
+func (s *sprite) separateName(name string) string {
+	if s.nameSeparator == "" {
+		return name
+	}
+	return strings.ReplaceAll(filepath.ToSlash(name), "/", s.nameSeparator)
+}
+
+// Rotated is always false as sprites are packed as they are, formats that
+// can describe sprites turned by 90 degrees, eg. starling, check it
+func (s *sprite) Rotated() bool { return false }
+
+// Texture coordinates of the sprite edges, between 0 and 1
+func (s *sprite) U0() float64 { return float64(s.x) / float64(s.atlasW) }
+func (s *sprite) V0() float64 { return float64(s.Top()) / float64(s.atlasH) }
+func (s *sprite) U1() float64 { return float64(s.x+s.w) / float64(s.atlasW) }
+func (s *sprite) V1() float64 { return float64(s.Top()+s.h) / float64(s.atlasH) }
+
+// ext returns the file extension that is dropped from the sprite name
+func (s *sprite) ext() string {
+	if s.ninePatch {
+		return s.path[len(s.path)-len(ninePatchExt):]
+	}
+	return path.Ext(s.path)
+}
+
+// decodeImage decodes the sprite's asset and returns the image content
+// that should be drawn into the atlas.
+func (s *sprite) decodeImage() (image.Image, error) {
+	var img image.Image
+	var err error
+	if s.img != nil {
+		img = s.img
+	} else if s.animation != nil {
+		img = s.animation.frame(s.frame)
+	} else if s.rasterizer != nil {
+		img, err = rasterizeAsset(s.rasterizer, s.Asset, s.path, s.w, s.h)
+	} else {
+		img, err = decodeAsset(s.Asset, s.path)
+		if err == nil {
+			img = applyOrientation(img, s.orientation)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.ninePatch {
+		img = stripNinePatchGuides(img)
+	}
+	if s.chromaKey != nil {
+		img = s.chromaKey.apply(img)
+	}
+	if s.Trimmed() {
+		img = img.(trimmer).SubImage(s.trim)
+	}
+	return img, nil
+}
+
+// release drops the decoded image content the sprite holds, after
+// which it is decoded from its asset if it is drawn again
+func (s *sprite) release() {
+	s.img = nil
+	s.animation = nil
+}
+
+// decodeAsset reads and decodes the full image of an asset
+func decodeAsset(asset Asset, assetPath string) (image.Image, error) {
+	assetReader, err := asset.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read asset '%s': %s", assetPath, err)
+	}
+	defer assetReader.Close()
+	img, _, err := image.Decode(assetReader)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode asset '%s': %s", assetPath, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,95 @@
+package packer
+
+import (
+	"hash/fnv"
+	"sort"
+
+	"github.com/psucodervn/lovepac/packing"
+)
+
+// pager chooses the units that each atlas is packed from when they are
+// assigned to home atlases up front rather than filling one atlas after
+// another, units that don't fit their home atlas overflow into the next.
+//
+// For Params.StablePaging the home atlas of every unit is picked by the
+// hash of its sprite names, so a sprite that is added or removed only
+// moves those sprites that overflow differently rather than every
+// smaller sprite.
+type pager struct {
+	homes [][]*packUnit
+	// order is the position of each unit in the packing order
+	order map[*packUnit]int
+}
+
+// newStablePager spreads the units over their home atlases
+func newStablePager(units []*packUnit, atlases int) *pager {
+	if atlases < 1 {
+		atlases = 1
+	}
+	homes := make([]int, len(units))
+	for i, unit := range units {
+		homes[i] = int(unitHash(unit) % uint32(atlases))
+	}
+	return newPager(units, homes, atlases)
+}
+
+// newPager returns a pager with the home atlas of each unit
+func newPager(units []*packUnit, homes []int, atlases int) *pager {
+	s := &pager{homes: make([][]*packUnit, atlases), order: map[*packUnit]int{}}
+	for i, unit := range units {
+		s.homes[homes[i]] = append(s.homes[homes[i]], unit)
+		s.order[unit] = i
+	}
+	return s
+}
+
+// unitHash hashes the first of the names of the unit's sprites,
+// which doesn't depend on the order the sprites are packed in
+func unitHash(unit *packUnit) uint32 {
+	name := ""
+	for _, block := range unit.blocks {
+		if spr, ok := block.(*sprite); ok && (name == "" || spr.path < name) {
+			name = spr.path
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return h.Sum32()
+}
+
+// next returns the units to pack the atlas with the 0 based index from,
+// the overflow of the previous atlas and the units whose home it is, in
+// packing order. If there are none the homes of the following atlas are
+// used instead so that no atlas is left empty.
+func (s *pager) next(index int, overflow []*packUnit) []*packUnit {
+	units := append([]*packUnit(nil), overflow...)
+	for ; index < len(s.homes); index++ {
+		units = append(units, s.homes[index]...)
+		s.homes[index] = nil
+		if len(units) > 0 {
+			break
+		}
+	}
+	sort.SliceStable(units, func(i, j int) bool { return s.order[units[i]] < s.order[units[j]] })
+	return units
+}
+
+// remaining returns true if any unit has a home atlas that was not packed yet
+func (s *pager) remaining() bool {
+	for _, homes := range s.homes {
+		if len(homes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// stablePageCount returns the number of atlases that the sprites are packed
+// into without StablePaging, which is the number of home atlases
+func stablePageCount(sprites []packing.Block, params *Params, strategy Strategy) int {
+	atlases, _, ok := tryStrategy(sprites, params, strategy)
+	if !ok {
+		return 1
+	}
+	return atlases
+}
@@ -0,0 +1,74 @@
+package packer_test
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+)
+
+// packedAtlases packs the images and returns the atlas descriptor that
+// lists each sprite
+func packedAtlases(t *testing.T, images map[string]image.Image, stable bool) map[string]string {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:         "atlas",
+		Format:       spriteNamesFormat,
+		Input:        NewImageStream(t, images),
+		Output:       outputRecorder,
+		Width:        64,
+		Height:       64,
+		StablePaging: stable,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	atlasOf := map[string]string{}
+	for filename, desc := range outputRecorder.Got() {
+		if !strings.HasSuffix(filename, ".txt") {
+			continue
+		}
+		for _, name := range strings.Fields(string(desc)) {
+			atlasOf[name] = filename
+		}
+	}
+	if len(atlasOf) != len(images) {
+		t.Fatalf("Expected every one of the %d sprites to be packed but got %d", len(images), len(atlasOf))
+	}
+	return atlasOf
+}
+
+func TestStablePagingKeepsSpritesOnTheirAtlasWhenOneIsAdded(t *testing.T) {
+	images := map[string]image.Image{}
+	for i := 0; i < 40; i++ {
+		images[fmt.Sprintf("icon%02d.png", i)] = newSolidImage(16, 16, color.White)
+	}
+
+	moved := map[bool]int{}
+	for _, stable := range []bool{false, true} {
+		before := packedAtlases(t, images, stable)
+		// The largest sprite is packed first, so by size alone every icon shifts
+		withBanner := map[string]image.Image{"banner.png": newSolidImage(32, 32, color.White)}
+		for name, img := range images {
+			withBanner[name] = img
+		}
+		after := packedAtlases(t, withBanner, stable)
+
+		for name, atlas := range before {
+			if after[name] != atlas {
+				moved[stable]++
+			}
+		}
+	}
+
+	// Only icons overflowing the home atlas of the banner move
+	if moved[true] > 2 || moved[true] >= moved[false] {
+		t.Errorf("Expected at most 2 icons to move to another atlas with StablePaging but %d moved, %d without it",
+			moved[true], moved[false])
+	}
+}
@@ -0,0 +1,87 @@
+package packer
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+)
+
+// StagingOutputter is an Outputter that holds every file in memory
+// until Commit is called, at which point the files are written to the
+// wrapped Outputter. Files that are never committed are never written,
+// so a failed or cancelled run leaves no half-written atlases behind.
+type StagingOutputter struct {
+	Outputter
+	mu    sync.Mutex
+	files map[string]*stagedFile
+}
+
+// stagedFile is the content of a file held by a StagingOutputter
+type stagedFile struct {
+	bytes.Buffer
+	// append is set when the file was first opened for appending,
+	// it is then appended to the existing file when committed
+	append bool
+}
+
+// NewStagingOutputter creates a StagingOutputter that commits to the given outputter
+func NewStagingOutputter(outputter Outputter) *StagingOutputter {
+	return &StagingOutputter{Outputter: outputter, files: map[string]*stagedFile{}}
+}
+
+// GetWriter implements the Outputter interface
+func (s *StagingOutputter) GetWriter(filename string, append bool) (io.WriteCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[filename]
+	if !ok || !append {
+		file = &stagedFile{append: append && !ok}
+		s.files[filename] = file
+	}
+	return nopWriteCloser{&file.Buffer}, nil
+}
+
+// Commit writes every staged file to the wrapped Outputter in order
+// of filename and clears the stage. Commit stops at the first error.
+func (s *StagingOutputter) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filenames := make([]string, 0, len(s.files))
+	for filename := range s.files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		file := s.files[filename]
+		err := withFile(s.Outputter, filename, file.append, func(writer io.Writer) error {
+			_, err := file.WriteTo(writer)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		delete(s.files, filename)
+	}
+	return nil
+}
+
+// runOutput returns the Outputter that a run writes to, a StagingOutputter
+// of the Output for Transactional runs, and the function that commits the
+// files written once the run has succeeded
+func (p *Params) runOutput() (Outputter, func() error) {
+	if !p.Transactional {
+		return p.Output, func() error { return nil }
+	}
+	staging := NewStagingOutputter(p.Output)
+	return staging, staging.Commit
+}
+
+// Discard drops every staged file without writing it
+func (s *StagingOutputter) Discard() {
+	s.mu.Lock()
+	s.files = map[string]*stagedFile{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,138 @@
+package packer_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestStagingOutputterWritesNothingUntilCommit(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	staging := packer.NewStagingOutputter(outputRecorder)
+
+	write := func(filename string, append bool, content string) {
+		writer, err := staging.GetWriter(filename, append)
+		if err != nil {
+			t.Fatalf("Failed to get writer: %s", err)
+		}
+		io.WriteString(writer, content)
+		writer.Close()
+	}
+	write("a.txt", false, "hello ")
+	write("a.txt", true, "world")
+	write("b.txt", false, "discarded")
+	write("b.txt", false, "replaced")
+
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Fatalf("Expected no files before commit but got %d", len(got))
+	}
+
+	if err := staging.Commit(); err != nil {
+		t.Fatalf("Expected commit to succeed but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	expected := map[string]string{"a.txt": "hello world", "b.txt": "replaced"}
+	for filename, content := range expected {
+		if buf, ok := got[filename]; !ok || string(buf) != content {
+			t.Errorf("Expected '%s' to contain '%s' but got '%s'", filename, content, buf)
+		}
+	}
+}
+
+func TestStagingOutputterDiscardDropsFiles(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	staging := packer.NewStagingOutputter(outputRecorder)
+
+	writer, _ := staging.GetWriter("a.txt", false)
+	io.WriteString(writer, "content")
+	writer.Close()
+
+	staging.Discard()
+	if err := staging.Commit(); err != nil {
+		t.Fatalf("Expected commit to succeed but got '%s'", err)
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected discarded files not to be written but got %d", len(got))
+	}
+}
+
+func TestFailedRunWritesNothing(t *testing.T) {
+	files := []string{
+		"button_active.png",
+		"button_hover.png",
+		"button.png",
+		"character_evil.png",
+		"character_hero.png",
+	}
+
+	// The first atlas is packed and encoded before
+	// the maximum number of atlases is exceeded
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:        target.Love,
+		Input:         packer.NewFilenameStream("./fixtures", files...),
+		Output:        outputRecorder,
+		Width:         400,
+		Height:        400,
+		MaxAtlases:    1,
+		Transactional: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err == nil {
+		t.Fatalf("Expected run to fail but error was nil")
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected a failed run to write nothing but got %d files", len(got))
+	}
+}
+
+func TestRunTimeoutCancelsRunAndWritesNothing(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Format:        target.Love,
+		Input:         NewImageStream(t, map[string]image.Image{"sprite.png": newSolidImage(8, 8, color.White)}),
+		Output:        outputRecorder,
+		Timeout:       time.Nanosecond,
+		Transactional: true,
+	}
+
+	err := packer.Run(context.Background(), params)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected '%s' but got '%v'", context.DeadlineExceeded, err)
+	}
+	if got := outputRecorder.Got(); len(got) != 0 {
+		t.Errorf("Expected a cancelled run to write nothing but got %d files", len(got))
+	}
+}
+
+func TestRunWritesEachFileAsItIsEncoded(t *testing.T) {
+	// The dependency file is written last, once every atlas is written
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  NewImageStream(t, map[string]image.Image{"sprite.png": newSolidImage(8, 8, color.White)}),
+		Output: packer.OutputterFunc(func(filename string, append bool) (io.WriteCloser, error) {
+			if filename == "atlas.d" {
+				return nil, errors.New("Disk full")
+			}
+			return outputRecorder.GetWriter(filename, append)
+		}),
+		DepFile: "atlas.d",
+	}
+
+	if err := packer.Run(context.Background(), params); err == nil {
+		t.Fatalf("Expected writing the dependency file to fail the run")
+	}
+	if _, ok := outputRecorder.Got()["atlas-1.png"]; !ok {
+		t.Errorf("Expected the atlas image to be written before the run failed but got %v", outputRecorder.Order())
+	}
+}
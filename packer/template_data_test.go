@@ -0,0 +1,86 @@
+package packer_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/packing"
+	"github.com/psucodervn/lovepac/target"
+)
+
+var (
+	blockType = reflect.TypeOf((*packing.Block)(nil)).Elem()
+	assetType = reflect.TypeOf((*packer.Asset)(nil)).Elem()
+)
+
+// templateRefs returns a template referencing every exported field of
+// the struct, and every method templates can call on it, ranging over
+// slices and descending into structs. The sprites of atlases are packed
+// as blocks, which are always sprites.
+func templateRefs(t reflect.Type) string {
+	if t == blockType {
+		t = packer.SpriteType
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	refs := &strings.Builder{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Anonymous {
+			continue
+		}
+		switch elem := field.Type; {
+		case elem.Kind() == reflect.Slice && isTemplateData(elem.Elem()):
+			refs.WriteString("{{range ." + field.Name + "}}" + templateRefs(elem.Elem()) + "{{end}}")
+		case isTemplateData(elem):
+			refs.WriteString("{{with ." + field.Name + "}}" + templateRefs(elem) + "{{end}}")
+		default:
+			refs.WriteString("{{." + field.Name + "}}")
+		}
+	}
+	methods := reflect.PtrTo(t)
+	for i := 0; i < methods.NumMethod(); i++ {
+		method := methods.Method(i)
+		if _, ok := assetType.MethodByName(method.Name); ok {
+			continue
+		}
+		// Only methods without arguments and with a single result can be called
+		if method.Type.NumIn() == 1 && method.Type.NumOut() == 1 {
+			refs.WriteString("{{." + method.Name + "}}")
+		}
+	}
+	return refs.String()
+}
+
+// isTemplateData returns true if templates descend into values of the type
+func isTemplateData(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == blockType || t.Kind() == reflect.Struct
+}
+
+func TestTemplateSampleDataMirrorsTheAtlases(t *testing.T) {
+	for _, test := range []struct {
+		data  reflect.Type
+		multi bool
+	}{
+		{packer.AtlasType, false},
+		{packer.AtlasSetType, true},
+	} {
+		refs := templateRefs(test.data)
+		format := target.Format{
+			Name:     test.data.Name(),
+			Template: template.Must(template.New(test.data.Name()).Parse(refs)),
+			Ext:      "txt",
+			Multi:    test.multi,
+		}
+		if err := format.Validate(); err != nil {
+			t.Errorf("Expected the sample data to have every field and method of the %s but got '%s'", test.data.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package packer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateTextureArray checks that every atlas of a TextureArray run is
+// packed at the same size, as the layers of a texture array must be
+func (p *Params) validateTextureArray() error {
+	if !p.TextureArray {
+		return nil
+	}
+	var conflicts []string
+	if p.Fit {
+		conflicts = append(conflicts, "Fit")
+	}
+	if len(p.AtlasSizes) > 0 {
+		conflicts = append(conflicts, "AtlasSizes")
+	}
+	if p.MaxFileBytes > 0 {
+		conflicts = append(conflicts, "MaxFileBytes")
+	}
+	if p.MinLastAtlasOccupancy > 0 {
+		conflicts = append(conflicts, "MinLastAtlasOccupancy")
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("TextureArray needs atlases of the same size and can not be used with %s", strings.Join(conflicts, ", "))
+	}
+	return nil
+}
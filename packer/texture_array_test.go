@@ -0,0 +1,77 @@
+package packer_test
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"sort"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestTextureArrayDescribesTheLayerOfEachSprite(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: target.TextureArray,
+		Input: NewImageStream(t, map[string]image.Image{
+			"a.png": newSolidImage(16, 16, color.White),
+			"b.png": newSolidImage(16, 16, color.White),
+			"c.png": newSolidImage(4, 4, color.White),
+		}),
+		Output:       outputRecorder,
+		Width:        16,
+		Height:       16,
+		TextureArray: true,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	got := outputRecorder.Got()
+	if _, ok := got["atlas-1.json"]; ok {
+		t.Errorf("Expected only the combined descriptor to be written but got %v", outputRecorder.Order())
+	}
+	var desc struct {
+		Layers []string `json:"layers"`
+		Frames []struct {
+			Layer int `json:"layer"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(got["atlas.json"], &desc); err != nil {
+		t.Fatalf("Expected a JSON descriptor but got '%s'", err)
+	}
+	if len(desc.Layers) != 3 {
+		t.Fatalf("Expected 3 layers but got %v", desc.Layers)
+	}
+	var layers []int
+	for _, frame := range desc.Frames {
+		layers = append(layers, frame.Layer)
+	}
+	sort.Ints(layers)
+	if len(layers) != 3 || layers[0] != 0 || layers[1] != 1 || layers[2] != 2 {
+		t.Errorf("Expected a sprite on each of the layers 0, 1 and 2 but got %v", layers)
+	}
+}
+
+func TestTextureArrayRejectsAtlasesOfDifferentSizes(t *testing.T) {
+	params := &packer.Params{
+		Name:         "atlas",
+		Format:       target.TextureArray,
+		Input:        NewImageStream(t, map[string]image.Image{"a.png": newSolidImage(4, 4, color.White)}),
+		Output:       NewOutputRecorder(),
+		Fit:          true,
+		MaxFileBytes: 1024,
+		TextureArray: true,
+	}
+
+	err := packer.Run(context.Background(), params)
+	expected := "TextureArray needs atlases of the same size and can not be used with Fit, MaxFileBytes"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected the error '%s' but got '%v'", expected, err)
+	}
+}
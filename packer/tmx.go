@@ -0,0 +1,360 @@
+package packer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tmxFlagMask clears the flip and rotation flags stored in the
+// highest bits of a tile gid, leaving the global tile id
+const tmxFlagMask = 0x0fffffff
+
+// TMXTile is an Asset representing a tile of a Tiled map's tileset
+// that is used by the map. The tile is encoded as a png when it is read.
+type TMXTile struct {
+	// Name of the asset, the map filename without its extension
+	// joined with the gid of the tile, eg. "level1/17.png", so
+	// that descriptors map the gids of the map to packed rects
+	Name string
+	// GID is the global tile id that the map refers to the tile by
+	GID uint32
+
+	img image.Image
+}
+
+// Reader implements the Asset interface
+func (t *TMXTile) Reader() (io.ReadCloser, error) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, t.img); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+// Asset implements the Asset interface
+func (t *TMXTile) Asset() string {
+	return t.Name
+}
+
+// NewTMXStream creates an asset streamer that streams every tile that
+// the Tiled map at the given path uses, from its tile layers, including
+// those of infinite maps and layer groups, and its tile objects. Unused
+// tiles of the tilesets are not packed. Tilesets may be embedded in the
+// map or external .tsx files but must be a single image, tilesets of
+// separate tile images are not supported. Layer data may be CSV, XML or
+// base64 encoded, uncompressed or gzip or zlib compressed.
+func NewTMXStream(tmxPath string) AssetStreamer {
+	return AssetStreamerFunc(func(ctx context.Context) (<-chan Asset, <-chan error) {
+		stream := make(chan Asset)
+		errc := make(chan error, 1)
+
+		go func() {
+			defer close(stream)
+			defer close(errc)
+
+			if ctx == nil {
+				errc <- errContextNil
+				return
+			}
+
+			tiles, err := readTMXTiles(tmxPath)
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			for _, tile := range tiles {
+				select {
+				case stream <- tile:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+		}()
+
+		return stream, errc
+	})
+}
+
+type tmxMap struct {
+	Tilesets []tmxTileset `xml:"tileset"`
+	tmxLayers
+}
+
+// tmxLayers are the layers of a map or of a layer group
+type tmxLayers struct {
+	Layers  []tmxLayer       `xml:"layer"`
+	Objects []tmxObjectGroup `xml:"objectgroup"`
+	Groups  []tmxLayers      `xml:"group"`
+}
+
+type tmxTileset struct {
+	FirstGID   uint32 `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	Name       string `xml:"name,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Margin     int    `xml:"margin,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+	Image      *struct {
+		Source string `xml:"source,attr"`
+	} `xml:"image"`
+}
+
+type tmxLayer struct {
+	Name string  `xml:"name,attr"`
+	Data tmxData `xml:"data"`
+}
+
+type tmxData struct {
+	Encoding    string     `xml:"encoding,attr"`
+	Compression string     `xml:"compression,attr"`
+	Content     string     `xml:",chardata"`
+	Tiles       []tmxGID   `xml:"tile"`
+	Chunks      []tmxChunk `xml:"chunk"`
+}
+
+type tmxChunk struct {
+	Content string   `xml:",chardata"`
+	Tiles   []tmxGID `xml:"tile"`
+}
+
+type tmxGID struct {
+	GID uint32 `xml:"gid,attr"`
+}
+
+type tmxObjectGroup struct {
+	Objects []tmxGID `xml:"object"`
+}
+
+// readTMXTiles reads the map at the given path and returns
+// a tile for every gid that it uses, in order of gid
+func readTMXTiles(tmxPath string) ([]*TMXTile, error) {
+	var m tmxMap
+	if err := readTMXFile(tmxPath, &m); err != nil {
+		return nil, err
+	}
+
+	used := map[uint32]bool{}
+	if err := m.tmxLayers.usedGIDs(used); err != nil {
+		return nil, fmt.Errorf("Failed to decode TMX '%s': %s", tmxPath, err)
+	}
+	// The tile of each gid is in the tileset with the largest firstgid not above it
+	tilesets := m.Tilesets
+	sort.Slice(tilesets, func(i, j int) bool { return tilesets[i].FirstGID < tilesets[j].FirstGID })
+
+	gids := make([]uint32, 0, len(used))
+	for gid := range used {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	base := strings.TrimSuffix(filepath.Base(tmxPath), filepath.Ext(tmxPath))
+	images := map[int]image.Image{}
+	tiles := make([]*TMXTile, 0, len(gids))
+	for _, gid := range gids {
+		i := sort.Search(len(tilesets), func(i int) bool { return tilesets[i].FirstGID > gid }) - 1
+		if i < 0 {
+			return nil, fmt.Errorf("TMX '%s' uses the tile %d that is in no tileset", tmxPath, gid)
+		}
+		img, ok := images[i]
+		if !ok {
+			var err error
+			if img, err = tilesets[i].load(filepath.Dir(tmxPath)); err != nil {
+				return nil, err
+			}
+			images[i] = img
+		}
+		tile, err := tilesets[i].tile(img, gid-tilesets[i].FirstGID)
+		if err != nil {
+			return nil, fmt.Errorf("TMX '%s' uses the tile %d: %s", tmxPath, gid, err)
+		}
+		tiles = append(tiles, &TMXTile{Name: fmt.Sprintf("%s/%d.png", base, gid), GID: gid, img: tile})
+	}
+	return tiles, nil
+}
+
+// readTMXFile decodes the XML of a map or tileset file
+func readTMXFile(filename string, v interface{}) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("Failed to decode TMX '%s': %s", filename, err)
+	}
+	return nil
+}
+
+// usedGIDs adds the gid of every tile the layers use to used
+func (l *tmxLayers) usedGIDs(used map[uint32]bool) error {
+	add := func(gid uint32) {
+		if gid &= tmxFlagMask; gid != 0 {
+			used[gid] = true
+		}
+	}
+	for _, layer := range l.Layers {
+		if err := layer.Data.usedGIDs(add); err != nil {
+			return fmt.Errorf("Layer '%s': %s", layer.Name, err)
+		}
+	}
+	for _, group := range l.Objects {
+		for _, object := range group.Objects {
+			add(object.GID)
+		}
+	}
+	for i := range l.Groups {
+		if err := l.Groups[i].usedGIDs(used); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usedGIDs calls add with every gid of the layer data
+func (d *tmxData) usedGIDs(add func(gid uint32)) error {
+	contents := []string{d.Content}
+	tiles := d.Tiles
+	for _, chunk := range d.Chunks {
+		contents = append(contents, chunk.Content)
+		tiles = append(tiles, chunk.Tiles...)
+	}
+	for _, tile := range tiles {
+		add(tile.GID)
+	}
+	for _, content := range contents {
+		gids, err := d.decode(strings.TrimSpace(content))
+		if err != nil {
+			return err
+		}
+		for _, gid := range gids {
+			add(gid)
+		}
+	}
+	return nil
+}
+
+// decode returns the gids of CSV or base64 encoded layer data
+func (d *tmxData) decode(content string) ([]uint32, error) {
+	if content == "" {
+		return nil, nil
+	}
+	if d.Encoding == "csv" {
+		var gids []uint32
+		for _, field := range strings.Split(content, ",") {
+			gid, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			gids = append(gids, uint32(gid))
+		}
+		return gids, nil
+	}
+	if d.Encoding != "base64" {
+		return nil, fmt.Errorf("Unsupported layer encoding '%s'", d.Encoding)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader = bytes.NewReader(data)
+	switch d.Compression {
+	case "":
+	case "gzip":
+		r, err = gzip.NewReader(r)
+	case "zlib":
+		r, err = zlib.NewReader(r)
+	default:
+		return nil, fmt.Errorf("Unsupported layer compression '%s'", d.Compression)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, err = ioutil.ReadAll(r); err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, errors.New("Layer data is not a whole number of gids")
+	}
+	gids := make([]uint32, len(data)/4)
+	for i := range gids {
+		gids[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return gids, nil
+}
+
+// load reads the tileset's external .tsx file, if it has one, and decodes
+// its image. Relative paths are relative to the file that refers to them.
+func (t *tmxTileset) load(dir string) (image.Image, error) {
+	if t.Source != "" {
+		tsxPath := filepath.Join(dir, t.Source)
+		firstGID := t.FirstGID
+		if err := readTMXFile(tsxPath, t); err != nil {
+			return nil, err
+		}
+		t.FirstGID = firstGID
+		dir = filepath.Dir(tsxPath)
+	}
+	if t.Image == nil || t.Image.Source == "" {
+		return nil, fmt.Errorf("Tileset '%s' is not a single image, which is not supported", t.Name)
+	}
+	if t.TileWidth <= 0 || t.TileHeight <= 0 {
+		return nil, fmt.Errorf("Tileset '%s' has an invalid tile size %dx%d", t.Name, t.TileWidth, t.TileHeight)
+	}
+
+	imagePath := filepath.Join(dir, t.Image.Source)
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode tileset image '%s': %s", imagePath, err)
+	}
+	return img, nil
+}
+
+// tile returns the tile with the local id of the tileset image
+func (t *tmxTileset) tile(img image.Image, id uint32) (image.Image, error) {
+	b := img.Bounds()
+	columns := t.Columns
+	if columns <= 0 {
+		columns = (b.Dx() - 2*t.Margin + t.Spacing) / (t.TileWidth + t.Spacing)
+	}
+	if columns <= 0 || (t.TileCount > 0 && int(id) >= t.TileCount) {
+		return nil, fmt.Errorf("Tileset '%s' has no tile %d", t.Name, id)
+	}
+	x := b.Min.X + t.Margin + int(id)%columns*(t.TileWidth+t.Spacing)
+	y := b.Min.Y + t.Margin + int(id)/columns*(t.TileHeight+t.Spacing)
+	r := image.Rect(x, y, x+t.TileWidth, y+t.TileHeight)
+	if !r.In(b) {
+		return nil, fmt.Errorf("Tileset '%s' has no tile %d", t.Name, id)
+	}
+	// Copy the tile so that the tileset image is not kept alive
+	tile := image.NewNRGBA(image.Rect(0, 0, t.TileWidth, t.TileHeight))
+	draw.Draw(tile, tile.Bounds(), img, r.Min, draw.Src)
+	return tile, nil
+}
@@ -0,0 +1,156 @@
+package packer_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+// tmxTileColors are the colors of the four 2x2 tiles of the test tileset
+var tmxTileColors = []color.NRGBA{
+	{255, 0, 0, 255},
+	{0, 255, 0, 255},
+	{0, 0, 255, 255},
+	{255, 255, 0, 255},
+}
+
+// writeTMXTileset writes a 4x4 tileset image of 2x2 tiles to the directory
+func writeTMXTileset(t *testing.T, dir string) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i, c := range tmxTileColors {
+		x, y := i%2*2, i/2*2
+		for p := 0; p < 4; p++ {
+			img.Set(x+p%2, y+p/2, c)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "tiles.png"), buf.String())
+}
+
+func writeTestFile(t *testing.T, filename, content string) {
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// packTMX packs the map and returns the descriptor line of each tile
+func packTMX(t *testing.T, tmxPath string) []string {
+	format := target.Format{
+		Name:     "tiles",
+		Template: template.Must(template.New("tiles").Parse("{{range .Sprites}}{{.DisplayName}}\n{{end}}")),
+		Ext:      "txt",
+	}
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:   "atlas",
+		Format: format,
+		Input:  packer.NewTMXStream(tmxPath),
+		Output: outputRecorder,
+		Width:  8,
+		Height: 8,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	got := strings.Fields(string(outputRecorder.Got()["atlas-1.txt"]))
+	sort.Strings(got)
+	return got
+}
+
+func TestTMXStreamPacksOnlyUsedTiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTMXTileset(t, dir)
+	// The second tile is flipped horizontally, which must not change its gid
+	writeTestFile(t, filepath.Join(dir, "level1.tmx"), `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="2" height="2" tilewidth="2" tileheight="2">
+ <tileset firstgid="1" name="tiles" tilewidth="2" tileheight="2" tilecount="4" columns="2">
+  <image source="tiles.png" width="4" height="4"/>
+ </tileset>
+ <layer id="1" name="ground" width="2" height="2">
+  <data encoding="csv">
+1,0,
+2147483652,1
+</data>
+ </layer>
+</map>`)
+
+	expected := []string{"level1/1", "level1/4"}
+	if got := packTMX(t, filepath.Join(dir, "level1.tmx")); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the used tiles %v to be packed but got %v", expected, got)
+	}
+}
+
+func TestTMXStreamReadsExternalTilesetsAndCompressedLayers(t *testing.T) {
+	dir := t.TempDir()
+	writeTMXTileset(t, dir)
+	writeTestFile(t, filepath.Join(dir, "tiles.tsx"), `<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" name="tiles" tilewidth="2" tileheight="2" tilecount="4" columns="2">
+ <image source="tiles.png" width="4" height="4"/>
+</tileset>`)
+
+	raw := &bytes.Buffer{}
+	for _, gid := range []uint32{0, 12, 12, 0} {
+		binary.Write(raw, binary.LittleEndian, gid)
+	}
+	compressed := &bytes.Buffer{}
+	zw := zlib.NewWriter(compressed)
+	zw.Write(raw.Bytes())
+	zw.Close()
+
+	writeTestFile(t, filepath.Join(dir, "level2.tmx"), `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="2" height="2" tilewidth="2" tileheight="2">
+ <tileset firstgid="10" source="tiles.tsx"/>
+ <group name="background">
+  <layer id="1" name="ground" width="2" height="2">
+   <data encoding="base64" compression="zlib">`+base64.StdEncoding.EncodeToString(compressed.Bytes())+`</data>
+  </layer>
+ </group>
+ <objectgroup id="2" name="props">
+  <object id="1" gid="13" x="0" y="0" width="2" height="2"/>
+ </objectgroup>
+</map>`)
+
+	expected := []string{"level2/12", "level2/13"}
+	if got := packTMX(t, filepath.Join(dir, "level2.tmx")); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the used tiles %v to be packed but got %v", expected, got)
+	}
+}
+
+func TestTMXStreamRejectsTilesOutsideTheTilesets(t *testing.T) {
+	dir := t.TempDir()
+	writeTMXTileset(t, dir)
+	writeTestFile(t, filepath.Join(dir, "broken.tmx"), `<map>
+ <tileset firstgid="1" name="tiles" tilewidth="2" tileheight="2" tilecount="4" columns="2">
+  <image source="tiles.png"/>
+ </tileset>
+ <layer name="ground"><data encoding="csv">5</data></layer>
+</map>`)
+
+	params := &packer.Params{
+		Format: target.Love,
+		Input:  packer.NewTMXStream(filepath.Join(dir, "broken.tmx")),
+		Output: NewOutputRecorder(),
+	}
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "tile 5") {
+		t.Errorf("Expected an error naming the missing tile but got '%v'", err)
+	}
+}
@@ -0,0 +1,129 @@
+package packer
+
+import (
+	"fmt"
+	"image"
+)
+
+// TrimMode selects how the fully transparent edges of sprites are
+// trimmed before packing. Descriptors give the untrimmed size of a
+// sprite as SourceWidth and SourceHeight and the position of the
+// trimmed sprite within it as TrimX and TrimY. Rasterized sprites,
+// nine-patches and the frames of ExplodeAnimations are not trimmed.
+type TrimMode int
+
+const (
+	// TrimNone packs every sprite at its full size. This is the default.
+	TrimNone TrimMode = iota
+	// TrimAlpha trims every fully transparent row and column at the
+	// edges of a sprite, packing only the box around its visible pixels.
+	TrimAlpha
+	// TrimSymmetric trims as many fully transparent columns from the
+	// right as from the left, and rows from the bottom as from the top,
+	// so that the center of the trimmed sprite is the center of the
+	// untrimmed sprite, eg. to rotate wheels and projectiles about it.
+	TrimSymmetric
+)
+
+// trimmer is implemented by the decoded images that can be cropped
+type trimmer interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// trimSprite decodes the sprite and trims its transparent edges as the
+// mode selects, scaling and rounding the trimmed size and offset. Sprites
+// that are rasterized, nine-patches or entirely transparent are not trimmed.
+func trimSprite(spr *sprite, mode TrimMode, scale float64, rounding ScaleRounding) error {
+	if mode == TrimNone || spr.rasterizer != nil || spr.ninePatch {
+		return nil
+	}
+	img, err := spr.decodeImage()
+	if err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+	visible := alphaBounds(img)
+	if visible.Empty() || visible == bounds {
+		return nil
+	}
+	if _, ok := img.(trimmer); !ok {
+		return fmt.Errorf("Asset '%s' can not be trimmed", spr.path)
+	}
+	if mode == TrimSymmetric {
+		visible = symmetricBounds(bounds, visible)
+	}
+
+	spr.sourceW, spr.sourceH = spr.w, spr.h
+	spr.trimX = rounding.scale(visible.Min.X-bounds.Min.X, scale)
+	spr.trimY = rounding.scale(visible.Min.Y-bounds.Min.Y, scale)
+	if mode == TrimSymmetric {
+		// Trim the same number of scaled pixels from opposite sides
+		spr.w = spr.sourceW - 2*spr.trimX
+		spr.h = spr.sourceH - 2*spr.trimY
+	} else {
+		spr.w = rounding.scale(visible.Dx(), scale)
+		spr.h = rounding.scale(visible.Dy(), scale)
+	}
+	spr.trim = visible
+	return nil
+}
+
+// alphaBounds returns the smallest rectangle holding every pixel of
+// the image that is not fully transparent, empty if there are none
+func alphaBounds(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	minX, minY, maxX, maxY := b.Max.X, b.Max.Y, b.Min.X, b.Min.Y
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a == 0 {
+				continue
+			}
+			if x < minX {
+				minX = x
+			}
+			if x >= maxX {
+				maxX = x + 1
+			}
+			if y < minY {
+				minY = y
+			}
+			maxY = y + 1
+		}
+	}
+	if minX >= maxX {
+		return image.Rectangle{}
+	}
+	return image.Rectangle{Min: image.Pt(minX, minY), Max: image.Pt(maxX, maxY)}
+}
+
+// symmetricBounds grows the visible rectangle within bounds until the
+// distance to opposite edges of the bounds is the same
+func symmetricBounds(bounds, visible image.Rectangle) image.Rectangle {
+	left, right := visible.Min.X-bounds.Min.X, bounds.Max.X-visible.Max.X
+	top, bottom := visible.Min.Y-bounds.Min.Y, bounds.Max.Y-visible.Max.Y
+	if right < left {
+		left = right
+	}
+	if bottom < top {
+		top = bottom
+	}
+	return image.Rect(bounds.Min.X+left, bounds.Min.Y+top, bounds.Max.X-left, bounds.Max.Y-top)
+}
+
+// Template data of the untrimmed sprite, equal to the
+// packed sprite when the sprite was not trimmed
+func (s *sprite) Trimmed() bool { return s.trim != image.Rectangle{} }
+func (s *sprite) TrimX() int    { return s.trimX }
+func (s *sprite) TrimY() int    { return s.trimY }
+func (s *sprite) SourceWidth() int {
+	if s.Trimmed() {
+		return s.sourceW
+	}
+	return s.w
+}
+func (s *sprite) SourceHeight() int {
+	if s.Trimmed() {
+		return s.sourceH
+	}
+	return s.h
+}
@@ -0,0 +1,143 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+var trimFormat = target.Format{
+	Name:     "trim",
+	Template: template.Must(template.New("trim").Parse("{{range .Sprites}}{{.Trimmed}} {{.Left}},{{.Top}} {{.Width}}x{{.Height}} {{.TrimX}},{{.TrimY}} {{.SourceWidth}}x{{.SourceHeight}}{{end}}")),
+	Ext:      "txt",
+}
+
+func TestTrimModeTrimsTransparentEdges(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	// A 10x8 image with visible pixels from 2,1 to 5,2
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 8))
+	for y := 1; y <= 2; y++ {
+		for x := 2; x <= 5; x++ {
+			img.Set(x, y, red)
+		}
+	}
+
+	var tests = []struct {
+		name     string
+		mode     packer.TrimMode
+		expected string
+		visible  image.Point
+	}{
+		{"none", packer.TrimNone, "false 0,0 10x8 0,0 10x8", image.Pt(2, 1)},
+		// Only the visible 4x2 box is packed
+		{"alpha", packer.TrimAlpha, "true 0,0 4x2 2,1 10x8", image.Pt(0, 0)},
+		// 2 columns are trimmed from both sides and 1 row from the top and bottom
+		{"symmetric", packer.TrimSymmetric, "true 0,0 6x6 2,1 10x8", image.Pt(0, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputRecorder := NewOutputRecorder()
+			params := &packer.Params{
+				Name:     "atlas",
+				Format:   trimFormat,
+				Input:    NewImageStream(t, map[string]image.Image{"wheel.png": img}),
+				Output:   outputRecorder,
+				Width:    16,
+				Height:   16,
+				TrimMode: tt.mode,
+			}
+
+			if err := packer.Run(context.Background(), params); err != nil {
+				t.Fatalf("Expected run to succeed without error but got '%s'", err)
+			}
+
+			if got := string(outputRecorder.Got()["atlas-1.txt"]); got != tt.expected {
+				t.Errorf("Expected the sprite to be described as '%s' but got '%s'", tt.expected, got)
+			}
+
+			atlas, err := png.Decode(bytes.NewReader(outputRecorder.Got()["atlas-1.png"]))
+			if err != nil {
+				t.Fatalf("Expected output image to be a valid png but got '%s'", err)
+			}
+			// The first visible pixel is drawn where the sprite was trimmed to
+			tx, ty := tt.visible.X, tt.visible.Y
+			if got := color.NRGBAModel.Convert(atlas.At(tx, ty)).(color.NRGBA); got != red {
+				t.Errorf("Expected pixel %d,%d to be %v but got %v", tx, ty, red, got)
+			}
+		})
+	}
+}
+
+func TestTrimModeKeepsFullyTransparentSprites(t *testing.T) {
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:     "atlas",
+		Format:   trimFormat,
+		Input:    NewImageStream(t, map[string]image.Image{"empty.png": image.NewNRGBA(image.Rect(0, 0, 3, 2))}),
+		Output:   outputRecorder,
+		Width:    4,
+		Height:   4,
+		TrimMode: packer.TrimAlpha,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expected := "false 0,0 3x2 0,0 3x2"
+	if got := string(outputRecorder.Got()["atlas-1.txt"]); got != expected {
+		t.Errorf("Expected the sprite to be described as '%s' but got '%s'", expected, got)
+	}
+}
+
+func TestInvalidTrimModeIsRejected(t *testing.T) {
+	params := &packer.Params{
+		Format:   target.Love,
+		Input:    NewImageStream(t, map[string]image.Image{}),
+		Output:   NewOutputRecorder(),
+		TrimMode: packer.TrimMode(-1),
+	}
+	err := packer.Run(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "TrimMode") {
+		t.Errorf("Expected an invalid TrimMode to be rejected but got '%v'", err)
+	}
+}
+
+func TestStarlingDescribesTheFrameOfTrimmedSprites(t *testing.T) {
+	// A 10x8 image with visible pixels from 2,1 to 5,2
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 8))
+	for y := 1; y <= 2; y++ {
+		for x := 2; x <= 5; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:     "atlas",
+		Format:   target.Starling,
+		Input:    NewImageStream(t, map[string]image.Image{"wheel.png": img}),
+		Output:   outputRecorder,
+		Width:    16,
+		Height:   16,
+		TrimMode: packer.TrimAlpha,
+	}
+
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+
+	expected := `<SubTexture name="wheel" x="0" y="0" width="4" height="2" frameX="-2" frameY="-1" frameWidth="10" frameHeight="8"/>`
+	if got := string(outputRecorder.Got()["atlas-1.xml"]); !strings.Contains(got, expected) {
+		t.Errorf("Expected the descriptor to contain '%s' but got '%s'", expected, got)
+	}
+}
@@ -0,0 +1,82 @@
+package packer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Drift describes how the sprites packed from the current assets
+// differ from the sprites recorded in an existing source map
+type Drift struct {
+	// Added are the sprites missing from the existing source map
+	Added []string
+	// Removed are the sprites of the existing source map
+	// that are no longer packed
+	Removed []string
+	// Changed are the sprites whose source content, atlas
+	// or rect differs from the existing source map
+	Changed []string
+}
+
+// HasDrift returns true if any sprite was added, removed or changed
+func (d *Drift) HasDrift() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// Verify packs the assets in the same way as Run, without writing any
+// output, and compares the result with the source map written by an
+// earlier Run with EmitSourceMap. The returned Drift lists the sprites
+// that would change if the atlases were regenerated, so that a CI check
+// can detect source assets that were changed without repacking.
+// GroupByPrefixDepth and FolderAsAtlas are not supported as each group
+// has a separate source map.
+func Verify(ctx context.Context, params *Params, existingSourceMap io.Reader) (*Drift, error) {
+	if params == nil {
+		return nil, errors.New("Params must not be nil")
+	}
+	if params.groupsAssets() {
+		return nil, errors.New("Verify does not support GroupByPrefixDepth, FolderAsAtlas or SeparateByExtension")
+	}
+
+	var existing map[string]sourceMapEntry
+	if err := json.NewDecoder(existingSourceMap).Decode(&existing); err != nil {
+		return nil, fmt.Errorf("Failed to read the existing source map: %s", err)
+	}
+
+	output := newMemoryOutputter()
+	verifyParams := *params
+	verifyParams.Output = output
+	verifyParams.EmitSourceMap = true
+	if _, err := RunWithResult(ctx, &verifyParams); err != nil {
+		return nil, err
+	}
+
+	var current map[string]sourceMapEntry
+	if err := json.Unmarshal(output.files[sourceMapFilename(verifyParams.runName())].Bytes(), &current); err != nil {
+		return nil, fmt.Errorf("Failed to read the packed source map: %s", err)
+	}
+
+	drift := &Drift{}
+	for name, entry := range current {
+		existingEntry, ok := existing[name]
+		switch {
+		case !ok:
+			drift.Added = append(drift.Added, name)
+		case !existingEntry.matches(entry):
+			drift.Changed = append(drift.Changed, name)
+		}
+	}
+	for name := range existing {
+		if _, ok := current[name]; !ok {
+			drift.Removed = append(drift.Removed, name)
+		}
+	}
+	sort.Strings(drift.Added)
+	sort.Strings(drift.Removed)
+	sort.Strings(drift.Changed)
+	return drift, nil
+}
@@ -0,0 +1,135 @@
+package packer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/psucodervn/lovepac/packer"
+	"github.com/psucodervn/lovepac/target"
+)
+
+func TestVerifyReportsDriftFromExistingSourceMap(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	images := map[string]image.Image{
+		"same.png":    newSolidImage(16, 16, white),
+		"changed.png": newSolidImage(16, 16, white),
+		"removed.png": newSolidImage(8, 8, white),
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        target.Love,
+		Input:         NewImageStream(t, images),
+		Output:        outputRecorder,
+		Width:         64,
+		Height:        64,
+		EmitSourceMap: true,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	sourceMap := outputRecorder.Got()["atlas.sourcemap.json"]
+
+	verify := func(images map[string]image.Image) *packer.Drift {
+		verifyRecorder := NewOutputRecorder()
+		params := &packer.Params{
+			Name:   "atlas",
+			Format: target.Love,
+			Input:  NewImageStream(t, images),
+			Output: verifyRecorder,
+			Width:  64,
+			Height: 64,
+		}
+		drift, err := packer.Verify(context.Background(), params, bytes.NewReader(sourceMap))
+		if err != nil {
+			t.Fatalf("Expected verify to succeed without error but got '%s'", err)
+		}
+		if got := verifyRecorder.Got(); len(got) != 0 {
+			t.Errorf("Expected verify not to output anything but got %d files", len(got))
+		}
+		return drift
+	}
+
+	if drift := verify(images); drift.HasDrift() {
+		t.Errorf("Expected no drift for unchanged assets but got %+v", drift)
+	}
+
+	changedImage := newSolidImage(16, 16, white)
+	changedImage.Set(4, 4, color.NRGBA{255, 0, 0, 255})
+	drift := verify(map[string]image.Image{
+		"same.png":    images["same.png"],
+		"changed.png": changedImage,
+		"added.png":   newSolidImage(8, 8, white),
+	})
+	expected := &packer.Drift{
+		Added:   []string{"added.png"},
+		Removed: []string{"removed.png"},
+		Changed: []string{"changed.png"},
+	}
+	if !reflect.DeepEqual(drift, expected) {
+		t.Errorf("Expected drift %+v but got %+v", expected, drift)
+	}
+}
+
+// withoutHashes returns the source map without the content hashes, as
+// it was written before assets were hashed
+func withoutHashes(t *testing.T, sourceMap []byte) []byte {
+	var entries map[string]map[string]interface{}
+	if err := json.Unmarshal(sourceMap, &entries); err != nil {
+		t.Fatalf("Expected the source map to be valid JSON but got '%s'", err)
+	}
+	for _, entry := range entries {
+		delete(entry, "hash")
+	}
+	stripped, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatalf("Failed to encode the source map: %s", err)
+	}
+	return stripped
+}
+
+func TestVerifyComparesSourceMapsWithoutHashesByPlacement(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	images := map[string]image.Image{
+		"a.png": newSolidImage(16, 16, white),
+		"b.png": newSolidImage(8, 8, white),
+	}
+
+	outputRecorder := NewOutputRecorder()
+	params := &packer.Params{
+		Name:          "atlas",
+		Format:        target.Love,
+		Input:         NewImageStream(t, images),
+		Output:        outputRecorder,
+		Width:         64,
+		Height:        64,
+		EmitSourceMap: true,
+	}
+	if err := packer.Run(context.Background(), params); err != nil {
+		t.Fatalf("Expected run to succeed without error but got '%s'", err)
+	}
+	sourceMap := withoutHashes(t, outputRecorder.Got()["atlas.sourcemap.json"])
+
+	images["b.png"] = newSolidImage(12, 12, white)
+	params = &packer.Params{
+		Name:   "atlas",
+		Format: target.Love,
+		Input:  NewImageStream(t, images),
+		Output: NewOutputRecorder(),
+		Width:  64,
+		Height: 64,
+	}
+	drift, err := packer.Verify(context.Background(), params, bytes.NewReader(sourceMap))
+	if err != nil {
+		t.Fatalf("Expected verify to succeed without error but got '%s'", err)
+	}
+	if expected := (&packer.Drift{Changed: []string{"b.png"}}); !reflect.DeepEqual(drift, expected) {
+		t.Errorf("Expected only the resized sprite to drift %+v but got %+v", expected, drift)
+	}
+}
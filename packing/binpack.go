@@ -1,13 +1,30 @@
 package packing
 
 type BinPacker struct {
-	root *node
+	root      *node
+	heuristic Heuristic
+	splitRule SplitRule
 }
 
 // NewBinPacker returns a packer with the given width and height
 func NewBinPacker(width, height int) *BinPacker {
+	return NewBinPackerWithHeuristic(width, height, FirstFit)
+}
+
+// NewBinPackerWithHeuristic returns a packer with the given width and
+// height that chooses where to place each block using the heuristic
+func NewBinPackerWithHeuristic(width, height int, heuristic Heuristic) *BinPacker {
+	return NewBinPackerWithRules(width, height, heuristic, SplitHorizontal)
+}
+
+// NewBinPackerWithRules returns a packer with the given width and height
+// that chooses where to place each block using the heuristic and divides
+// the space left around each block by the split rule
+func NewBinPackerWithRules(width, height int, heuristic Heuristic, splitRule SplitRule) *BinPacker {
 	return &BinPacker{
-		root: &node{x: 0, y: 0, w: width, h: height},
+		root:      &node{x: 0, y: 0, w: width, h: height},
+		heuristic: heuristic,
+		splitRule: splitRule,
 	}
 }
 
@@ -27,8 +44,9 @@ func (b *BinPacker) Pack(block Block) error {
 		return ErrInputTooLarge
 	}
 
-	if n := b.findNode(b.root, bw, bh); n != nil {
-		b.splitNode(n, bw, bh)
+	if n := b.root.findBest(bw, bh, b.heuristic); n != nil {
+		n.split(bw, bh, b.splitRule)
+		b.root.mergeFree(n.right, n.down)
 		block.Place(n.x, n.y)
 	} else {
 		return ErrOutOfRoom
@@ -36,22 +54,3 @@ func (b *BinPacker) Pack(block Block) error {
 
 	return nil
 }
-
-func (b *BinPacker) findNode(root *node, w int, h int) *node {
-	if root.used {
-		if r := b.findNode(root.right, w, h); r != nil {
-			return r
-		}
-		return b.findNode(root.down, w, h)
-	} else if (w <= root.w) && (h <= root.h) {
-		return root
-	} else {
-		return nil
-	}
-}
-
-func (b *BinPacker) splitNode(n *node, w int, h int) {
-	n.used = true
-	n.right = &node{x: n.x + w, y: n.y, w: n.w - w, h: h}
-	n.down = &node{x: n.x, y: n.y + h, w: n.w, h: n.h - h}
-}
@@ -1,6 +1,7 @@
 package packing_test
 
 import (
+	"sort"
 	"testing"
 
 	. "github.com/psucodervn/lovepac/packing"
@@ -41,6 +42,26 @@ func TestBinPackingReturnsErrorIfInputBlockWillNeverFit(t *testing.T) {
 	}
 }
 
+func TestBinPackingAcceptsBlockThatExactlyFillsTheSheet(t *testing.T) {
+	packer := NewBinPacker(100, 50)
+	block := &TestBlock{id: "exact.png", w: 100, h: 50}
+	if err := packer.Pack(block); err != nil {
+		t.Fatalf("Expected a block the size of the sheet to fit but got '%v'", err)
+	}
+	if !block.placeWasCalled || block.x != 0 || block.y != 0 {
+		t.Errorf("Expected the block to be placed at {0,0} but got {%d,%d}", block.x, block.y)
+	}
+
+	if err := packer.Pack(&TestBlock{id: "1x1.png", w: 1, h: 1}); err != ErrOutOfRoom {
+		t.Errorf("Expected a full sheet to return '%v' but got '%v'", ErrOutOfRoom, err)
+	}
+	for _, block := range []*TestBlock{{id: "wide.png", w: 101, h: 50}, {id: "tall.png", w: 100, h: 51}} {
+		if err := NewBinPacker(100, 50).Pack(block); err != ErrInputTooLarge {
+			t.Errorf("Expected '%s' to return '%v' but got '%v'", block.id, ErrInputTooLarge, err)
+		}
+	}
+}
+
 func TestBinPackingReturnsErrorIfItRunsOutOfSpace(t *testing.T) {
 	blocks := []Block{
 		&TestBlock{id: "1.png", w: 200, h: 200},
@@ -68,8 +89,17 @@ func TestBinPackingStillContinuesWhenRunOutOfSpace(t *testing.T) {
 		&TestBlock{id: "3.png", w: 100, h: 50}:  nil,
 	}
 
+	// Which of the 200x200 blocks fits depends on the order they are
+	// packed in, so they are packed in the order of their ids
+	order := make([]Block, 0, len(blocks))
+	for block := range blocks {
+		order = append(order, block)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].(*TestBlock).id < order[j].(*TestBlock).id })
+
 	packer := NewBinPacker(300, 300)
-	for block, expectedErr := range blocks {
+	for _, block := range order {
+		expectedErr := blocks[block]
 		if err := packer.Pack(block); err != expectedErr {
 			t.Errorf("Expected packer.Pack of block '%s' to return '%v' but got '%v'",
 				block.(*TestBlock).id, expectedErr, err)
@@ -85,3 +115,94 @@ func TestBinPackingStillContinuesWhenRunOutOfSpace(t *testing.T) {
 		}
 	}
 }
+
+func TestBinPackingHeuristicsChooseFreeRectangle(t *testing.T) {
+	tests := []struct {
+		heuristic Heuristic
+		x, y      int
+	}{
+		{FirstFit, 30, 10},
+		{BestAreaFit, 0, 70},
+		// The space right of 'tall' and 'thin' is merged, leaving
+		// a longer side than the space below them
+		{BestShortSideFit, 0, 70},
+		{BottomLeft, 30, 10},
+	}
+
+	for _, test := range tests {
+		packer := NewBinPackerWithHeuristic(100, 100, test.heuristic)
+		blocks := []*TestBlock{
+			{id: "tall", w: 30, h: 60},
+			{id: "thin", w: 30, h: 10},
+			{id: "wide", w: 60, h: 20},
+		}
+		for _, block := range blocks {
+			if err := packer.Pack(block); err != nil {
+				t.Fatalf("Expected '%s' to fit with heuristic %d but got '%s'", block.id, test.heuristic, err)
+			}
+		}
+
+		wide := blocks[2]
+		if wide.x != test.x || wide.y != test.y {
+			t.Errorf("Expected heuristic %d to place 'wide' at %d,%d but got %d,%d",
+				test.heuristic, test.x, test.y, wide.x, wide.y)
+		}
+	}
+}
+
+func TestBinPackingSplitRulesDivideTheLeftoverSpace(t *testing.T) {
+	tests := []struct {
+		rule  SplitRule
+		first *TestBlock
+		fits  bool
+	}{
+		// A horizontal cut leaves no room as tall as the sheet
+		{SplitHorizontal, &TestBlock{w: 20, h: 60}, false},
+		{SplitVertical, &TestBlock{w: 20, h: 60}, true},
+		// More space is left to the right of the block, which stays whole
+		{SplitShorterLeftoverAxis, &TestBlock{w: 20, h: 60}, true},
+		{SplitLongerLeftoverAxis, &TestBlock{w: 20, h: 60}, false},
+		// More space is left below the block, so it is cut horizontally
+		{SplitShorterLeftoverAxis, &TestBlock{w: 60, h: 20}, false},
+	}
+
+	for _, test := range tests {
+		packer := NewBinPackerWithRules(100, 100, FirstFit, test.rule)
+		if err := packer.Pack(test.first); err != nil {
+			t.Fatalf("Expected the first block to fit with %s but got '%v'", test.rule, err)
+		}
+		tall := &TestBlock{id: "tall.png", w: 30, h: 100}
+		err := packer.Pack(tall)
+		if test.fits && (err != nil || tall.x != test.first.w || tall.y != 0) {
+			t.Errorf("Expected a full height block to be placed at {%d,0} after a %dx%d block with %s but got {%d,%d} and '%v'",
+				test.first.w, test.first.w, test.first.h, test.rule, tall.x, tall.y, err)
+		}
+		if !test.fits && err != ErrOutOfRoom {
+			t.Errorf("Expected no room for a full height block after a %dx%d block with %s but got '%v'",
+				test.first.w, test.first.h, test.rule, err)
+		}
+	}
+}
+
+func TestBinPackingMergesFreeRectanglesSharingAnEdge(t *testing.T) {
+	packer := NewBinPacker(100, 100)
+	blocks := []*TestBlock{
+		{id: "top", w: 50, h: 40},
+		// Too tall for the space right of 'top', so it is placed below
+		{id: "bottom", w: 50, h: 60},
+		// Only fits once the space right of both blocks is merged
+		{id: "column", w: 50, h: 100},
+	}
+	for _, block := range blocks {
+		if err := packer.Pack(block); err != nil {
+			t.Fatalf("Expected '%s' to fit but got '%s'", block.id, err)
+		}
+	}
+
+	expected := [][2]int{{0, 0}, {0, 40}, {50, 0}}
+	for i, block := range blocks {
+		if block.x != expected[i][0] || block.y != expected[i][1] {
+			t.Errorf("Expected '%s' to be placed at %v but got %d,%d", block.id, expected[i], block.x, block.y)
+		}
+	}
+}
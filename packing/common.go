@@ -35,3 +35,99 @@ type node struct {
 	right *node
 	down  *node
 }
+
+// find returns the first unused node in the tree that
+// can fit the given width and height, or nil
+func (n *node) find(w int, h int) *node {
+	if n.used {
+		if r := n.right.find(w, h); r != nil {
+			return r
+		}
+		return n.down.find(w, h)
+	} else if (w <= n.w) && (h <= n.h) {
+		return n
+	} else {
+		return nil
+	}
+}
+
+// split marks the node as used by a w x h block and divides
+// the remaining space into right and down nodes by the rule
+func (n *node) split(w int, h int, rule SplitRule) {
+	n.used = true
+	if rule.horizontal(n, w, h) {
+		n.right = &node{x: n.x + w, y: n.y, w: n.w - w, h: h}
+		n.down = &node{x: n.x, y: n.y + h, w: n.w, h: n.h - h}
+		return
+	}
+	n.right = &node{x: n.x + w, y: n.y, w: n.w - w, h: n.h}
+	n.down = &node{x: n.x, y: n.y + h, w: w, h: n.h - h}
+}
+
+// mergeFree joins the changed free nodes with any unused node that they
+// share a whole edge with, as splitting leaves space that would fit a
+// block in two nodes too small for it. The node found first in the tree
+// grows to cover both and the other is emptied, so that no two nodes
+// overlap. Grown nodes are merged again until no more can be joined.
+func (n *node) mergeFree(changed ...*node) {
+	for len(changed) > 0 {
+		a := changed[len(changed)-1]
+		changed = changed[:len(changed)-1]
+		if a.used || a.w == 0 || a.h == 0 {
+			continue
+		}
+		var first, second *node
+		n.walkFree(func(b *node) {
+			if first != nil || b == a || !a.adjoins(b) {
+				return
+			}
+			first, second = b, a
+			if a.before(b, n) {
+				first, second = a, b
+			}
+		})
+		if first == nil {
+			continue
+		}
+		x, y := min(first.x, second.x), min(first.y, second.y)
+		if first.x == second.x {
+			first.h += second.h
+		} else {
+			first.w += second.w
+		}
+		first.x, first.y = x, y
+		second.w, second.h = 0, 0
+		changed = append(changed, first)
+	}
+}
+
+// adjoins returns true if the nodes share a whole edge, so
+// that together they cover a single rectangle
+func (n *node) adjoins(o *node) bool {
+	if o.w == 0 || o.h == 0 {
+		return false
+	}
+	if n.x == o.x && n.w == o.w {
+		return n.y+n.h == o.y || o.y+o.h == n.y
+	}
+	if n.y == o.y && n.h == o.h {
+		return n.x+n.w == o.x || o.x+o.w == n.x
+	}
+	return false
+}
+
+// before returns true if n is found before o when searching the tree
+func (n *node) before(o *node, root *node) bool {
+	found := false
+	isBefore := false
+	root.walkFree(func(candidate *node) {
+		if found {
+			return
+		}
+		if candidate == n || candidate == o {
+			found = true
+			isBefore = candidate == n
+		}
+	})
+	return isBefore
+}
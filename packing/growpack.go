@@ -1,5 +1,127 @@
 package packing
 
+import "math"
+
+// GrowingPacker packs blocks into a space that starts at the size of
+// the first block and grows right or down as needed, up to a maximum
+// width and height. It grows in whichever direction keeps the space
+// closest to square, so blocks should be sorted by their maximum side
+// (see ByMaxSide) for the best results.
 type GrowingPacker struct {
-	root *node
+	root           *node
+	maxW, maxH     int
+	maxAspectRatio float64
+}
+
+// NewGrowingPacker returns a packer that grows up to the given maximum
+// width and height. A maxAspectRatio greater than 0 limits growth that
+// would make the longer side more than maxAspectRatio times the shorter
+// side; growth in the shorter dimension is always allowed.
+func NewGrowingPacker(maxWidth, maxHeight int, maxAspectRatio float64) *GrowingPacker {
+	return &GrowingPacker{
+		maxW:           maxWidth,
+		maxH:           maxHeight,
+		maxAspectRatio: maxAspectRatio,
+	}
+}
+
+// Size returns the current width and height of the packed space
+func (g *GrowingPacker) Size() (int, int) {
+	if g.root == nil {
+		return 0, 0
+	}
+	return g.root.w, g.root.h
+}
+
+// Pack implements the Packer interface
+func (g *GrowingPacker) Pack(block Block) error {
+	bw, bh := block.Size()
+	if bw > g.maxW || bh > g.maxH {
+		return ErrInputTooLarge
+	}
+
+	if g.root == nil {
+		g.root = &node{x: 0, y: 0, w: bw, h: bh}
+	}
+
+	n := g.root.find(bw, bh)
+	if n == nil {
+		n = g.grow(bw, bh)
+	}
+	if n == nil {
+		return ErrOutOfRoom
+	}
+
+	n.split(bw, bh, SplitHorizontal)
+	block.Place(n.x, n.y)
+	return nil
+}
+
+// grow enlarges the root to make room for a w x h block
+// returning the node the block should be placed in, or nil
+func (g *GrowingPacker) grow(w, h int) *node {
+	rw, rh := g.root.w, g.root.h
+	canGrowRight := h <= rh && rw+w <= g.maxW && g.allowed(rw, rh, rw+w, rh)
+	canGrowDown := w <= rw && rh+h <= g.maxH && g.allowed(rw, rh, rw, rh+h)
+
+	// Prefer growth that keeps the space square
+	shouldGrowRight := canGrowRight && rh >= rw+w
+	shouldGrowDown := canGrowDown && rw >= rh+h
+
+	switch {
+	case shouldGrowRight:
+		return g.growRight(w, h)
+	case shouldGrowDown:
+		return g.growDown(w, h)
+	case canGrowRight:
+		return g.growRight(w, h)
+	case canGrowDown:
+		return g.growDown(w, h)
+	}
+	return nil
+}
+
+// allowed reports whether growing from w x h to nw x nh respects the
+// maximum aspect ratio. Growth that reduces the ratio is always allowed.
+func (g *GrowingPacker) allowed(w, h, nw, nh int) bool {
+	if g.maxAspectRatio <= 0 {
+		return true
+	}
+	ratio := aspectRatio(nw, nh)
+	return ratio <= g.maxAspectRatio || ratio < aspectRatio(w, h)
+}
+
+func (g *GrowingPacker) growRight(w, h int) *node {
+	g.root = &node{
+		used:  true,
+		x:     0,
+		y:     0,
+		w:     g.root.w + w,
+		h:     g.root.h,
+		down:  g.root,
+		right: &node{x: g.root.w, y: 0, w: w, h: g.root.h},
+	}
+	return g.root.find(w, h)
+}
+
+func (g *GrowingPacker) growDown(w, h int) *node {
+	g.root = &node{
+		used:  true,
+		x:     0,
+		y:     0,
+		w:     g.root.w,
+		h:     g.root.h + h,
+		down:  &node{x: 0, y: g.root.h, w: g.root.w, h: h},
+		right: g.root,
+	}
+	return g.root.find(w, h)
+}
+
+// aspectRatio returns the ratio of the longer side to the shorter side
+func aspectRatio(w, h int) float64 {
+	long, short := math.Max(float64(w), float64(h)), math.Min(float64(w), float64(h))
+	if short == 0 {
+		return math.Inf(1)
+	}
+	return long / short
 }
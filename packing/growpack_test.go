@@ -0,0 +1,106 @@
+package packing_test
+
+import (
+	"testing"
+
+	. "github.com/psucodervn/lovepac/packing"
+)
+
+func TestGrowingPackerGrowsToFitBlocks(t *testing.T) {
+	blocks := []Block{
+		&TestBlock{id: "1.png", w: 100, h: 100},
+		&TestBlock{id: "2.png", w: 100, h: 100},
+		&TestBlock{id: "3.png", w: 100, h: 100},
+		&TestBlock{id: "4.png", w: 100, h: 100},
+	}
+
+	packer := NewGrowingPacker(1000, 1000, 0)
+	for _, block := range blocks {
+		if err := packer.Pack(block); err != nil {
+			t.Errorf("Expected that packer.Pack would not return an error but got %s", err.Error())
+		}
+	}
+
+	if w, h := packer.Size(); w != 200 || h != 200 {
+		t.Errorf("Expected packer to grow to 200x200 but got %dx%d", w, h)
+	}
+
+	for _, block := range blocks {
+		if !block.(*TestBlock).placeWasCalled {
+			t.Errorf("Block (%s) did not receive a result node", block.(*TestBlock).id)
+		}
+	}
+}
+
+func TestGrowingPackerReturnsErrorIfInputBlockWillNeverFit(t *testing.T) {
+	packer := NewGrowingPacker(100, 100, 0)
+	err := packer.Pack(&TestBlock{id: "doesnotfit.png", w: 200, h: 200})
+
+	expected := ErrInputTooLarge
+	if err != expected {
+		t.Errorf("Expected packer.Pack to return '%v' but got '%v'", expected, err)
+	}
+}
+
+func TestGrowingPackerAcceptsBlockOfExactlyTheMaximumSize(t *testing.T) {
+	packer := NewGrowingPacker(100, 50, 0)
+	if err := packer.Pack(&TestBlock{id: "exact.png", w: 100, h: 50}); err != nil {
+		t.Fatalf("Expected a block of the maximum size to fit but got '%v'", err)
+	}
+	if w, h := packer.Size(); w != 100 || h != 50 {
+		t.Errorf("Expected the packer to be 100x50 but got %dx%d", w, h)
+	}
+	if err := packer.Pack(&TestBlock{id: "1x1.png", w: 1, h: 1}); err != ErrOutOfRoom {
+		t.Errorf("Expected a full packer to return '%v' but got '%v'", ErrOutOfRoom, err)
+	}
+}
+
+func TestGrowingPackerReturnsErrorWhenItCanNoLongerGrow(t *testing.T) {
+	packer := NewGrowingPacker(150, 150, 0)
+	err1 := packer.Pack(&TestBlock{id: "1.png", w: 100, h: 100})
+	err2 := packer.Pack(&TestBlock{id: "2.png", w: 100, h: 100})
+
+	if err1 != nil {
+		t.Errorf("Expected packer.Pack of '1.png' to fit but got '%v'", err1)
+	}
+	if err2 != ErrOutOfRoom {
+		t.Errorf("Expected packer.Pack of '2.png' to return '%v' but got '%v'", ErrOutOfRoom, err2)
+	}
+}
+
+func TestGrowingPackerRespectsMaxAspectRatio(t *testing.T) {
+	// The maximum height prevents growing down, so without a
+	// constraint the space becomes long and thin as it grows right
+	newBlocks := func() []Block {
+		var blocks []Block
+		for i := 0; i < 5; i++ {
+			blocks = append(blocks, &TestBlock{id: "square.png", w: 100, h: 100})
+		}
+		return blocks
+	}
+
+	unconstrained := NewGrowingPacker(1000, 100, 0)
+	for _, block := range newBlocks() {
+		if err := unconstrained.Pack(block); err != nil {
+			t.Errorf("Expected that unconstrained.Pack would not return an error but got %s", err.Error())
+		}
+	}
+	if w, h := unconstrained.Size(); w != 500 || h != 100 {
+		t.Errorf("Expected unconstrained packer to be 500x100 but got %dx%d", w, h)
+	}
+
+	constrained := NewGrowingPacker(1000, 100, 2)
+	blocks := newBlocks()
+	for i, block := range blocks {
+		expected := error(nil)
+		if i >= 2 {
+			expected = ErrOutOfRoom
+		}
+		if err := constrained.Pack(block); err != expected {
+			t.Errorf("Expected constrained.Pack of block %d to return '%v' but got '%v'", i, expected, err)
+		}
+	}
+	if w, h := constrained.Size(); w != 200 || h != 100 {
+		t.Errorf("Expected constrained packer to be 200x100 but got %dx%d", w, h)
+	}
+}
@@ -0,0 +1,101 @@
+package packing
+
+import "fmt"
+
+// Heuristic selects which free rectangle of a BinPacker
+// a block is placed into when more than one can fit it.
+//
+// The sort order of the blocks decides which block is placed
+// next, the heuristic decides where it goes. Sorting the largest
+// blocks first leaves small blocks to fill the gaps, and a best fit
+// heuristic keeps those gaps as small and as usable as possible.
+type Heuristic int
+
+const (
+	// FirstFit places the block in the first free rectangle found
+	// in the packing tree that fits, favouring the right of earlier
+	// blocks over the space below. This is the default.
+	FirstFit Heuristic = iota
+	// BestAreaFit places the block in the free rectangle that
+	// leaves the least area unused.
+	BestAreaFit
+	// BestShortSideFit places the block in the free rectangle whose
+	// shorter leftover side is smallest, leaving long thin strips
+	// rather than awkward gaps.
+	BestShortSideFit
+	// BottomLeft places the block in the free rectangle closest to
+	// the origin row, breaking ties by the leftmost, which packs blocks
+	// into tight rows. The name comes from the classic bottom-left rule,
+	// atlas coordinates start at the top left so rows fill from the top.
+	BottomLeft
+)
+
+// score returns how well a w x h block fits the node, lower is better
+func (h Heuristic) score(n *node, w, hgt int) (int, int) {
+	switch h {
+	case BestAreaFit:
+		return n.w*n.h - w*hgt, min(n.w-w, n.h-hgt)
+	case BestShortSideFit:
+		shortSide, longSide := n.w-w, n.h-hgt
+		if shortSide > longSide {
+			shortSide, longSide = longSide, shortSide
+		}
+		return shortSide, longSide
+	case BottomLeft:
+		return n.y, n.x
+	}
+	return 0, 0
+}
+
+// findBest returns the unused node in the tree that best fits
+// the given width and height according to the heuristic, or nil
+func (n *node) findBest(w, h int, heuristic Heuristic) *node {
+	if heuristic == FirstFit {
+		return n.find(w, h)
+	}
+
+	var best *node
+	var bestPrimary, bestSecondary int
+	n.walkFree(func(candidate *node) {
+		if w > candidate.w || h > candidate.h {
+			return
+		}
+		primary, secondary := heuristic.score(candidate, w, h)
+		if best == nil || primary < bestPrimary || (primary == bestPrimary && secondary < bestSecondary) {
+			best, bestPrimary, bestSecondary = candidate, primary, secondary
+		}
+	})
+	return best
+}
+
+// walkFree calls fn for every unused node in the tree, in
+// the same order that find searches them
+func (n *node) walkFree(fn func(*node)) {
+	if n.used {
+		n.right.walkFree(fn)
+		n.down.walkFree(fn)
+		return
+	}
+	fn(n)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (h Heuristic) String() string {
+	switch h {
+	case FirstFit:
+		return "first-fit"
+	case BestAreaFit:
+		return "best-area-fit"
+	case BestShortSideFit:
+		return "best-short-side-fit"
+	case BottomLeft:
+		return "bottom-left"
+	}
+	return fmt.Sprintf("Heuristic(%d)", int(h))
+}
@@ -0,0 +1,66 @@
+package packing
+
+import (
+	"container/heap"
+	"image"
+	"sort"
+)
+
+// FindOverlap returns the indices of two of the rectangles that overlap,
+// ok is false if none do. Rectangles that only share an edge do not
+// overlap and empty rectangles are ignored. It is a self-check for the
+// placements of packers, eg. while developing a new Heuristic.
+//
+// The rectangles are swept from left to right. The rectangles that the
+// sweep is inside of can't overlap each other until an overlap is found,
+// so they are kept sorted by their top edge and each rectangle is only
+// compared with the one it would be inserted next to.
+func FindOverlap(rects []image.Rectangle) (i, j int, ok bool) {
+	order := make([]int, 0, len(rects))
+	for k, r := range rects {
+		if !r.Empty() {
+			order = append(order, k)
+		}
+	}
+	sort.Slice(order, func(a, b int) bool { return rects[order[a]].Min.X < rects[order[b]].Min.X })
+
+	// active are the rectangles the sweep is inside of, by top edge
+	var active []int
+	ends := &rightEdges{rects: rects}
+	for _, k := range order {
+		r := rects[k]
+		for ends.Len() > 0 && rects[ends.indices[0]].Max.X <= r.Min.X {
+			done := rects[heap.Pop(ends).(int)]
+			at := sort.Search(len(active), func(a int) bool { return rects[active[a]].Min.Y >= done.Min.Y })
+			active = append(active[:at], active[at+1:]...)
+		}
+
+		at := sort.Search(len(active), func(a int) bool { return rects[active[a]].Max.Y > r.Min.Y })
+		if at < len(active) && rects[active[at]].Min.Y < r.Max.Y {
+			return active[at], k, true
+		}
+		active = append(active, 0)
+		copy(active[at+1:], active[at:])
+		active[at] = k
+		heap.Push(ends, k)
+	}
+	return 0, 0, false
+}
+
+// rightEdges is a heap of the indices of rectangles by their right edge
+type rightEdges struct {
+	rects   []image.Rectangle
+	indices []int
+}
+
+func (e *rightEdges) Len() int { return len(e.indices) }
+func (e *rightEdges) Less(a, b int) bool {
+	return e.rects[e.indices[a]].Max.X < e.rects[e.indices[b]].Max.X
+}
+func (e *rightEdges) Swap(a, b int)      { e.indices[a], e.indices[b] = e.indices[b], e.indices[a] }
+func (e *rightEdges) Push(x interface{}) { e.indices = append(e.indices, x.(int)) }
+func (e *rightEdges) Pop() interface{} {
+	last := e.indices[len(e.indices)-1]
+	e.indices = e.indices[:len(e.indices)-1]
+	return last
+}
@@ -0,0 +1,43 @@
+package packing_test
+
+import (
+	"image"
+	"testing"
+
+	. "github.com/psucodervn/lovepac/packing"
+)
+
+func TestFindOverlapFindsOverlappingRects(t *testing.T) {
+	rects := []image.Rectangle{
+		image.Rect(0, 0, 10, 10),
+		image.Rect(20, 0, 30, 10),
+		image.Rect(0, 20, 10, 30),
+		image.Rect(25, 5, 35, 15),
+	}
+	i, j, ok := FindOverlap(rects)
+	if !ok {
+		t.Fatalf("Expected an overlap to be found")
+	}
+	if (i != 1 || j != 3) && (i != 3 || j != 1) {
+		t.Errorf("Expected rects 1 and 3 to overlap but got %d and %d", i, j)
+	}
+}
+
+func TestFindOverlapIgnoresSharedEdges(t *testing.T) {
+	var rects []image.Rectangle
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 50; x++ {
+			rects = append(rects, image.Rect(x*4, y*3, x*4+4, y*3+3))
+		}
+	}
+	// Empty rects are ignored wherever they are
+	rects = append(rects, image.Rect(5, 5, 5, 5))
+	if i, j, ok := FindOverlap(rects); ok {
+		t.Errorf("Expected no overlap in the grid but got %v and %v", rects[i], rects[j])
+	}
+
+	rects = append(rects, image.Rect(199, 149, 201, 151))
+	if _, j, ok := FindOverlap(rects); !ok || j != len(rects)-1 {
+		t.Errorf("Expected the rect over the corner of the grid to overlap")
+	}
+}
@@ -0,0 +1,124 @@
+package packing
+
+// RectPacker is a packer with a fixed width and height in which areas
+// can be reserved at given positions before packing the other blocks
+// around them. It tracks every maximal free rectangle, so unlike the
+// BinPacker a block fits anywhere that there is room left for it.
+type RectPacker struct {
+	w, h      int
+	free      []*node
+	heuristic Heuristic
+}
+
+// NewRectPacker returns a packer with the given width and height
+// that chooses where to place each block using the heuristic. The
+// free rectangles are not ordered as a tree is, so FirstFit behaves
+// as BottomLeft.
+func NewRectPacker(width, height int, heuristic Heuristic) *RectPacker {
+	if heuristic == FirstFit {
+		heuristic = BottomLeft
+	}
+	return &RectPacker{
+		w:         width,
+		h:         height,
+		free:      []*node{{x: 0, y: 0, w: width, h: height}},
+		heuristic: heuristic,
+	}
+}
+
+// Size returns the width and height of the RectPacker
+func (r *RectPacker) Size() (int, int) { return r.w, r.h }
+
+// Reserve marks the w x h area at x, y as used so that no block is
+// packed into it. ErrOutOfRoom is returned if any of the area is
+// outside the packer or was already used, leaving the packer unchanged.
+func (r *RectPacker) Reserve(x, y, w, h int) error {
+	area := &node{x: x, y: y, w: w, h: h}
+	for _, free := range r.free {
+		if free.contains(area) {
+			r.use(area)
+			return nil
+		}
+	}
+	return ErrOutOfRoom
+}
+
+// Pack implements the Packer interface
+func (r *RectPacker) Pack(block Block) error {
+	bw, bh := block.Size()
+	if bw > r.w || bh > r.h {
+		return ErrInputTooLarge
+	}
+
+	var best *node
+	var bestPrimary, bestSecondary int
+	for _, free := range r.free {
+		if bw > free.w || bh > free.h {
+			continue
+		}
+		primary, secondary := r.heuristic.score(free, bw, bh)
+		if best == nil || primary < bestPrimary || (primary == bestPrimary && secondary < bestSecondary) {
+			best, bestPrimary, bestSecondary = free, primary, secondary
+		}
+	}
+	if best == nil {
+		return ErrOutOfRoom
+	}
+
+	x, y := best.x, best.y
+	r.use(&node{x: x, y: y, w: bw, h: bh})
+	block.Place(x, y)
+	return nil
+}
+
+// use splits every free rectangle overlapping the used area into the
+// up to four rectangles around it, then drops the rectangles that are
+// contained by another so that only the maximal free rectangles remain
+func (r *RectPacker) use(used *node) {
+	var free []*node
+	for _, f := range r.free {
+		if !f.overlaps(used) {
+			free = append(free, f)
+			continue
+		}
+		if used.x > f.x {
+			free = append(free, &node{x: f.x, y: f.y, w: used.x - f.x, h: f.h})
+		}
+		if right := used.x + used.w; right < f.x+f.w {
+			free = append(free, &node{x: right, y: f.y, w: f.x + f.w - right, h: f.h})
+		}
+		if used.y > f.y {
+			free = append(free, &node{x: f.x, y: f.y, w: f.w, h: used.y - f.y})
+		}
+		if bottom := used.y + used.h; bottom < f.y+f.h {
+			free = append(free, &node{x: f.x, y: bottom, w: f.w, h: f.y + f.h - bottom})
+		}
+	}
+
+	r.free = r.free[:0]
+	for i, f := range free {
+		contained := false
+		for j, other := range free {
+			// Of two equal rectangles only the first is kept
+			if i != j && other.contains(f) && (!f.contains(other) || j < i) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			r.free = append(r.free, f)
+		}
+	}
+}
+
+// contains returns true if the other rectangle lies entirely within n
+func (n *node) contains(other *node) bool {
+	return other.x >= n.x && other.y >= n.y &&
+		other.x+other.w <= n.x+n.w && other.y+other.h <= n.y+n.h
+}
+
+// overlaps returns true if the rectangles share any area
+func (n *node) overlaps(other *node) bool {
+	return other.x < n.x+n.w && n.x < other.x+other.w &&
+		other.y < n.y+n.h && n.y < other.y+other.h
+}
@@ -0,0 +1,61 @@
+package packing_test
+
+import (
+	"testing"
+
+	. "github.com/psucodervn/lovepac/packing"
+)
+
+func TestRectPackerPacksAroundReservedAreas(t *testing.T) {
+	packer := NewRectPacker(100, 100, FirstFit)
+	// Reserve the centre, leaving a 25 pixel border all around it
+	if err := packer.Reserve(25, 25, 50, 50); err != nil {
+		t.Fatalf("Expected reserving a free area to succeed but got '%s'", err)
+	}
+
+	blocks := []*TestBlock{
+		{id: "top", w: 100, h: 25},
+		{id: "bottom", w: 100, h: 25},
+		{id: "left", w: 25, h: 50},
+		{id: "right", w: 25, h: 50},
+	}
+	for _, block := range blocks {
+		if err := packer.Pack(block); err != nil {
+			t.Fatalf("Expected block (%s) to fit around the reserved area but got '%s'", block.id, err)
+		}
+		if block.x < 75 && block.x+block.w > 25 && block.y < 75 && block.y+block.h > 25 {
+			t.Errorf("Expected block (%s) not to overlap the reserved area but it was placed at {%d,%d}", block.id, block.x, block.y)
+		}
+	}
+
+	if err := packer.Pack(&TestBlock{id: "full", w: 1, h: 1}); err != ErrOutOfRoom {
+		t.Errorf("Expected a full packer to return '%v' but got '%v'", ErrOutOfRoom, err)
+	}
+}
+
+func TestRectPackerRejectsReservingUsedArea(t *testing.T) {
+	tests := []struct {
+		x, y, w, h int
+	}{
+		// Overlapping the first reserved area
+		{40, 40, 20, 20},
+		// Outside the packer
+		{90, 90, 20, 20},
+		{-1, 0, 10, 10},
+	}
+
+	for _, test := range tests {
+		packer := NewRectPacker(100, 100, FirstFit)
+		if err := packer.Reserve(0, 0, 50, 50); err != nil {
+			t.Fatalf("Expected reserving a free area to succeed but got '%s'", err)
+		}
+		if err := packer.Reserve(test.x, test.y, test.w, test.h); err != ErrOutOfRoom {
+			t.Errorf("Expected reserving %dx%d at {%d,%d} to return '%v' but got '%v'",
+				test.w, test.h, test.x, test.y, ErrOutOfRoom, err)
+		}
+		// Reserving an adjacent area is still possible
+		if err := packer.Reserve(50, 0, 50, 50); err != nil {
+			t.Errorf("Expected the failed reservation to leave the packer unchanged but got '%s'", err)
+		}
+	}
+}
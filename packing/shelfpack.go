@@ -0,0 +1,115 @@
+package packing
+
+import "fmt"
+
+// FillDirection selects the order that the space of an atlas is filled
+// in. Filling in a fixed direction gives predictable layouts that are
+// easy to scan, at some cost in density.
+type FillDirection int
+
+const (
+	// FillFree does not fill in a fixed direction, blocks are placed
+	// wherever the heuristic of the packer chooses. This is the default.
+	FillFree FillDirection = iota
+	// FillRows fills rows from left to right, from the top down
+	FillRows
+	// FillColumns fills columns from top to bottom, from the left
+	FillColumns
+)
+
+func (d FillDirection) String() string {
+	switch d {
+	case FillFree:
+		return "free"
+	case FillRows:
+		return "rows"
+	case FillColumns:
+		return "columns"
+	}
+	return fmt.Sprintf("FillDirection(%d)", int(d))
+}
+
+// ShelfPacker places blocks side by side on shelves, rows or columns as
+// tall or wide as their largest block, in the fill direction. Each block
+// is placed on the first shelf with room for it, and a new shelf is
+// started after the last one when none has. Blocks sorted tallest first
+// for rows, or widest first for columns, waste the least space.
+type ShelfPacker struct {
+	// w is the length of the shelves and h the space they are stacked
+	// in, the width and height of the packer when filling rows
+	w, h    int
+	columns bool
+	shelves []shelf
+}
+
+// shelf is a row of blocks at offset with the depth of its largest
+// block, the next block is placed at used along the row
+type shelf struct {
+	offset, depth, used int
+}
+
+// NewShelfPacker returns a packer with the given width and height that
+// fills shelves in the direction, FillFree fills rows
+func NewShelfPacker(width, height int, direction FillDirection) *ShelfPacker {
+	if direction == FillColumns {
+		return &ShelfPacker{w: height, h: width, columns: true}
+	}
+	return &ShelfPacker{w: width, h: height}
+}
+
+// Size returns the width and height of the ShelfPacker
+func (s *ShelfPacker) Size() (int, int) {
+	if s.columns {
+		return s.h, s.w
+	}
+	return s.w, s.h
+}
+
+// Pack implements the Packer interface
+func (s *ShelfPacker) Pack(block Block) error {
+	bw, bh := block.Size()
+	if s.columns {
+		bw, bh = bh, bw
+	}
+	if bw > s.w || bh > s.h {
+		return ErrInputTooLarge
+	}
+
+	for i := range s.shelves {
+		sh := &s.shelves[i]
+		if sh.used+bw > s.w {
+			continue
+		}
+		// Only the last shelf can grow deeper, into the space after it
+		last := i == len(s.shelves)-1
+		if bh > sh.depth && (!last || sh.offset+bh > s.h) {
+			continue
+		}
+		if bh > sh.depth {
+			sh.depth = bh
+		}
+		s.place(block, sh.used, sh.offset)
+		sh.used += bw
+		return nil
+	}
+
+	offset := 0
+	if n := len(s.shelves); n > 0 {
+		offset = s.shelves[n-1].offset + s.shelves[n-1].depth
+	}
+	if offset+bh > s.h {
+		return ErrOutOfRoom
+	}
+	s.shelves = append(s.shelves, shelf{offset: offset, depth: bh, used: bw})
+	s.place(block, 0, offset)
+	return nil
+}
+
+// place places the block at the position along and across the shelves
+func (s *ShelfPacker) place(block Block, along, across int) {
+	if s.columns {
+		block.Place(across, along)
+		return
+	}
+	block.Place(along, across)
+}
@@ -0,0 +1,60 @@
+package packing_test
+
+import (
+	"testing"
+
+	. "github.com/psucodervn/lovepac/packing"
+)
+
+func TestShelfPackerFillsInTheDirection(t *testing.T) {
+	type position struct{ x, y int }
+	for _, test := range []struct {
+		direction FillDirection
+		expected  []position
+	}{
+		{FillRows, []position{{0, 0}, {30, 0}, {60, 0}, {0, 30}, {30, 30}}},
+		{FillColumns, []position{{0, 0}, {0, 30}, {0, 60}, {30, 0}, {30, 30}}},
+	} {
+		blocks := make([]*TestBlock, len(test.expected))
+		packer := NewShelfPacker(100, 100, test.direction)
+		for i := range blocks {
+			blocks[i] = &TestBlock{w: 30, h: 30}
+			if err := packer.Pack(blocks[i]); err != nil {
+				t.Fatalf("Expected that packer.Pack would not return an error but got %s", err.Error())
+			}
+		}
+		for i, block := range blocks {
+			if block.x != test.expected[i].x || block.y != test.expected[i].y {
+				t.Errorf("Expected block %d filling %s to be placed at %v but got {%d %d}",
+					i, test.direction, test.expected[i], block.x, block.y)
+			}
+		}
+	}
+}
+
+func TestShelfPackerPlacesBlocksOnTheFirstShelfWithRoom(t *testing.T) {
+	packer := NewShelfPacker(100, 100, FillRows)
+	blocks := []*TestBlock{
+		{id: "tall", w: 60, h: 50},
+		{id: "wide", w: 70, h: 20},
+		{id: "small", w: 40, h: 30},
+		// Too tall for the first shelf, extends the open second shelf
+		{id: "deeper", w: 20, h: 40},
+	}
+	for _, block := range blocks {
+		if err := packer.Pack(block); err != nil {
+			t.Fatalf("Expected that packer.Pack would not return an error but got %s", err.Error())
+		}
+	}
+
+	expected := map[string][2]int{"tall": {0, 0}, "wide": {0, 50}, "small": {60, 0}, "deeper": {70, 50}}
+	for _, block := range blocks {
+		if at := expected[block.id]; block.x != at[0] || block.y != at[1] {
+			t.Errorf("Expected block (%s) to be placed at %v but got {%d %d}", block.id, at, block.x, block.y)
+		}
+	}
+
+	if err := packer.Pack(&TestBlock{w: 20, h: 20}); err != ErrOutOfRoom {
+		t.Errorf("Expected ErrOutOfRoom once the shelves fill the packer but got %v", err)
+	}
+}
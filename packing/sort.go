@@ -28,3 +28,27 @@ func (a ByMaxSide) Less(i, j int) bool {
 	wj, hj := a[j].Size()
 	return math.Max(float64(wi), float64(hi)) > math.Max(float64(wj), float64(hj))
 }
+
+// ByWidth implements sort interface for []Block
+// by comparing the width of each block
+type ByWidth []Block
+
+func (a ByWidth) Len() int      { return len(a) }
+func (a ByWidth) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByWidth) Less(i, j int) bool {
+	wi, _ := a[i].Size()
+	wj, _ := a[j].Size()
+	return wi > wj
+}
+
+// ByHeight implements sort interface for []Block
+// by comparing the height of each block
+type ByHeight []Block
+
+func (a ByHeight) Len() int      { return len(a) }
+func (a ByHeight) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByHeight) Less(i, j int) bool {
+	_, hi := a[i].Size()
+	_, hj := a[j].Size()
+	return hi > hj
+}
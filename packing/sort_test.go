@@ -44,3 +44,29 @@ func TestSortByMaxSide(t *testing.T) {
 		}
 	}
 }
+
+func TestSortByWidth(t *testing.T) {
+	expected := []string{"5", "1", "2", "3", "4"}
+
+	sort.Stable(ByWidth(blocks))
+
+	for i := range blocks {
+		got := blocks[i].(*TestBlock)
+		if got.id != expected[i] {
+			t.Errorf("Expected '%s' at index %d, got '%s'", expected[i], i, got.id)
+		}
+	}
+}
+
+func TestSortByHeight(t *testing.T) {
+	expected := []string{"4", "5", "1", "2", "3"}
+
+	sort.Stable(ByHeight(blocks))
+
+	for i := range blocks {
+		got := blocks[i].(*TestBlock)
+		if got.id != expected[i] {
+			t.Errorf("Expected '%s' at index %d, got '%s'", expected[i], i, got.id)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package packing
+
+import "fmt"
+
+// SplitRule selects how a BinPacker divides the free space left in a
+// rectangle once a block is placed in its top left corner. The leftover
+// L shape is cut into a rectangle right of the block and one below it,
+// either horizontally, along the bottom of the block so that the space
+// below spans the whole width, or vertically, along the right of the
+// block so that the space to the right spans the whole height.
+//
+// Cutting so that the larger leftover stays whole avoids slivers too
+// thin for any other block, which matters most for sprites of mixed
+// aspect ratios.
+type SplitRule int
+
+const (
+	// SplitHorizontal always cuts along the bottom of the block, which
+	// favours filling rows. This is the default.
+	SplitHorizontal SplitRule = iota
+	// SplitVertical always cuts along the right of the block, which
+	// favours filling columns.
+	SplitVertical
+	// SplitShorterLeftoverAxis cuts horizontally when there is at least
+	// as much space left below the block as to its right, so the longer
+	// leftover stays whole.
+	SplitShorterLeftoverAxis
+	// SplitLongerLeftoverAxis cuts horizontally when there is more space
+	// left to the right of the block than below it.
+	SplitLongerLeftoverAxis
+	// SplitMinimizeArea cuts so that one leftover rectangle is as large
+	// as possible at the expense of the other.
+	SplitMinimizeArea
+	// SplitMaximizeArea cuts so that the two leftover rectangles are as
+	// close in area as possible.
+	SplitMaximizeArea
+	// SplitShorterAxis cuts horizontally when the free rectangle is at
+	// most as wide as it is tall and vertically otherwise.
+	SplitShorterAxis
+	// SplitLongerAxis cuts horizontally when the free rectangle is
+	// wider than it is tall and vertically otherwise.
+	SplitLongerAxis
+)
+
+// horizontal returns true if the free rectangle that a w x h block is
+// placed in should be cut along the bottom of the block
+func (r SplitRule) horizontal(n *node, w, h int) bool {
+	leftoverW, leftoverH := n.w-w, n.h-h
+	switch r {
+	case SplitVertical:
+		return false
+	case SplitShorterLeftoverAxis:
+		return leftoverW <= leftoverH
+	case SplitLongerLeftoverAxis:
+		return leftoverW > leftoverH
+	case SplitMinimizeArea:
+		return w*leftoverH > leftoverW*h
+	case SplitMaximizeArea:
+		return w*leftoverH <= leftoverW*h
+	case SplitShorterAxis:
+		return n.w <= n.h
+	case SplitLongerAxis:
+		return n.w > n.h
+	}
+	return true
+}
+
+func (r SplitRule) String() string {
+	switch r {
+	case SplitHorizontal:
+		return "horizontal"
+	case SplitVertical:
+		return "vertical"
+	case SplitShorterLeftoverAxis:
+		return "shorter-leftover-axis"
+	case SplitLongerLeftoverAxis:
+		return "longer-leftover-axis"
+	case SplitMinimizeArea:
+		return "minimize-area"
+	case SplitMaximizeArea:
+		return "maximize-area"
+	case SplitShorterAxis:
+		return "shorter-axis"
+	case SplitLongerAxis:
+		return "longer-axis"
+	}
+	return fmt.Sprintf("SplitRule(%d)", int(r))
+}
@@ -0,0 +1,38 @@
+package target
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"text/template"
+)
+
+// Funcs are the functions made available to every format template.
+// They cover the small amount of arithmetic some descriptor formats
+// need, eg. converting a top-left origin to a bottom-left origin, fsub
+// doing the same for fractions such as pivots, and
+// quoting values in JSON based formats. The u16, u32 and cstring
+// functions write little endian integers and NUL terminated strings
+// for binary formats.
+// Custom templates can make use of them by calling
+// template.New(name).Funcs(target.Funcs) before parsing.
+var Funcs = template.FuncMap{
+	"add":  func(a, b int) int { return a + b },
+	"sub":  func(a, b int) int { return a - b },
+	"mul":  func(a, b int) int { return a * b },
+	"fsub": func(a, b float64) float64 { return a - b },
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"u16": func(v int) string {
+		b := make([]byte, 2)
+		binary.LittleEndian.PutUint16(b, uint16(v))
+		return string(b)
+	},
+	"u32": func(v int) string {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(v))
+		return string(b)
+	},
+	"cstring": func(s string) string { return s + "\x00" },
+}
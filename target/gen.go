@@ -75,6 +75,6 @@ import (
 	"text/template"
 )
 {{ range .Templates }}
-var {{ .Name }}Template = template.Must(template.New("{{ .Name }}").Parse(` + "`{{ .TemplateText }}`" + `))
+var {{ .Name }}Template = template.Must(template.New("{{ .Name }}").Funcs(Funcs).Parse(` + "`{{ .TemplateText }}`" + `))
 {{ end }}
 `))
@@ -0,0 +1,10 @@
+local image = love.graphics.newImage('{{.ImageFilename}}')
+{{- if .AlphaFilename}}
+local alpha = love.graphics.newImage('{{.AlphaFilename}}')
+{{- end}}
+local quads = {}
+
+{{range .Sprites -}}
+quads['{{.Name}}'] = love.graphics.newQuad({{.Left}},{{.Top}},{{.Width}},{{.Height}},{{$.Width}},{{$.Height}})
+{{end}}
+return {image = image{{if .AlphaFilename}}, alpha = alpha{{end}}, quads = quads}
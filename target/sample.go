@@ -0,0 +1,114 @@
+package target
+
+import "path"
+
+// sampleAtlas mirrors the template data that the packer passes to a
+// format's template. It is used by Format.Validate to exercise a
+// template without running a full pack, so it must be kept in sync
+// with the exported fields and methods of the packer's atlas and sprite.
+type sampleAtlas struct {
+	Name    string
+	Sprites []*sampleSprite
+
+	DescFilename  string
+	ImageFilename string
+	AlphaFilename string
+
+	Width   int
+	Height  int
+	Padding int
+	Scale   float64
+	// Occupancy is the fraction of the atlas covered by sprites
+	Occupancy float64
+
+	Animations []*sampleAnimation
+	Meta       sampleMeta
+}
+
+// sampleMeta mirrors the meta describing the build that wrote a descriptor
+type sampleMeta struct {
+	App      string
+	Version  string
+	BuildTag string
+}
+
+// sampleAnimation mirrors the animations detected by the packer
+type sampleAnimation struct {
+	Name   string
+	Frames []*sampleSprite
+}
+
+type sampleSprite struct {
+	name  string
+	x, y  int
+	w, h  int
+	index int
+}
+
+func (s *sampleSprite) Name() string        { return path.Base(s.name) }
+func (s *sampleSprite) DisplayName() string { return s.name }
+func (s *sampleSprite) Left() int           { return s.x }
+func (s *sampleSprite) Top() int            { return s.y }
+func (s *sampleSprite) Width() int          { return s.w }
+func (s *sampleSprite) Height() int         { return s.h }
+func (s *sampleSprite) BorderLeft() int     { return 0 }
+func (s *sampleSprite) BorderTop() int      { return 0 }
+func (s *sampleSprite) BorderRight() int    { return 0 }
+func (s *sampleSprite) BorderBottom() int   { return 0 }
+func (s *sampleSprite) Delay() int          { return 0 }
+func (s *sampleSprite) Index() int          { return s.index }
+func (s *sampleSprite) Layer() int          { return 0 }
+func (s *sampleSprite) Rotated() bool       { return false }
+func (s *sampleSprite) Trimmed() bool       { return false }
+func (s *sampleSprite) TrimX() int          { return 0 }
+func (s *sampleSprite) TrimY() int          { return 0 }
+func (s *sampleSprite) SourceWidth() int    { return s.w }
+func (s *sampleSprite) SourceHeight() int   { return s.h }
+func (s *sampleSprite) Palette() []string   { return []string{"#ffffffff"} }
+func (s *sampleSprite) U0() float64         { return 0 }
+func (s *sampleSprite) V0() float64         { return 0 }
+func (s *sampleSprite) U1() float64         { return 0.5 }
+func (s *sampleSprite) V1() float64         { return 0.5 }
+func (s *sampleSprite) PivotX() float64     { return 0.5 }
+func (s *sampleSprite) PivotY() float64     { return 0.5 }
+
+// newSampleAtlas returns an atlas containing a single sprite
+func newSampleAtlas() *sampleAtlas {
+	sprites := []*sampleSprite{{name: "sprites/sprite", x: 0, y: 0, w: 32, h: 32}}
+	return &sampleAtlas{
+		Name:          "sample-1",
+		Sprites:       sprites,
+		DescFilename:  "sample-1.desc",
+		ImageFilename: "sample-1.png",
+		Width:         64,
+		Height:        64,
+		Scale:         1.0,
+		Occupancy:     0.25,
+		Animations:    []*sampleAnimation{{Name: "sprites/sprite", Frames: sprites}},
+		Meta:          newSampleMeta(),
+	}
+}
+
+// sampleAtlasSet mirrors the template data of Multi formats
+type sampleAtlasSet struct {
+	Name       string
+	Atlases    []*sampleAtlas
+	Animations []*sampleAnimation
+	Meta       sampleMeta
+}
+
+// newSampleAtlasSet returns a set containing a single sample atlas
+func newSampleAtlasSet() *sampleAtlasSet {
+	a := newSampleAtlas()
+	return &sampleAtlasSet{
+		Name:       "sample",
+		Atlases:    []*sampleAtlas{a},
+		Animations: a.Animations,
+		Meta:       a.Meta,
+	}
+}
+
+// newSampleMeta returns the meta of a tagged build
+func newSampleMeta() sampleMeta {
+	return sampleMeta{App: "https://github.com/psucodervn/lovepac", Version: "3.0", BuildTag: "sample"}
+}
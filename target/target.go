@@ -2,7 +2,13 @@
 // the texture packer.
 package target
 
-import "text/template"
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
 
 //go:generate go run gen.go
 
@@ -17,28 +23,100 @@ type Format struct {
 	// used when the descriptor file is written to
 	// the file system.
 	Ext string
+	// Multi formats describe several atlases in a single descriptor.
+	// Their template is executed with a value containing the base
+	// .Name and the list of .Atlases rather than a single atlas.
+	Multi bool
+	// SplitAlpha formats reference the separate alpha image of each
+	// atlas with the AlphaFilename template value, or no image at all,
+	// and can be written by runs with packer.Params.SplitAlpha. Custom
+	// formats that reference the alpha image should set it.
+	SplitAlpha bool
 
 	// TODO add features supported (eg. trimming, rotation etc)
 }
 
+// NewFormat returns a custom format that renders descriptors with the
+// template and writes them with the file extension, a leading "." is
+// dropped. An error is returned if the template is nil, the extension
+// is empty or the template fails to execute against a sample atlas.
+// Templates parsed with Funcs may use the same functions as the
+// built in formats.
+func NewFormat(name, ext string, tmpl *template.Template) (Format, error) {
+	if tmpl == nil {
+		return Unknown, errors.New("Format template must not be nil")
+	}
+	ext = strings.TrimPrefix(ext, ".")
+	if ext == "" {
+		return Unknown, errors.New("Format extension must not be empty")
+	}
+	format := Format{Name: name, Template: tmpl, Ext: ext}
+	if err := format.Validate(); err != nil {
+		return Unknown, fmt.Errorf("Invalid template of format '%s': %s", name, err)
+	}
+	return format, nil
+}
+
 // IsValid checks that a format has a valid template
 // and file extension
 func (f Format) IsValid() bool {
 	return f.Template != nil && f.Ext != ""
 }
 
+// Validate executes the format's template against a sample atlas
+// containing a single sprite and returns any error encountered.
+// Unlike IsValid, this catches template execution errors such as
+// references to fields that do not exist.
+func (f Format) Validate() error {
+	if !f.IsValid() {
+		return errors.New("Format must have a template and file extension")
+	}
+	if f.Multi {
+		return f.Template.Execute(ioutil.Discard, newSampleAtlasSet())
+	}
+	return f.Template.Execute(ioutil.Discard, newSampleAtlas())
+}
+
 var (
 	// Unknown format, should used for error responses
-	Unknown = Format{"unknown", nil, ""}
+	Unknown = Format{Name: "unknown"}
 	// Love format for the love2d game engine
-	Love = Format{"love", loveTemplate, "lua"}
+	Love = Format{Name: "love", Template: loveTemplate, Ext: "lua"}
+	// LoveModule format for the love2d game engine, a module that loads
+	// the atlas image and returns it with the quads, ready to require
+	LoveModule = Format{Name: "lovemodule", Template: lovemoduleTemplate, Ext: "lua", SplitAlpha: true}
 	// Starling format for the Starling game engine
-	Starling = Format{"starling", starlingTemplate, "xml"}
+	Starling = Format{Name: "starling", Template: starlingTemplate, Ext: "xml", SplitAlpha: true}
 	// Spine format for the Spine tool
-	Spine = Format{"spine", spineTemplate, "atlas"}
+	Spine = Format{Name: "spine", Template: spineTemplate, Ext: "atlas"}
+	// Unity format for the TexturePacker Importer in the Unity game engine
+	Unity = Format{Name: "unity", Template: unityTemplate, Ext: "tpsheet"}
+	// PhaserMulti format for the Phaser 3 game framework's multiatlas
+	// loader, which describes every atlas page in one JSON file
+	PhaserMulti = Format{Name: "phasermulti", Template: phasermultiTemplate, Ext: "json", Multi: true, SplitAlpha: true}
+	// Binary format for runtimes that load or mmap the descriptor without
+	// parsing text. It describes every atlas in fixed size little endian
+	// records followed by a string table, the byte layout is documented
+	// in binary.template.bin.
+	Binary = Format{Name: "binary", Template: binaryTemplate, Ext: "bin", Multi: true}
+	// TSDef format declares a TypeScript union type of every sprite name,
+	// for type checked lookups in TypeScript and JavaScript projects
+	TSDef = Format{Name: "tsdef", Template: tsdefTemplate, Ext: "d.ts", Multi: true, SplitAlpha: true}
+	// TextureArray format describes every atlas as a layer of one 2D
+	// texture array, giving the layer of each sprite alongside its rect,
+	// see packer.Params.TextureArray
+	TextureArray = Format{Name: "texturearray", Template: texturearrayTemplate, Ext: "json", Multi: true, SplitAlpha: true}
+	// NDJSON format describes every frame of every atlas as a line of
+	// newline delimited JSON, for sets of tens of thousands of sprites.
+	// The descriptor is written as it is rendered, even when the JSON
+	// formats are rendered into memory to be reformatted with
+	// packer.Params.PrettyPrint or Minify, and it can be read a frame at
+	// a time. packer.Params.Transactional holds it in memory until the
+	// run succeeds.
+	NDJSON = Format{Name: "ndjson", Template: ndjsonTemplate, Ext: "ndjson", Multi: true, SplitAlpha: true}
 )
 
-var allFormats = []Format{Love, Starling}
+var allFormats = []Format{Love, LoveModule, Starling, Unity, PhaserMulti, Binary, TSDef, TextureArray, NDJSON}
 
 // FormatNamed returns a known format with the given name.
 func FormatNamed(name string) Format {
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// This file was generated by robots at 2022-04-07 02:03:24.193461 +0700 +07 m=+0.001588834
+// This file was generated by robots at 2026-10-14 19:27:20.390144179 +0000 UTC m=+0.000563507
 // TODO add the commit hash in here too
 
 package target
@@ -8,7 +8,38 @@ import (
 	"text/template"
 )
 
-var loveTemplate = template.Must(template.New("love").Parse(`local quads = {}
+var binaryTemplate = template.Must(template.New("binary").Funcs(Funcs).Parse(`{{- /*
+Every value is a little endian uint32 unless stated otherwise.
+
+Header, 16 bytes:
+	magic "LPAB", version uint16 (1), reserved uint16 (0)
+	atlas count, string table offset from the start of the file
+Atlas records, 16 bytes each:
+	image filename offset, width, height, sprite count
+Sprite records, 24 bytes each, in atlas order:
+	name offset, x, y, width, height, flags (reserved, 0)
+String table:
+	NUL terminated image filenames then sprite names, in record order.
+	Offsets of names are relative to the start of the string table.
+*/ -}}
+{{- $sprites := 0}}{{range .Atlases}}{{$sprites = add $sprites (len .Sprites)}}{{end -}}
+LPAB
+{{- u16 1}}{{u16 0}}
+{{- u32 (len .Atlases)}}{{u32 (add (add 16 (mul 16 (len .Atlases))) (mul 24 $sprites))}}
+{{- $offset := 0}}
+{{- range .Atlases}}
+{{- u32 $offset}}{{u32 .Width}}{{u32 .Height}}{{u32 (len .Sprites)}}
+{{- $offset = add $offset (add (len .ImageFilename) 1)}}
+{{- end}}
+{{- range .Atlases}}{{range .Sprites}}
+{{- u32 $offset}}{{u32 .Left}}{{u32 .Top}}{{u32 .Width}}{{u32 .Height}}{{u32 0}}
+{{- $offset = add $offset (add (len .Name) 1)}}
+{{- end}}{{end}}
+{{- range .Atlases}}{{cstring .ImageFilename}}{{end}}
+{{- range .Atlases}}{{range .Sprites}}{{cstring .Name}}{{end}}{{end -}}
+`))
+
+var loveTemplate = template.Must(template.New("love").Funcs(Funcs).Parse(`local quads = {}
 
 {{range .Sprites -}}
 quads['{{.Name}}'] = love.graphics.newQuad({{.Left}},{{.Top}},{{.Width}},{{.Height}},{{$.Width}},{{$.Height}})
@@ -16,19 +47,158 @@ quads['{{.Name}}'] = love.graphics.newQuad({{.Left}},{{.Top}},{{.Width}},{{.Heig
 return quads
 `))
 
-var spineTemplate = template.Must(template.New("spine").Parse(`{{.ImageFilename}}
+var lovemoduleTemplate = template.Must(template.New("lovemodule").Funcs(Funcs).Parse(`local image = love.graphics.newImage('{{.ImageFilename}}')
+{{- if .AlphaFilename}}
+local alpha = love.graphics.newImage('{{.AlphaFilename}}')
+{{- end}}
+local quads = {}
+
+{{range .Sprites -}}
+quads['{{.Name}}'] = love.graphics.newQuad({{.Left}},{{.Top}},{{.Width}},{{.Height}},{{$.Width}},{{$.Height}})
+{{end}}
+return {image = image{{if .AlphaFilename}}, alpha = alpha{{end}}, quads = quads}
+`))
+
+var ndjsonTemplate = template.Must(template.New("ndjson").Funcs(Funcs).Parse(`{{- /*
+Newline delimited JSON, one line per frame of every atlas, so that huge
+sets are written and read a frame at a time instead of as one document.
+Each frame names the image of its atlas, and its alpha image when the
+alpha is split, and gives the size of the atlas.
+*/ -}}
+{{- range $atlas := .Atlases}}{{range $sprite := $atlas.Sprites -}}
+{"filename":{{json $sprite.Name}},"image":{{json $atlas.ImageFilename}},{{if $atlas.AlphaFilename}}"alphaImage":{{json $atlas.AlphaFilename}},{{end}}"size":{"w":{{$atlas.Width}},"h":{{$atlas.Height}}},"frame":{"x":{{$sprite.Left}},"y":{{$sprite.Top}},"w":{{$sprite.Width}},"h":{{$sprite.Height}}},"trimmed":{{$sprite.Trimmed}},"spriteSourceSize":{"x":{{$sprite.TrimX}},"y":{{$sprite.TrimY}},"w":{{$sprite.Width}},"h":{{$sprite.Height}}},"sourceSize":{"w":{{$sprite.SourceWidth}},"h":{{$sprite.SourceHeight}}}}
+{{end}}{{end -}}
+`))
+
+var phasermultiTemplate = template.Must(template.New("phasermulti").Funcs(Funcs).Parse(`{
+	"textures": [
+		{{- range $i, $atlas := .Atlases}}{{if $i}},{{end}}
+		{
+			"image": {{json $atlas.ImageFilename}},
+			{{- if $atlas.AlphaFilename}}
+			"alphaImage": {{json $atlas.AlphaFilename}},
+			{{- end}}
+			"format": "RGBA8888",
+			"size": {"w": {{$atlas.Width}}, "h": {{$atlas.Height}}},
+			"scale": {{$atlas.Scale}},
+			"frames": [
+				{{- range $j, $sprite := $atlas.Sprites}}{{if $j}},{{end}}
+				{
+					"filename": {{json $sprite.Name}},
+					"rotated": false,
+					"trimmed": {{$sprite.Trimmed}},
+					"sourceSize": {"w": {{$sprite.SourceWidth}}, "h": {{$sprite.SourceHeight}}},
+					"spriteSourceSize": {"x": {{$sprite.TrimX}}, "y": {{$sprite.TrimY}}, "w": {{$sprite.Width}}, "h": {{$sprite.Height}}},
+					"frame": {"x": {{$sprite.Left}}, "y": {{$sprite.Top}}, "w": {{$sprite.Width}}, "h": {{$sprite.Height}}}
+				}
+				{{- end}}
+			]
+		}
+		{{- end}}
+	],
+	{{- if .Animations}}
+	"animations": {
+		{{- range $i, $animation := .Animations}}{{if $i}},{{end}}
+		{{json $animation.Name}}: [
+			{{- range $j, $frame := $animation.Frames}}{{if $j}}, {{end}}{{json $frame.Name}}{{end -}}
+		]
+		{{- end}}
+	},
+	{{- end}}
+	"meta": {
+		"app": {{json .Meta.App}},
+		"version": {{json .Meta.Version}}
+		{{- if .Meta.BuildTag}},
+		"buildTag": {{json .Meta.BuildTag}}
+		{{- end}}
+	}
+}
+`))
+
+var spineTemplate = template.Must(template.New("spine").Funcs(Funcs).Parse(`{{.ImageFilename}}
 size:{{.Width}},{{.Height}}
 scale:{{.Scale}}
 {{- range .Sprites}}
 {{.DisplayName}}
 bounds:{{.Left}},{{.Top}},{{.Width}},{{.Height}}
+index:{{.Index}}
 {{- end}}
 
 `))
 
-var starlingTemplate = template.Must(template.New("starling").Parse(`<TextureAtlas imagePath="{{.ImageFilename}}">
+var starlingTemplate = template.Must(template.New("starling").Funcs(Funcs).Parse(`<TextureAtlas imagePath="{{.ImageFilename}}"{{if .AlphaFilename}} alphaPath="{{.AlphaFilename}}"{{end}}>
 {{- range .Sprites}}
-    <SubTexture name="{{.Name}}" x="{{.Left}}" y="{{.Top}}" width="{{.Width}}" height="{{.Height}}"/>
+    <SubTexture name="{{.Name}}" x="{{.Left}}" y="{{.Top}}" width="{{.Width}}" height="{{.Height}}"
+    {{- if .Trimmed}} frameX="{{sub 0 .TrimX}}" frameY="{{sub 0 .TrimY}}" frameWidth="{{.SourceWidth}}" frameHeight="{{.SourceHeight}}"{{end}}/>
 {{- end}}
 </TextureAtlas>
 `))
+
+var texturearrayTemplate = template.Must(template.New("texturearray").Funcs(Funcs).Parse(`{{- /*
+A single 2D texture array, each atlas image is a layer of the array
+and every frame names the layer it is on. All layers are the same size.
+When the alpha is split the alphaLayers list the alpha image of each layer.
+*/ -}}
+{
+	"layers": [
+		{{- range $i, $atlas := .Atlases}}{{if $i}},{{end}}
+		{{json $atlas.ImageFilename}}
+		{{- end}}
+	],
+	{{- if (index .Atlases 0).AlphaFilename}}
+	"alphaLayers": [
+		{{- range $i, $atlas := .Atlases}}{{if $i}},{{end}}
+		{{json $atlas.AlphaFilename}}
+		{{- end}}
+	],
+	{{- end}}
+	{{- with index .Atlases 0}}
+	"size": {"w": {{.Width}}, "h": {{.Height}}},
+	"scale": {{.Scale}},
+	{{- end}}
+	"frames": [
+		{{- range $i, $atlas := .Atlases}}{{range $j, $sprite := $atlas.Sprites}}{{if or $i $j}},{{end}}
+		{"filename": {{json $sprite.Name}}, "layer": {{$sprite.Layer}}, "frame": {"x": {{$sprite.Left}}, "y": {{$sprite.Top}}, "w": {{$sprite.Width}}, "h": {{$sprite.Height}}}}
+		{{- end}}{{end}}
+	],
+	"meta": {
+		"app": {{json .Meta.App}},
+		"version": {{json .Meta.Version}}
+		{{- if .Meta.BuildTag}},
+		"buildTag": {{json .Meta.BuildTag}}
+		{{- end}}
+	}
+}
+`))
+
+var tsdefTemplate = template.Must(template.New("tsdef").Funcs(Funcs).Parse(`{{- /*
+A TypeScript union of the names of every sprite, so that lookups by
+name are checked at compile time. Names in several atlases repeat.
+A set without sprites has no names, which is never.
+*/ -}}
+// Code generated by lovepac. DO NOT EDIT.
+
+export type SpriteName =
+{{- $empty := true}}
+{{- range $atlas := .Atlases}}{{range $sprite := $atlas.Sprites}}{{$empty = false}}
+	| {{json $sprite.Name}}
+{{- end}}{{end}}{{if $empty}} never{{end}};
+`))
+
+var unityTemplate = template.Must(template.New("unity").Funcs(Funcs).Parse(`# Sprite sheet data for Unity.
+#
+# To import these sprites into your Unity project, install the
+# TexturePacker Importer from the Unity Asset Store.
+#
+:format=40300
+:texture={{.ImageFilename}}
+:size={{.Width}}x{{.Height}}
+:pivotpoints=enabled
+:borders=enabled
+
+# Sprite data
+# name; x; y; width; height; pivot x; pivot y; border left; border right; border top; border bottom
+{{range .Sprites -}}
+{{.Name}};{{.Left}};{{sub (sub $.Height .Top) .Height}};{{.Width}};{{.Height}}; {{.PivotX}};{{fsub 1.0 .PivotY}}; {{.BorderLeft}};{{.BorderRight}};{{.BorderTop}};{{.BorderBottom}}
+{{end -}}
+`))
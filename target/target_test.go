@@ -1,7 +1,9 @@
 package target_test
 
 import (
+	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/psucodervn/lovepac/target"
 )
@@ -23,3 +25,94 @@ func TestIsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate(t *testing.T) {
+	for _, format := range []target.Format{target.Love, target.LoveModule, target.Starling, target.Spine, target.Unity, target.PhaserMulti, target.Binary, target.TSDef, target.TextureArray, target.NDJSON} {
+		if err := format.Validate(); err != nil {
+			t.Errorf("Expected format '%s' to be valid but got '%s'", format.Name, err)
+		}
+	}
+
+	typo := target.Format{
+		Name:     "typo",
+		Template: template.Must(template.New("typo").Parse("{{range .Sprites}}{{.Nmae}}{{end}}")),
+		Ext:      "txt",
+	}
+	if err := typo.Validate(); err == nil {
+		t.Errorf("Expected format with a field name typo to fail validation but got nil")
+	}
+
+	if err := target.Unknown.Validate(); err == nil {
+		t.Errorf("Expected unknown format to fail validation but got nil")
+	}
+}
+
+func TestNewFormatValidatesTheOccupancyOfAtlases(t *testing.T) {
+	tmpl := template.Must(template.New("occ").Parse("{{.Occupancy}}"))
+	if _, err := target.NewFormat("occ", "txt", tmpl); err != nil {
+		t.Errorf("Expected a template using the atlas occupancy to be valid but got '%s'", err)
+	}
+}
+
+func TestNewFormat(t *testing.T) {
+	tmpl := template.Must(template.New("names").Parse("{{range .Sprites}}{{.Name}}\n{{end}}"))
+	format, err := target.NewFormat("names", ".txt", tmpl)
+	if err != nil {
+		t.Fatalf("Expected a valid custom format but got '%s'", err)
+	}
+	if !format.IsValid() || format.Name != "names" || format.Ext != "txt" || format.Template != tmpl {
+		t.Errorf("Expected a valid format named 'names' with the extension 'txt' but got '%v'", format)
+	}
+
+	typo := template.Must(template.New("typo").Parse("{{range .Sprites}}{{.Nmae}}{{end}}"))
+	tests := map[string]struct {
+		ext  string
+		tmpl *template.Template
+	}{
+		"nil template": {"txt", nil},
+		"no extension": {"", tmpl},
+		"only a dot":   {".", tmpl},
+		"field typo":   {"txt", typo},
+	}
+	for name, test := range tests {
+		if _, err := target.NewFormat(name, test.ext, test.tmpl); err == nil {
+			t.Errorf("Expected a custom format with %s to result in an error", name)
+		}
+	}
+}
+
+func TestFormatNamed(t *testing.T) {
+	formats := map[string]target.Format{
+		"love":         target.Love,
+		"lovemodule":   target.LoveModule,
+		"starling":     target.Starling,
+		"unity":        target.Unity,
+		"phasermulti":  target.PhaserMulti,
+		"binary":       target.Binary,
+		"tsdef":        target.TSDef,
+		"texturearray": target.TextureArray,
+		"ndjson":       target.NDJSON,
+		"notreal":      target.Unknown,
+	}
+
+	for name, expect := range formats {
+		got := target.FormatNamed(name)
+		if got != expect {
+			t.Errorf("Expected 'FormatNamed' for '%s' to return format '%s' but got '%s'", name, expect.Name, got.Name)
+		}
+	}
+}
+
+func TestTSDefOfAnEmptySetIsNever(t *testing.T) {
+	type sprite struct{ Name string }
+	type atlas struct{ Sprites []sprite }
+	empty := struct{ Atlases []atlas }{Atlases: []atlas{{}}}
+
+	buf := &strings.Builder{}
+	if err := target.TSDef.Template.Execute(buf, empty); err != nil {
+		t.Fatalf("Expected the empty set to render without error but got '%s'", err)
+	}
+	if got := buf.String(); !strings.HasSuffix(got, "export type SpriteName = never;\n") {
+		t.Errorf("Expected the empty set to declare a never type but got\n\n%s", got)
+	}
+}